@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// batchSuffix is the custom method suffix of the batch route (/posts:batch)
+// Gin matches it as a path parameter, so the handler checks that the suffix is exactly ":batch"
+const batchSuffix = ":batch"
+
+// BatchPostsHandler applies create, update, delete and like operations to many posts in one request
+// Expects a JSON payload with `operations` and an optional `atomic` flag
+// Returns a result per operation; atomic batches are rolled back entirely when any operation fails
+func BatchPostsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	if c.Param("batch") != batchSuffix {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	var req dto.BatchRequest
+	if err := bindStrictJSON(c, "batch_posts", &req); err != nil {
+		log.Errorln("Failed to execute batch: Invalid request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. A batch needs 1-100 operations of type create, update, delete or like")})
+		return
+	}
+
+	operations := make([]services.BatchOperation, len(req.Operations))
+	for i, operation := range req.Operations {
+		operations[i] = services.BatchOperation{
+			Op:      services.BatchOp(operation.Op),
+			PostID:  operation.PostID,
+			Content: operation.Content,
+		}
+	}
+
+	// Call the service to execute the batch
+	results, err := services.ExecuteBatch(ctx, operations, req.Atomic)
+	if err != nil && !errors.Is(err, services.ErrBatchRolledBack) {
+		log.Errorln("Failed to execute batch: Error occurred in batch service: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to execute batch: " + err.Error()})
+		return
+	}
+
+	response := dto.BatchResponse{Atomic: req.Atomic, Results: make([]dto.BatchItemResult, len(results))}
+	for i, result := range results {
+		item := dto.BatchItemResult{Index: i, Op: string(result.Op)}
+		switch {
+		case result.Err != nil:
+			item.Status = statusForServiceError(result.Err)
+			item.Error = result.Err.Error()
+			response.Failed++
+		case result.RolledBack:
+			item.Status = http.StatusFailedDependency
+			item.Error = "not applied: another operation of the atomic batch failed"
+			response.Failed++
+		default:
+			item.Status = http.StatusOK
+			if result.Op == services.BatchCreate {
+				item.Status = http.StatusCreated
+			}
+			post := dto.NewPostResponse(result.Post, renderOptions(c))
+			item.Post = &post
+			response.Succeeded++
+		}
+		response.Results[i] = item
+	}
+
+	log = log.WithFields(logrus.Fields{"atomic": req.Atomic, "succeeded": response.Succeeded, "failed": response.Failed})
+	if errors.Is(err, services.ErrBatchRolledBack) {
+		log.Warnln("Atomic batch rolled back")
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	log.Infoln("Batch executed")
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mini-social-media-api/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// errInvalidBody marks errors from decoding the body, as opposed to validation failures
+var errInvalidBody = errors.New("invalid request body")
+
+// bindStrictJSON decodes the JSON request body into obj, rejecting unknown fields and trailing data,
+// and then applies the binding validation rules of obj
+// Failures are counted as validation rejections of the operation, like those of the services
+func bindStrictJSON(c *gin.Context, operation string, obj interface{}) error {
+	err := decodeStrictJSON(c, obj)
+	if err != nil {
+		metrics.ValidationRejectionsTotal.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+// decodeStrictJSON decodes and validates the JSON request body for bindStrictJSON
+func decodeStrictJSON(c *gin.Context, obj interface{}) error {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidBody, err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("%w: body must contain a single JSON object", errInvalidBody)
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bindErrorMessage returns the client facing message for a failed bind
+// Validation failures use validationMessage, malformed bodies and unknown fields report the decoding error
+func bindErrorMessage(err error, validationMessage string) string {
+	if errors.Is(err, errInvalidBody) {
+		return "Invalid request: " + err.Error()
+	}
+	return validationMessage
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookmarkPostHandler privately saves the post in the `postID` URL parameter for the user in the X-User-ID header
+func BookmarkPostHandler(c *gin.Context) {
+	changePost(c, "bookmark post", services.BookmarkPost, http.StatusOK, "Bookmarked the post successfully")
+}
+
+// UnbookmarkPostHandler removes the post in the `postID` URL parameter from the bookmarks of the user in the X-User-ID header
+func UnbookmarkPostHandler(c *gin.Context) {
+	changePost(c, "remove bookmark", services.UnbookmarkPost, http.StatusOK, "Removed the bookmark successfully")
+}
+
+// GetBookmarksHandler lists the bookmarks of the user in the X-User-ID header, most recently bookmarked first
+// Supports `cursor` and `limit` query parameters; the cursor of the next page is returned with each page
+func GetBookmarksHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	cursor, limit, ok := parseCursorPagination(c)
+	if !ok {
+		return
+	}
+
+	bookmarks, next, err := services.GetBookmarks(ctx, cursor, limit)
+	if err != nil {
+		log.Errorln("Failed to get bookmarks: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get bookmarks: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved bookmarks successfully")
+	c.JSON(http.StatusOK, dto.BookmarksPage{
+		Posts:      dto.NewPostResponses(bookmarks, renderOptions(c)),
+		Limit:      limit,
+		NextCursor: next,
+	})
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"mini-social-media-api/services"
+	"net/http"
+)
+
+// statusForServiceError maps errors returned by the services to HTTP status codes
+func statusForServiceError(err error) int {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.Is(err, services.ErrPostNotFound), errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrMediaNotFound),
+		errors.Is(err, services.ErrPollNotFound), errors.Is(err, services.ErrNotificationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPatchConflict), errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrAttachmentInUse),
+		errors.Is(err, services.ErrAlreadyReposted), errors.Is(err, services.ErrPollClosed), errors.Is(err, services.ErrAlreadyVoted):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUnknownCaller):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrNotAuthor):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrMediaTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedMedia):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrMediaUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, services.ErrFieldNotPatchable), errors.As(err, &validationErr):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
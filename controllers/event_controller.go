@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval is how often an idle event stream sends a comment, so proxies and clients keep the connection open
+var heartbeatInterval = 15 * time.Second
+
+// StreamPostEventsHandler streams the changes to the post in the `postID` URL parameter as Server-Sent Events
+// Resumes after the Last-Event-ID header, or the `last_event_id` query parameter for clients that cannot set headers
+func StreamPostEventsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to stream post events: Error in converting post id to int: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	lastEventID, ok := parseLastEventID(c)
+	if !ok {
+		return
+	}
+
+	stream, err := services.StreamPost(ctx, postID, lastEventID)
+	if err != nil {
+		log.Errorln("Failed to stream post events: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to stream post events: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Streaming post events")
+	streamEvents(c, stream)
+}
+
+// StreamTimelineEventsHandler streams the changes to the home timeline of the user in the X-User-ID header as Server-Sent Events
+// Resumes after the Last-Event-ID header, or the `last_event_id` query parameter for clients that cannot set headers
+func StreamTimelineEventsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	lastEventID, ok := parseLastEventID(c)
+	if !ok {
+		return
+	}
+
+	stream, err := services.StreamTimeline(ctx, lastEventID)
+	if err != nil {
+		log.Errorln("Failed to stream home timeline events: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to stream home timeline events: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Streaming home timeline events")
+	streamEvents(c, stream)
+}
+
+// parseLastEventID reads the ID of the last event a resuming client received, 0 for a new stream
+// Responds with 400 and returns ok false if it is not a non-negative integer
+func parseLastEventID(c *gin.Context) (uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, true
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warnln("Invalid Last-Event-ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// streamEvents writes the events of a stream as Server-Sent Events until the stream or the request ends
+func streamEvents(c *gin.Context, stream <-chan services.PostEvent) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Stops nginx from buffering the stream
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	options := renderOptions(c)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			if err := writeEvent(c, event, options); err != nil {
+				logging.FromContext(c.Request.Context()).Errorln("Failed to write event: " + err.Error())
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent writes an event with its ID, so a reconnecting client resumes after it, and its data as JSON
+func writeEvent(c *gin.Context, event services.PostEvent, options dto.RenderOptions) error {
+	var data interface{}
+	switch event.Type {
+	case services.EventResync:
+		data = struct{}{} // Clients only dispatch events with data
+	case services.EventPostDeleted:
+		data = dto.DeletedPostEvent{ID: event.Post.ID}
+	default:
+		data = dto.NewPostResponse(event.Post, options)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+	return err
+}
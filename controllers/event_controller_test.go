@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"mini-social-media-api/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamPostEventsHandlerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/posts/:postID/events", StreamPostEventsHandler)
+
+	post, _ := services.CreatePostWithOptions(context.Background(), "Streamed", services.PostOptions{})
+
+	tests := []struct {
+		name        string
+		path        string
+		lastEventID string
+		wantStatus  int
+	}{
+		{"Invalid post ID", "/posts/abc/events", "", http.StatusBadRequest},
+		{"Unknown post", "/posts/999999/events", "", http.StatusNotFound},
+		{"Invalid Last-Event-ID", fmt.Sprintf("/posts/%d/events", post.ID), "abc", http.StatusBadRequest},
+		{"Negative Last-Event-ID", fmt.Sprintf("/posts/%d/events", post.ID), "-1", http.StatusBadRequest},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+			if testCase.lastEventID != "" {
+				request.Header.Set("Last-Event-ID", testCase.lastEventID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+
+			if w.Code != testCase.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamPostEventsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/posts/:postID/events", StreamPostEventsHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	defer func(interval time.Duration) { heartbeatInterval = interval }(heartbeatInterval)
+	heartbeatInterval = 10 * time.Millisecond
+
+	post, _ := services.CreatePostWithOptions(context.Background(), "Streamed", services.PostOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/posts/%d/events", server.URL, post.ID), nil)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", contentType)
+	}
+
+	// The stream is subscribed once the headers are sent, so the like is streamed
+	services.LikePost(context.Background(), post.ID)
+
+	var lines []string
+	heartbeats := 0
+	scanner := bufio.NewScanner(response.Body)
+	for len(lines) < 3 && scanner.Scan() {
+		switch line := scanner.Text(); {
+		case line == ": keepalive":
+			heartbeats++
+		case line != "":
+			lines = append(lines, line)
+		}
+	}
+	for heartbeats == 0 && scanner.Scan() {
+		if scanner.Text() == ": keepalive" {
+			heartbeats++
+		}
+	}
+
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: post.liked" || !strings.Contains(lines[2], `"likes":1`) {
+		t.Errorf("Expected a post.liked event with its ID and the liked post, got: %q", lines)
+	}
+	if heartbeats == 0 {
+		t.Error("Expected keepalive comments while the stream is idle")
+	}
+}
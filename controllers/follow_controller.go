@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowUserHandler makes the user in the X-User-ID header follow the user in the `userID` URL parameter
+// Following a user twice is not an error
+func FollowUserHandler(c *gin.Context) {
+	changeRelation(c, "follow", services.FollowUser, "Followed user successfully")
+}
+
+// UnfollowUserHandler makes the user in the X-User-ID header stop following the user in the `userID` URL parameter
+// Unfollowing a user that is not followed is not an error
+func UnfollowUserHandler(c *gin.Context) {
+	changeRelation(c, "unfollow", services.UnfollowUser, "Unfollowed user successfully")
+}
+
+// changeRelation applies a change to the relation of the calling user with another user, such as a follow,
+// and responds with the other user
+func changeRelation(c *gin.Context, action string, apply func(ctx context.Context, followerID, followeeID int) (models.User, error), message string) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	followeeID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		log.Errorln("Failed to " + action + " user: Error in converting user ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	followerID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, followerID)
+
+	followee, err := apply(ctx, followerID, followeeID)
+	if err != nil {
+		log.Errorln("Failed to " + action + " user: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to " + action + " user: " + err.Error()})
+		return
+	}
+
+	log.Infoln(message)
+	c.JSON(http.StatusOK, dto.UserEnvelope{Message: message, User: dto.NewUserResponse(followee)})
+}
+
+// GetFollowersHandler lists the followers of the user in the `userID` URL parameter, most recent follow first
+func GetFollowersHandler(c *gin.Context) {
+	listFollows(c, "followers", services.GetFollowers)
+}
+
+// GetFollowingHandler lists the users followed by the user in the `userID` URL parameter, most recent follow first
+func GetFollowingHandler(c *gin.Context) {
+	listFollows(c, "following", services.GetFollowing)
+}
+
+// listFollows responds with a page of one side of the follow graph of a user
+func listFollows(c *gin.Context, name string, list func(ctx context.Context, userID, page, limit int) ([]models.User, int, error)) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		log.Errorln("Failed to get " + name + ": Error in converting user ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	user, err := services.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Errorln("Failed to get " + name + ": " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get " + name + ": " + err.Error()})
+		return
+	}
+
+	listed, total, err := list(ctx, userID, page, limit)
+	if err != nil {
+		log.Errorln("Failed to get " + name + ": " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get " + name + ": " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved " + name + " successfully")
+	c.JSON(http.StatusOK, dto.UsersPage{
+		User:  dto.NewUserResponse(user),
+		Users: dto.NewUserResponses(listed),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireCaller returns the ID of the calling user, stored in the request context by the Identity middleware
+// Responds with 401 and returns ok false for anonymous requests
+func requireCaller(c *gin.Context) (int, bool) {
+	id := identity.FromContext(c.Request.Context())
+	if id == 0 {
+		logging.FromContext(c.Request.Context()).Warnln("Unidentified caller: missing " + middleware.UserIDHeader + " header")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identify the calling user with the " + middleware.UserIDHeader + " header"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and the alt_text field around the file
+const multipartOverhead = 64 << 10
+
+// UploadMediaHandler stores an uploaded image that can then be attached to a post
+// Expects a multipart/form-data body with the image in `file` and an optional `alt_text`
+// Returns the stored attachment, or an error if the file is missing, too large or not a supported image
+func UploadMediaHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	// Stop reading the body as soon as it is too large instead of buffering it
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxUploadBytes+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Errorln("Failed to upload media: Request body is too large")
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Failed to upload media: " + services.ErrMediaTooLarge.Error()})
+			return
+		}
+		log.Errorln("Failed to upload media: Invalid multipart body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Send the image as multipart/form-data in the file field"})
+		return
+	}
+	if header.Size > services.MaxUploadBytes {
+		log.Errorln("Failed to upload media: File is too large")
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Failed to upload media: " + services.ErrMediaTooLarge.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Errorln("Failed to upload media: Could not open the uploaded file: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Could not read the uploaded file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Errorln("Failed to upload media: Could not read the uploaded file: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Could not read the uploaded file"})
+		return
+	}
+
+	attachment, err := services.UploadMedia(ctx, data, c.PostForm("alt_text"))
+	if err != nil {
+		log.Errorln("Failed to upload media: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to upload media: " + err.Error()})
+		return
+	}
+
+	log.WithField(logging.FieldMediaID, attachment.ID).Infoln("Media uploaded successfully")
+	c.JSON(http.StatusCreated, dto.MediaEnvelope{Message: "Media uploaded successfully", Media: dto.NewAttachmentResponse(attachment)})
+}
+
+// GetMediaHandler serves an uploaded image by the `mediaID` URL parameter
+func GetMediaHandler(c *gin.Context) {
+	serveMedia(c, false)
+}
+
+// GetMediaThumbnailHandler serves the JPEG thumbnail of an uploaded image by the `mediaID` URL parameter
+func GetMediaThumbnailHandler(c *gin.Context) {
+	serveMedia(c, true)
+}
+
+// serveMedia streams an image or its thumbnail from the blob store
+// Blobs never change once stored, so attachments of public posts can be cached forever; any other attachment
+// may be hidden or deleted with its post, so it is kept out of caches
+func serveMedia(c *gin.Context, thumbnail bool) {
+	ctx := c.Request.Context()
+	mediaID := c.Param("mediaID")
+	log := logging.FromContext(ctx).WithField(logging.FieldMediaID, mediaID)
+
+	content, err := services.OpenMedia(ctx, mediaID, thumbnail)
+	if err != nil {
+		log.Errorln("Failed to get media: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get media: " + err.Error()})
+		return
+	}
+	defer content.Body.Close()
+
+	size := int64(-1)
+	if !thumbnail {
+		size = int64(content.Attachment.Size)
+	}
+	cacheControl := "private, no-store"
+	if content.Public {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	c.DataFromReader(http.StatusOK, size, content.Attachment.ContentType, content.Body, map[string]string{
+		"Cache-Control":           cacheControl,
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Disposition":     "inline",
+		"Content-Security-Policy": "default-src 'none'",
+	})
+}
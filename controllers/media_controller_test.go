@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/media"
+	"mini-social-media-api/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartBody builds a multipart/form-data body holding the given file, if any, and alt text
+func multipartBody(t *testing.T, file []byte, altText string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if file != nil {
+		part, err := writer.CreateFormFile("file", "upload.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file)
+	}
+	writer.WriteField("alt_text", altText)
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadMediaHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/media/", UploadMediaHandler)
+	router.GET("/media/:mediaID", GetMediaHandler)
+
+	store, err := media.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	services.Blobs = store
+	defer func() { services.Blobs = nil }()
+
+	var image32 bytes.Buffer
+	png.Encode(&image32, image.NewGray(image.Rect(0, 0, 32, 32)))
+
+	tests := []struct {
+		name       string
+		file       []byte
+		wantStatus int
+	}{
+		{"Valid image", image32.Bytes(), http.StatusCreated},
+		{"Missing file", nil, http.StatusBadRequest},
+		{"Not an image", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{"Too large", make([]byte, services.MaxUploadBytes+1), http.StatusRequestEntityTooLarge},
+	}
+
+	var uploaded dto.MediaEnvelope
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, testCase.file, "A black square")
+			req := httptest.NewRequest(http.MethodPost, "/media/", body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != testCase.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", testCase.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusCreated {
+				json.Unmarshal(w.Body.Bytes(), &uploaded)
+			}
+		})
+	}
+
+	// Only attachments of public posts may be kept by shared caches
+	cacheControl := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/"+uploaded.Media.ID, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		return w.Header().Get("Cache-Control")
+	}
+	if got := cacheControl(); got != "private, no-store" {
+		t.Errorf("Expected unattached media to stay out of caches, got Cache-Control '%s'", got)
+	}
+	if _, err := services.CreatePostWithOptions(context.Background(), "Look", services.PostOptions{Attachments: []services.AttachmentRef{{ID: uploaded.Media.ID}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := cacheControl(); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected media of a public post to be cached, got Cache-Control '%s'", got)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown media, got %d", w.Code)
+	}
+}
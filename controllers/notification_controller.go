@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationsHandler lists the notifications of the user in the X-User-ID header, most recently updated first
+// Supports `page` and `limit` query parameters, and `unread=true` to list unread notifications only
+func GetNotificationsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		log.Warnln("Invalid unread query parameter")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unread parameter"})
+		return
+	}
+
+	notifications, total, unread, err := services.GetNotifications(ctx, unreadOnly, page, limit)
+	if err != nil {
+		log.Errorln("Failed to get notifications: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get notifications: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved notifications successfully")
+	c.JSON(http.StatusOK, dto.NotificationsPage{
+		Notifications: dto.NewNotificationResponses(notifications),
+		Page:          page,
+		Limit:         limit,
+		Total:         total,
+		Unread:        unread,
+	})
+}
+
+// MarkNotificationReadHandler marks the notification in the `notificationID` URL parameter as read
+func MarkNotificationReadHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	notificationID, err := strconv.Atoi(c.Param("notificationID"))
+	if err != nil {
+		log.Errorln("Failed to mark notification as read: Error in converting notification ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	notification, err := services.MarkNotificationRead(ctx, notificationID)
+	if err != nil {
+		log.Errorln("Failed to mark notification as read: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to mark notification as read: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Notification marked as read")
+	c.JSON(http.StatusOK, dto.NotificationEnvelope{Message: "Notification marked as read", Notification: dto.NewNotificationResponse(notification)})
+}
+
+// MarkAllNotificationsReadHandler marks every notification of the user in the X-User-ID header as read
+func MarkAllNotificationsReadHandler(c *gin.Context) {
+	log := logging.FromContext(c.Request.Context())
+
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	marked := services.MarkAllNotificationsRead(c.Request.Context())
+	log.Infoln("Notifications marked as read")
+	c.JSON(http.StatusOK, dto.NotificationsReadResponse{Message: "Notifications marked as read", Marked: marked})
+}
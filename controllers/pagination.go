@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"mini-social-media-api/logging"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePagination reads the page and limit query parameters, defaulting to the first page of 10 items
+// Responds with 400 and returns ok false if either parameter is not a positive integer
+func parsePagination(c *gin.Context) (page, limit int, ok bool) {
+	log := logging.FromContext(c.Request.Context())
+
+	// Default page if not set
+	page = 1
+
+	// Parse query parameters for pagination
+	if pg, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pg)
+		if err != nil || parsedPage <= 0 {
+			log.Warnln("Invalid page query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+			return 0, 0, false
+		}
+		page = parsedPage
+	}
+
+	limit, ok = parseLimit(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+// parseCursorPagination reads the cursor and limit query parameters, defaulting to the first page of 10 items
+// The cursor is opaque and checked by the service; responds with 400 and returns ok false if the limit is invalid
+func parseCursorPagination(c *gin.Context) (cursor string, limit int, ok bool) {
+	limit, ok = parseLimit(c)
+	if !ok {
+		return "", 0, false
+	}
+	return c.Query("cursor"), limit, true
+}
+
+// parseLimit reads the limit query parameter, defaulting to 10
+// Responds with 400 and returns ok false if it is not a positive integer
+func parseLimit(c *gin.Context) (limit int, ok bool) {
+	limit = 10
+	if lt, exists := c.GetQuery("limit"); exists {
+		parsedLimit, err := strconv.Atoi(lt)
+		if err != nil || parsedLimit <= 0 {
+			logging.FromContext(c.Request.Context()).Warnln("Invalid limit query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return 0, false
+		}
+		limit = parsedLimit
+	}
+	return limit, true
+}
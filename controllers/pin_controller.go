@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PinPostHandler pins the post in the `postID` URL parameter to the profile of its author, the user in the X-User-ID header
+func PinPostHandler(c *gin.Context) {
+	changePost(c, "pin post", services.PinPost, http.StatusOK, "Pinned the post successfully")
+}
+
+// UnpinPostHandler unpins the post in the `postID` URL parameter from the profile of the user in the X-User-ID header
+func UnpinPostHandler(c *gin.Context) {
+	changePost(c, "unpin post", services.UnpinPost, http.StatusOK, "Unpinned the post successfully")
+}
+
+// GetPinnedPostsHandler lists the posts pinned by the user in the `userID` URL parameter, most recently pinned first
+func GetPinnedPostsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		log.Errorln("Failed to get pinned posts: Error in converting user ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	pinned, err := services.GetPinnedPosts(ctx, userID)
+	if err != nil {
+		log.Errorln("Failed to get pinned posts: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get pinned posts: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved pinned posts successfully")
+	c.JSON(http.StatusOK, dto.PinnedPostsResponse{Posts: dto.NewPostResponses(pinned, renderOptions(c))})
+}
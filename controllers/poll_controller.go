@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VotePollHandler records the vote of the user in the X-User-ID header in the poll of a post
+// Expects a `postID` as a URL parameter and the indexes of the chosen options as `choices` in the JSON payload
+// Returns the post with the poll results, or an error if the poll is closed or the caller already voted
+func VotePollHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to vote: Error in converting post ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	var req dto.PollVoteRequest
+	if err := bindStrictJSON(c, "vote_poll", &req); err != nil {
+		log.Errorln("Failed to vote: Error in request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. choices should list 1 to 4 option indexes")})
+		return
+	}
+
+	post, err := services.VotePoll(ctx, postID, req.Choices)
+	if err != nil {
+		log.Errorln("Failed to vote: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to vote: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Vote recorded successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Vote recorded successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
+}
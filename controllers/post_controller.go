@@ -1,221 +1,346 @@
 package controllers
 
 import (
+	"io"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
 	"mini-social-media-api/models"
 	"mini-social-media-api/services"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 // CreatePostHandler handles the creation of a new post
-// Expects a JSON payload with `content` in the request body
+// Expects a JSON payload with `content` and optional `attachments` in the request body
+// The post is attributed to the user in the X-User-ID header, or anonymous without it
 // Returns the created post or an error if the request is invalid or creation fails
 func CreatePostHandler(c *gin.Context) {
-	var req models.Post
-	err := c.ShouldBindJSON(&req) // Parse the request body into the Post model
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	var req dto.CreatePostRequest
+	err := bindStrictJSON(c, "create_post", &req) // Parse the request body, rejecting unknown fields
 	if err != nil {
-		logrus.Errorln("Failed to create the post: Invalid request body: " + err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Content should be within 1-250 characters"})
+		log.Errorln("Failed to create the post: Invalid request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. Content should be within 1-250 characters, with at most 4 attachments")})
 		return
 	}
 
+	options := services.PostOptions{
+		AuthorID:   identity.FromContext(ctx),
+		Visibility: models.Visibility(req.Visibility),
+		QuoteOf:    req.QuoteOf,
+		Status:     models.PostStatus(req.Status),
+		PublishAt:  req.PublishAt,
+		TTL:        time.Duration(req.TTLSeconds) * time.Second,
+	}
+	if req.Poll != nil {
+		options.Poll = &services.PollSpec{Options: req.Poll.Options, Multiple: req.Poll.Multiple, ClosesAt: req.Poll.ClosesAt}
+	}
+	for _, attachment := range req.Attachments {
+		options.Attachments = append(options.Attachments, services.AttachmentRef{ID: attachment.ID, AltText: attachment.AltText})
+	}
+
 	// Call the service to create a new post
-	post, err := services.CreatePost(req.Content)
+	post, err := services.CreatePostWithOptions(ctx, req.Content, options)
 	if err != nil {
-		logrus.Errorln("Failed to create the post: Error occurred in create post service: " + err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to create post: " + err.Error()})
+		log.Errorln("Failed to create the post: Error occurred in create post service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to create post: " + err.Error()})
 		return
 	}
 
-	logrus.Infoln("Post created successfully")
-	c.JSON(http.StatusCreated, gin.H{"message": "Post created successfully", "post": post})
+	log.WithField(logging.FieldPostID, post.ID).Infoln("Post created successfully")
+	c.JSON(http.StatusCreated, dto.PostEnvelope{Message: "Post created successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
 }
 
 // UpdatePostHandler handles updating an existing post
 // Expects a `postID` as a URL parameter and `content` in the JSON payload
 // Returns the updated post or an error if the post is not found or the request is invalid
 func UpdatePostHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
 	postIDParam := c.Param("postID")         // Retrieve the post ID from URL parameters
 	postID, err := strconv.Atoi(postIDParam) // Convert post ID to integer
 	if err != nil {
-		logrus.Errorln("Failed to update post: Error in converting post ID to int")
+		log.Errorln("Failed to update post: Error in converting post ID to int")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
 		return
 	}
+	log = log.WithField(logging.FieldPostID, postID)
 
-	var req models.Post
+	var req dto.UpdatePostRequest
 
-	err = c.ShouldBindJSON(&req) // Parse the request body into the Post model
+	err = bindStrictJSON(c, "update_post", &req) // Parse the request body, rejecting unknown fields
 	if err != nil {
-		logrus.Errorln("Failed to update post: Error in request body: " + err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Content should be within 1-250 characters"})
+		log.Errorln("Failed to update post: Error in request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. Content should be within 1-250 characters")})
 		return
 	}
 
 	// Call the service to update the post
-	post, err := services.UpdatePost(postID, req.Content)
+	post, err := services.UpdatePost(ctx, postID, req.Content)
 	if err != nil {
-		logrus.Errorln("Failed to update post: Error occurred in update post service: " + err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to update post: " + err.Error()})
+		log.Errorln("Failed to update post: Error occurred in update post service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to update post: " + err.Error()})
 		return
 	}
 
-	logrus.Infoln("Post updated success.  ID: " + postIDParam)
-	c.JSON(http.StatusOK, gin.H{"message": "Post updated successfully", "post": post})
+	log.Infoln("Post updated successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Post updated successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
+}
+
+// UpdatePostVisibilityHandler changes who can see a post
+// Expects a `postID` as a URL parameter and `visibility` in the JSON payload; only the author in X-User-ID may change it
+// Returns the updated post or an error if the post is not found, the caller is not the author or the request is invalid
+func UpdatePostVisibilityHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to change post visibility: Error in converting post ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	var req dto.UpdateVisibilityRequest
+	if err := bindStrictJSON(c, "set_visibility", &req); err != nil {
+		log.Errorln("Failed to change post visibility: Error in request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. Visibility should be public, unlisted, followers or private")})
+		return
+	}
+
+	post, err := services.SetPostVisibility(ctx, postID, models.Visibility(req.Visibility))
+	if err != nil {
+		log.Errorln("Failed to change post visibility: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to change post visibility: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Post visibility changed successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Post visibility changed successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
+}
+
+// DeletePostHandler deletes an existing post along with its comments
+// Expects a `postID` as a URL parameter
+// Returns the deleted post or an error if the post is not found or the ID is invalid
+func DeletePostHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to delete post: Error in converting post ID to int: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	// Call the service to delete the post
+	post, err := services.DeletePost(ctx, postID)
+	if err != nil {
+		log.Errorln("Failed to delete post: Error occurred in delete post service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to delete post: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Post deleted successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Post deleted successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
 }
 
 // LikePostHandler increments the like count for a specific post
 // Expects a `postID` as a URL parameter
 // Returns the updated post or an error if the post is not found or the ID is invalid
 func LikePostHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
 	postIDParam := c.Param("postID")
 	postID, err := strconv.Atoi(postIDParam)
 	if err != nil {
-		logrus.Errorln("Failed to like the post: Error in converting post ID to int: " + err.Error())
+		log.Errorln("Failed to like the post: Error in converting post ID to int: " + err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
 		return
 	}
+	log = log.WithField(logging.FieldPostID, postID)
 
 	// Call the service to increment likes
-	post, err := services.LikePost(postID)
+	post, err := services.LikePost(ctx, postID)
 	if err != nil {
-		logrus.Errorln("Failed to like the post: Error occurred in like post service: " + err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to like: " + err.Error()})
+		log.Errorln("Failed to like the post: Error occurred in like post service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to like: " + err.Error()})
 		return
 	}
 
-	logrus.Infoln("Like added successfully. ID: " + postIDParam)
-	c.JSON(http.StatusOK, gin.H{"message": "Liked the post successfully", "post": post})
+	log.Infoln("Like added successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Liked the post successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
 }
 
 // GetPostDetailsHandler retrieves the details of a specific post by ID
 // Expects a `postID` as a URL parameter
 // Returns the post details or an error if the post is not found
 func GetPostDetailsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
 	postIDParam := c.Param("postID")
 	postID, err := strconv.Atoi(postIDParam)
 	if err != nil {
-		logrus.Errorln("Failed to get details of the post: Error in converting post id to int: " + err.Error())
+		log.Errorln("Failed to get details of the post: Error in converting post id to int: " + err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get post details. Invalid post ID"})
 		return
 	}
+	log = log.WithField(logging.FieldPostID, postID)
 
 	// Fetch post details
-	post, err := services.GetPostDetailsByID(postID)
+	post, err := services.GetPostDetailsByID(ctx, postID)
 	if err != nil {
-		logrus.Errorln("Failed to get post details: Error occurred in get post details service: " + err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to get post details: " + err.Error()})
+		log.Errorln("Failed to get post details: Error occurred in get post details service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get post details: " + err.Error()})
 		return
 	}
 
 	// Construct and return the response
-	logrus.Infoln("Retrieved post successfully. ID: " + postIDParam)
-	c.JSON(http.StatusOK, gin.H{"post": post})
+	log.Infoln("Retrieved post successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Post: dto.NewPostResponse(post, renderOptions(c))})
 }
 
 // AddCommentHandler adds a comment to a specific post
 // Expects a `postID` as a URL parameter and comment text in the JSON payload
 // Returns the updated post or an error if the post is not found or the request is invalid
 func AddCommentHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
 	postID := c.Param("postID")
 	postIDInt, err := strconv.Atoi(postID)
 	if err != nil {
-		logrus.Errorln("Failed to add comment: Error in converting post id to int: " + err.Error())
+		log.Errorln("Failed to add comment: Error in converting post id to int: " + err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to add comment: No post found"})
 		return
 	}
+	log = log.WithField(logging.FieldPostID, postIDInt)
 
-	var reqComment models.Comment
-	err = c.ShouldBindJSON(&reqComment)
+	var reqComment dto.CreateCommentRequest
+	err = bindStrictJSON(c, "add_comment", &reqComment)
 	if err != nil {
-		logrus.Errorln("Failed to add comment: Error in request body: " + err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Text should be within 1-150 characters"})
+		log.Errorln("Failed to add comment: Error in request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. Text should be within 1-150 characters")})
 		return
 	}
 
 	// Call the service to add a comment
-	updatedPost, err := services.AddComment(postIDInt, reqComment)
+	updatedPost, err := services.AddComment(ctx, postIDInt, models.Comment{Text: reqComment.Text})
 	if err != nil {
-		logrus.Errorln("Failed to add comment: Error occurred in add comment service")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to add the comment: " + err.Error()})
+		log.Errorln("Failed to add comment: Error occurred in add comment service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to add the comment: " + err.Error()})
 		return
 	}
 
-	logrus.Infof("Comment added to post %v successfully \n", postID)
-	c.JSON(http.StatusOK, gin.H{"message": "Comment added successfully", "post": updatedPost})
+	log.Infoln("Comment added successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Comment added successfully", Post: dto.NewPostResponse(updatedPost, renderOptions(c))})
 }
 
 // GetAllPostsHandlerWithPagination retrieves all posts from the in-memory storage with pagination support
 // Returns the paginated list of posts
 func GetAllPostsHandlerWithPagination(c *gin.Context) {
-	// Default pagination parameters if not set
-	page := 1
-	limit := 10
-
-	// Parse query parameters for pagination
-	pg, exists := c.GetQuery("page")
-	if exists {
-		parsedPage, err := strconv.Atoi(pg)
-		if err == nil && parsedPage > 0 {
-			page = parsedPage
-		} else {
-			logrus.Warnln("Invalid page query parameter")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
-			return
-		}
-	}
-
-	if lt, exists := c.GetQuery("limit"); exists {
-		parsedLimit, err := strconv.Atoi(lt)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		} else {
-			logrus.Warnln("Invalid limit query parameter")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
-			return
-		}
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
 	}
 
 	// Get all posts from the service
-	posts := services.GetAllPosts()
+	posts := services.GetAllPosts(ctx)
 	totalPosts := len(posts)
 
 	if totalPosts == 0 {
-		logrus.Infoln("Failed to retrieve posts: No posts found")
-		c.JSON(http.StatusOK, gin.H{"message": "No posts found"})
+		log.Infoln("Failed to retrieve posts: No posts found")
+		c.JSON(http.StatusOK, dto.PostsPage{Message: "No posts found"})
 		return
 	}
 
-	// Calculate pagination boundaries
-	startIndex := (page - 1) * limit
-	endIndex := startIndex + limit
-
-	if startIndex >= totalPosts {
-		logrus.Infoln("Page out of range: No posts found")
-		c.JSON(http.StatusOK, gin.H{
-			"message": "No posts found",
-			"page":    page,
-			"limit":   limit,
-			"total":   totalPosts,
+	// Compare pages before multiplying so huge page numbers cannot overflow the start index
+	if page-1 > (totalPosts-1)/limit {
+		log.Infoln("Page out of range: No posts found")
+		c.JSON(http.StatusOK, dto.PostsPage{
+			Message: "No posts found",
+			Page:    page,
+			Limit:   limit,
+			Total:   totalPosts,
 		})
 		return
 	}
 
-	if endIndex > totalPosts {
-		endIndex = totalPosts
-	}
-
 	// Paginate posts
+	startIndex := (page - 1) * limit
+	endIndex := min(startIndex+limit, totalPosts)
 	paginatedPosts := posts[startIndex:endIndex]
 
-	logrus.Infof("Retrieved posts for page %d with limit %d", page, limit)
-	c.JSON(http.StatusOK, gin.H{
-		"posts": paginatedPosts,
-		"page":  page,
-		"limit": limit,
-		"total": totalPosts,
+	log.WithFields(logrus.Fields{"page": page, "limit": limit}).Infoln("Retrieved posts")
+	c.JSON(http.StatusOK, dto.PostsPage{
+		Posts: dto.NewPostResponses(paginatedPosts, renderOptions(c)),
+		Page:  page,
+		Limit: limit,
+		Total: totalPosts,
 	})
 }
+
+// maxPatchBodySize bounds the size of patch documents accepted by PatchPostHandler
+const maxPatchBodySize = 64 << 10
+
+// PatchPostHandler applies a partial update to an existing post
+// Expects a `postID` as a URL parameter and a JSON Merge Patch (application/merge-patch+json)
+// or JSON Patch (application/json-patch+json) document in the request body
+// Returns the updated post or an error if the post is not found, the patch is invalid or it changes a read-only field
+func PatchPostHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to patch post: Error in converting post ID to int: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	format := services.PatchFormat(c.ContentType())
+	if format != services.MergePatch && format != services.JSONPatch {
+		log.Errorln("Failed to patch post: Unsupported content type " + c.ContentType())
+		c.Header("Accept-Patch", string(services.MergePatch)+", "+string(services.JSONPatch))
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch format. Use application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+
+	document, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBodySize+1))
+	if err != nil || len(document) > maxPatchBodySize {
+		log.Errorln("Failed to patch post: Unable to read request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. Patch document is missing or too large"})
+		return
+	}
+
+	// Call the service to apply the patch
+	post, err := services.PatchPost(ctx, postID, format, document, renderOptions(c))
+	if err != nil {
+		log.Errorln("Failed to patch post: Error occurred in patch post service: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to patch post: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Post patched successfully")
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: "Post patched successfully", Post: dto.NewPostResponse(post, renderOptions(c))})
+}
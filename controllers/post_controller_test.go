@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/metrics"
+	"mini-social-media-api/models"
+	"mini-social-media-api/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCreatePostHandlerRequestBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/posts/", CreatePostHandler)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid body", `{"content": "Hello"}`, http.StatusCreated},
+		{"Unknown field likes", `{"content": "Hello", "likes": 100}`, http.StatusBadRequest},
+		{"Unknown field id", `{"content": "Hello", "id": 7}`, http.StatusBadRequest},
+		{"Unknown field comments", `{"content": "Hello", "comments": []}`, http.StatusBadRequest},
+		{"Missing content", `{}`, http.StatusBadRequest},
+		{"Trailing data", `{"content": "Hello"} {"content": "again"}`, http.StatusBadRequest},
+		{"Malformed JSON", `{"content": `, http.StatusBadRequest},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/posts/", strings.NewReader(testCase.body)))
+
+			if w.Code != testCase.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", testCase.wantStatus, w.Code, w.Body.String())
+			}
+
+			if testCase.wantStatus == http.StatusCreated {
+				var body struct {
+					Post map[string]interface{} `json:"post"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				for _, field := range []string{"id", "content", "likes", "comments", "created_at", "updated_at"} {
+					if _, exists := body.Post[field]; !exists {
+						t.Errorf("Expected post field '%s' in response", field)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePostHandlerCountsRejections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/posts/", CreatePostHandler)
+	counter := metrics.ValidationRejectionsTotal.WithLabelValues("create_post")
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCounted float64
+	}{
+		{"Valid body", `{"content": "Hello"}`, 0},
+		{"Content over 250 characters", `{"content": "` + strings.Repeat("a", 251) + `"}`, 1},
+		{"Empty content", `{"content": ""}`, 1},
+		{"Unknown field", `{"content": "Hello", "likes": 100}`, 1},
+		{"Malformed JSON", `{"content": `, 1},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			before := testutil.ToFloat64(counter)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/posts/", strings.NewReader(testCase.body)))
+
+			if got := testutil.ToFloat64(counter) - before; got != testCase.wantCounted {
+				t.Errorf("Expected the create_post rejection counter to increase by %v, got %v (status %d)", testCase.wantCounted, got, w.Code)
+			}
+		})
+	}
+}
+
+func TestLikePostHandlerStatuses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/posts/:postID/like", LikePostHandler)
+
+	author, err := services.CreateUser(context.Background(), "like_handler_author", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asAuthor := identity.NewContext(context.Background(), author.ID)
+	published, _ := services.CreatePostWithOptions(asAuthor, "Published", services.PostOptions{AuthorID: author.ID})
+	draft, _ := services.CreatePostWithOptions(asAuthor, "Draft", services.PostOptions{AuthorID: author.ID, Status: models.StatusDraft})
+
+	tests := []struct {
+		name       string
+		postID     string
+		wantStatus int
+	}{
+		{"Published post", fmt.Sprint(published.ID), http.StatusOK},
+		{"Unpublished post", fmt.Sprint(draft.ID), http.StatusUnprocessableEntity},
+		{"Unknown post", "999999", http.StatusNotFound},
+		{"Invalid post ID", "abc", http.StatusBadRequest},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/posts/"+testCase.postID+"/like", nil).WithContext(asAuthor)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+
+			if w.Code != testCase.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreatePostHandlerRendersHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/posts/", CreatePostHandler)
+
+	tests := []struct {
+		name     string
+		query    string
+		content  string
+		wantHTML string
+	}{
+		{"Without render", "", "**hi**", ""},
+		{"Markdown", "?render=html", "**hi** #go", `<strong>hi</strong> <a class="hashtag" href="/v1/tags/go/posts">#go</a>`},
+		{"Script is escaped", "?render=html", "<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"Attribute injection", "?render=html", `https://a.b/"onclick="x`, `<a class="url" href="https://a.b/" rel="nofollow noopener noreferrer" target="_blank">https://a.b/</a>&#34;onclick=&#34;x`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"content": testCase.content})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/posts/"+testCase.query, strings.NewReader(string(body))))
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+			var response struct {
+				Post struct {
+					Entities    []map[string]interface{} `json:"entities"`
+					ContentHTML *string                  `json:"content_html"`
+				} `json:"post"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Post.Entities == nil {
+				t.Errorf("Expected entities in response")
+			}
+			if testCase.wantHTML == "" && response.Post.ContentHTML != nil {
+				t.Errorf("Expected no content_html without render=html, got %q", *response.Post.ContentHTML)
+			}
+			if testCase.wantHTML != "" && (response.Post.ContentHTML == nil || *response.Post.ContentHTML != testCase.wantHTML) {
+				t.Errorf("Expected content_html %q, got %v", testCase.wantHTML, response.Post.ContentHTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// renderOptions reads the optional representations requested with the `render` query parameter
+// render=html adds the sanitized HTML rendering of the text of posts and comments
+func renderOptions(c *gin.Context) dto.RenderOptions {
+	return dto.RenderOptions{HTML: c.Query("render") == "html"}
+}
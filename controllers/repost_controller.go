@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RepostPostHandler reshares the post in the `postID` URL parameter as the user in the X-User-ID header
+// Returns the repost with the original embedded, or an error if the post cannot be shared or is already reposted
+func RepostPostHandler(c *gin.Context) {
+	changePost(c, "repost", services.Repost, http.StatusCreated, "Reposted the post successfully")
+}
+
+// UndoRepostHandler deletes the repost of the post in the `postID` URL parameter by the user in the X-User-ID header
+func UndoRepostHandler(c *gin.Context) {
+	changePost(c, "undo repost", services.UndoRepost, http.StatusOK, "Repost removed successfully")
+}
+
+// changePost applies a change of the calling user to a post, such as a repost or a bookmark, and responds with the post
+func changePost(c *gin.Context, action string, apply func(ctx context.Context, id int) (models.Post, error), status int, message string) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to " + action + ": Error in converting post ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	post, err := apply(ctx, postID)
+	if err != nil {
+		log.Errorln("Failed to " + action + ": " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to " + action + ": " + err.Error()})
+		return
+	}
+
+	log.Infoln(message)
+	c.JSON(status, dto.PostEnvelope{Message: message, Post: dto.NewPostResponse(post, renderOptions(c))})
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"mini-social-media-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockUserHandler makes the user in the X-User-ID header block the user in the `userID` URL parameter
+// Blocking removes the follows between both users and hides their posts from each other
+func BlockUserHandler(c *gin.Context) {
+	changeRelation(c, "block", services.BlockUser, "Blocked user successfully")
+}
+
+// UnblockUserHandler removes the block of the user in the `userID` URL parameter by the user in the X-User-ID header
+func UnblockUserHandler(c *gin.Context) {
+	changeRelation(c, "unblock", services.UnblockUser, "Unblocked user successfully")
+}
+
+// MuteUserHandler hides the posts and comments of the user in the `userID` URL parameter
+// from the listings of the user in the X-User-ID header, without telling the muted user
+func MuteUserHandler(c *gin.Context) {
+	changeRelation(c, "mute", services.MuteUser, "Muted user successfully")
+}
+
+// UnmuteUserHandler removes the mute of the user in the `userID` URL parameter by the user in the X-User-ID header
+func UnmuteUserHandler(c *gin.Context) {
+	changeRelation(c, "unmute", services.UnmuteUser, "Unmuted user successfully")
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetScheduledPostsHandler lists the drafts and scheduled posts of the user in the X-User-ID header
+// Supports `page` and `limit` query parameters
+func GetScheduledPostsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	scheduled, total, err := services.GetScheduledPosts(ctx, page, limit)
+	if err != nil {
+		log.Errorln("Failed to get scheduled posts: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get scheduled posts: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved scheduled posts successfully")
+	c.JSON(http.StatusOK, dto.ScheduledPostsPage{
+		Posts: dto.NewPostResponses(scheduled, renderOptions(c)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// SchedulePostHandler sets when the unpublished post in the `postID` URL parameter is published
+// Expects `publish_at` in the JSON payload; null turns the post back into a draft
+func SchedulePostHandler(c *gin.Context) {
+	var req dto.SchedulePostRequest
+	if err := bindStrictJSON(c, "schedule_post", &req); err != nil {
+		logging.FromContext(c.Request.Context()).Errorln("Failed to schedule post: Error in request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. publish_at should be an RFC 3339 time or null")})
+		return
+	}
+
+	changeSchedule(c, "schedule post", func(ctx context.Context, id int) (models.Post, error) {
+		return services.SchedulePost(ctx, id, req.PublishAt)
+	}, "Post scheduled successfully")
+}
+
+// UnschedulePostHandler cancels the publication of the scheduled post in the `postID` URL parameter,
+// which becomes a draft
+func UnschedulePostHandler(c *gin.Context) {
+	changeSchedule(c, "cancel scheduled post", func(ctx context.Context, id int) (models.Post, error) {
+		return services.SchedulePost(ctx, id, nil)
+	}, "Scheduled post cancelled successfully")
+}
+
+// PublishPostHandler publishes the draft or scheduled post in the `postID` URL parameter at once
+func PublishPostHandler(c *gin.Context) {
+	changeSchedule(c, "publish post", services.PublishPost, "Post published successfully")
+}
+
+// changeSchedule applies a change to an unpublished post of the calling user and responds with the post
+func changeSchedule(c *gin.Context, action string, apply func(ctx context.Context, id int) (models.Post, error), message string) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	postID, err := strconv.Atoi(c.Param("postID"))
+	if err != nil {
+		log.Errorln("Failed to " + action + ": Error in converting post ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	log = log.WithField(logging.FieldPostID, postID)
+
+	if _, ok := requireCaller(c); !ok {
+		return
+	}
+
+	post, err := apply(ctx, postID)
+	if err != nil {
+		log.Errorln("Failed to " + action + ": " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to " + action + ": " + err.Error()})
+		return
+	}
+
+	log.Infoln(message)
+	c.JSON(http.StatusOK, dto.PostEnvelope{Message: message, Post: dto.NewPostResponse(post, renderOptions(c))})
+}
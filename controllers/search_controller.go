@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchPostsHandler searches the content and comments of posts
+// Expects the query in the `q` query parameter, with optional `page` and `limit` for pagination
+// Returns the matching posts ranked by relevance, each with a highlighted snippet
+func SearchPostsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := c.Query("q")
+	results, total, err := services.SearchPosts(ctx, query, page, limit)
+	if err != nil {
+		log.Warnln("Failed to search posts: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	response := dto.SearchResponse{
+		Query:   query,
+		Results: make([]dto.SearchResult, 0, len(results)),
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	}
+	for _, result := range results {
+		response.Results = append(response.Results, dto.SearchResult{
+			Post:    dto.NewPostResponse(result.Post, renderOptions(c)),
+			Score:   result.Score,
+			Snippet: result.Snippet,
+		})
+	}
+
+	log.WithField("total", total).Infoln("Search completed successfully")
+	c.JSON(http.StatusOK, response)
+}
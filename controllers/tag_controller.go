@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/hashtag"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the limit query parameter of trending tags
+const (
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+// GetPostsByTagHandler retrieves the posts using a hashtag
+// Expects the `tag` URL parameter, with optional `page` and `limit` query parameters for pagination
+// Returns the page of tagged posts, most recently tagged first
+func GetPostsByTagHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx).WithField("hashtag", c.Param("tag"))
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	tagged, total, err := services.GetPostsByTag(ctx, c.Param("tag"), page, limit)
+	if err != nil {
+		log.Warnln("Failed to retrieve tagged posts: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, _ := hashtag.Normalize(c.Param("tag")) // Already validated by the service
+	log.Infoln("Tagged posts retrieved successfully")
+	c.JSON(http.StatusOK, dto.TagPostsPage{
+		Tag:   tag,
+		Posts: dto.NewPostResponses(tagged, renderOptions(c)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// GetTrendingTagsHandler lists the hashtags rising fastest
+// Accepts an optional `window` query parameter (1h or 24h, default 1h) and `limit` (1-50, default 10)
+// Returns the trending tags with their counts and velocity scores
+func GetTrendingTagsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	windowParam := c.DefaultQuery("window", "1h")
+	window, ok := services.TrendingWindows[windowParam]
+	if !ok {
+		log.Warnln("Invalid window query parameter")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window parameter. Supported windows are 1h and 24h"})
+		return
+	}
+
+	limit := defaultTrendingLimit
+	if lt, exists := c.GetQuery("limit"); exists {
+		parsedLimit, err := strconv.Atoi(lt)
+		if err != nil || parsedLimit <= 0 || parsedLimit > maxTrendingLimit {
+			log.Warnln("Invalid limit query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter. Limit should be within 1-50"})
+			return
+		}
+		limit = parsedLimit
+	}
+
+	trends := services.GetTrendingTags(ctx, window, limit)
+	response := dto.TrendingTagsResponse{Window: windowParam, Tags: make([]dto.TrendingTag, 0, len(trends))}
+	for _, trend := range trends {
+		response.Tags = append(response.Tags, dto.TrendingTag{
+			Tag:      trend.Tag,
+			Count:    trend.Count,
+			Expected: trend.Expected,
+			Velocity: trend.Velocity,
+		})
+	}
+
+	log.Infoln("Trending tags retrieved successfully")
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetHomeTimelineHandler lists the posts of the user in the X-User-ID header and of the accounts they follow, newest first
+// Supports `page` and `limit` query parameters
+func GetHomeTimelineHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	timeline, total, err := services.GetHomeTimeline(ctx, userID, page, limit)
+	if err != nil {
+		log.Errorln("Failed to get home timeline: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get home timeline: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved home timeline successfully")
+	c.JSON(http.StatusOK, dto.TimelinePage{
+		Posts: dto.NewPostResponses(timeline, renderOptions(c)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"mini-social-media-api/dto"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateUserHandler registers a new user
+// Expects a JSON payload with `username` and an optional `display_name`
+// Returns the created user or an error if the username is invalid or taken
+func CreateUserHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	var req dto.CreateUserRequest
+	if err := bindStrictJSON(c, "create_user", &req); err != nil {
+		log.Errorln("Failed to create the user: Invalid request body: " + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err, "Invalid request. Username should be within 1-30 characters")})
+		return
+	}
+
+	user, err := services.CreateUser(ctx, req.Username, req.DisplayName)
+	if err != nil {
+		log.Errorln("Failed to create the user: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to create user: " + err.Error()})
+		return
+	}
+
+	log.WithField(logging.FieldUserID, user.ID).Infoln("User created successfully")
+	c.JSON(http.StatusCreated, dto.UserEnvelope{Message: "User created successfully", User: dto.NewUserResponse(user)})
+}
+
+// GetUserHandler retrieves a user by the `userID` URL parameter
+func GetUserHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		log.Errorln("Failed to get user: Error in converting user ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	user, err := services.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Errorln("Failed to get user: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get user: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved user successfully")
+	c.JSON(http.StatusOK, dto.UserEnvelope{User: dto.NewUserResponse(user)})
+}
+
+// GetUserMentionsHandler retrieves the posts mentioning a user in their content or comments
+// Expects a `userID` URL parameter, with optional `page` and `limit` query parameters for pagination
+// Returns the page of posts, newest first
+func GetUserMentionsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logging.FromContext(ctx)
+
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		log.Errorln("Failed to get mentions: Error in converting user ID to int")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	log = log.WithField(logging.FieldUserID, userID)
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	user, err := services.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Errorln("Failed to get mentions: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get mentions: " + err.Error()})
+		return
+	}
+
+	mentioning, total, err := services.GetPostsMentioningUser(ctx, userID, page, limit)
+	if err != nil {
+		log.Errorln("Failed to get mentions: " + err.Error())
+		c.JSON(statusForServiceError(err), gin.H{"error": "Failed to get mentions: " + err.Error()})
+		return
+	}
+
+	log.Infoln("Retrieved mentions successfully")
+	c.JSON(http.StatusOK, dto.MentionsPage{
+		User:  dto.NewUserResponse(user),
+		Posts: dto.NewPostResponses(mentioning, renderOptions(c)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
@@ -0,0 +1,80 @@
+package dto
+
+import "time"
+
+// CreatePostRequest is the body accepted when creating a post
+type CreatePostRequest struct {
+	Content     string              `json:"content" binding:"required,max=250"`
+	Visibility  string              `json:"visibility,omitempty" binding:"omitempty,oneof=public unlisted followers private" description:"Who can see the post (default public); anonymous posts are always public"`
+	Attachments []AttachmentRequest `json:"attachments,omitempty" binding:"max=4,dive" description:"Uploaded media to attach, each usable by a single post"`
+	QuoteOf     int                 `json:"quote_of,omitempty" binding:"omitempty,min=1" description:"Public or unlisted post to quote"`
+	Status      string              `json:"status,omitempty" binding:"omitempty,oneof=published draft scheduled" description:"draft keeps the post to the caller until published; defaults to published, or scheduled with publish_at"`
+	PublishAt   *time.Time          `json:"publish_at,omitempty" description:"Future time at which to publish the post"`
+	TTLSeconds  int                 `json:"ttl_seconds,omitempty" binding:"omitempty,min=60,max=604800" description:"Seconds after which the post disappears, like a story; published posts only"`
+	Poll        *PollRequest        `json:"poll,omitempty"`
+}
+
+// AttachmentRequest references media uploaded with POST /media
+type AttachmentRequest struct {
+	ID      string `json:"id" binding:"required"`
+	AltText string `json:"alt_text,omitempty" binding:"max=1000" description:"Replaces the alt text given at upload"`
+}
+
+// PollRequest describes the poll of a new post
+type PollRequest struct {
+	Options  []string  `json:"options" binding:"required,min=2,max=4,dive,required,max=50"`
+	Multiple bool      `json:"multiple,omitempty" description:"Let voters choose several options"`
+	ClosesAt time.Time `json:"closes_at" binding:"required" description:"When voting ends, within 30 days of publication"`
+}
+
+// PollVoteRequest is the body accepted when voting in a poll
+type PollVoteRequest struct {
+	Choices []int `json:"choices" binding:"required,min=1,max=4" description:"Indexes of the chosen options, a single one unless the poll allows several"`
+}
+
+// UpdatePostRequest is the body accepted when updating a post
+type UpdatePostRequest struct {
+	Content string `json:"content" binding:"required,max=250"`
+}
+
+// UpdateVisibilityRequest is the body accepted when changing who can see a post
+type UpdateVisibilityRequest struct {
+	Visibility string `json:"visibility" binding:"required,oneof=public unlisted followers private"`
+}
+
+// SchedulePostRequest is the body accepted when choosing when an unpublished post is published
+type SchedulePostRequest struct {
+	PublishAt *time.Time `json:"publish_at" description:"Future time at which to publish the post; null turns it back into a draft"`
+}
+
+// CreateCommentRequest is the body accepted when commenting on a post
+type CreateCommentRequest struct {
+	Text string `json:"text" binding:"required,max=150"`
+}
+
+// CreateUserRequest is the body accepted when registering a user
+type CreateUserRequest struct {
+	Username    string `json:"username" binding:"required,max=30" description:"Letters, digits and underscores, unique ignoring case"`
+	DisplayName string `json:"display_name,omitempty" binding:"max=50" description:"Defaults to the username"`
+}
+
+// PostMergePatch documents the fields that may be set in a JSON Merge Patch of a post
+// Every other post field is read only
+type PostMergePatch struct {
+	Content    *string `json:"content,omitempty" binding:"max=250"`
+	Visibility *string `json:"visibility,omitempty" binding:"omitempty,oneof=public unlisted followers private" description:"Who can see the post; anonymous posts are always public"`
+}
+
+// BatchRequest is the body accepted by the batch endpoint
+type BatchRequest struct {
+	Atomic     bool                    `json:"atomic" description:"Roll back every operation if any operation fails"`
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+// BatchOperationRequest is a single operation of a batch
+// post_id is required by update, delete and like; content by create and update
+type BatchOperationRequest struct {
+	Op      string `json:"op" binding:"required,oneof=create update delete like"`
+	PostID  int    `json:"post_id,omitempty"`
+	Content string `json:"content,omitempty"`
+}
@@ -0,0 +1,479 @@
+package dto
+
+import (
+	"mini-social-media-api/models"
+	"time"
+)
+
+// PostResponse is the wire representation of a post
+type PostResponse struct {
+	ID          int                   `json:"id"`
+	AuthorID    int                   `json:"author_id,omitempty" description:"User who wrote the post, absent for anonymous posts"`
+	Visibility  string                `json:"visibility" description:"public, unlisted (hidden from listings), followers or private (author only)"`
+	Status      string                `json:"status" description:"published, or draft or scheduled while only the author can see the post"`
+	PublishAt   *time.Time            `json:"publish_at,omitempty" description:"When a scheduled post is published"`
+	ExpiresAt   *time.Time            `json:"expires_at,omitempty" description:"When the post disappears, for expiring posts"`
+	Content     string                `json:"content"`
+	Hashtags    []string              `json:"hashtags" description:"Normalized hashtags found in the content, without the leading #"`
+	Mentions    []MentionResponse     `json:"mentions"`
+	Entities    []EntityResponse      `json:"entities" description:"URLs, hashtags, mentions and Markdown spans of the content"`
+	ContentHTML string                `json:"content_html,omitempty" description:"Sanitized HTML rendering of the content, only with render=html"`
+	Previews    []LinkPreviewResponse `json:"previews" description:"Previews of the links in the content, added in the background once fetched"`
+	Attachments []AttachmentResponse  `json:"attachments"`
+	Poll        *PollResponse         `json:"poll,omitempty"`
+	Likes       int                   `json:"likes"`
+	Reposts     int                   `json:"reposts"`
+	Quotes      int                   `json:"quotes"`
+	Comments    []CommentResponse     `json:"comments"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+
+	RepostOf            int           `json:"repost_of,omitempty" description:"Post reshared by this repost, which has no content of its own"`
+	QuoteOf             int           `json:"quote_of,omitempty" description:"Post quoted by this post"`
+	Original            *PostResponse `json:"original,omitempty" description:"The reposted or quoted post, as seen by the caller"`
+	OriginalUnavailable bool          `json:"original_unavailable,omitempty" description:"Set when the reposted or quoted post was deleted or cannot be seen by the caller"`
+}
+
+// CommentResponse is the wire representation of a comment
+type CommentResponse struct {
+	ID        int               `json:"id"`
+	AuthorID  int               `json:"author_id,omitempty" description:"User who wrote the comment, absent for anonymous comments"`
+	Text      string            `json:"text"`
+	Mentions  []MentionResponse `json:"mentions"`
+	Entities  []EntityResponse  `json:"entities" description:"URLs, hashtags, mentions and Markdown spans of the text"`
+	TextHTML  string            `json:"text_html,omitempty" description:"Sanitized HTML rendering of the text, only with render=html"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// MentionResponse is the wire representation of an @mention
+type MentionResponse struct {
+	Username string `json:"username" description:"Username as written, without the @"`
+	UserID   int    `json:"user_id,omitempty" description:"Mentioned user, absent when no user has the username"`
+	Resolved bool   `json:"resolved" description:"Whether the username belonged to a user when the text was written"`
+	Start    int    `json:"start" description:"Offset of the @ in the text, in characters"`
+	End      int    `json:"end" description:"Offset just after the username, in characters"`
+}
+
+// LinkPreviewResponse is the wire representation of a link preview
+type LinkPreviewResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"site_name"`
+}
+
+// PollResponse is the wire representation of a poll as the caller sees it
+type PollResponse struct {
+	Options     []PollOptionResponse `json:"options"`
+	Multiple    bool                 `json:"multiple" description:"Voters may choose several options"`
+	ClosesAt    time.Time            `json:"closes_at"`
+	Closed      bool                 `json:"closed"`
+	ShowResults bool                 `json:"show_results" description:"Votes are shown once the caller voted, to the author and once the poll closed"`
+	Voters      *int                 `json:"voters,omitempty" description:"Number of users who voted, with the results"`
+	Voted       []int                `json:"voted,omitempty" description:"Indexes of the options chosen by the caller"`
+}
+
+// PollOptionResponse is the wire representation of a poll option
+type PollOptionResponse struct {
+	Text  string `json:"text"`
+	Votes *int   `json:"votes,omitempty" description:"Only with the results"`
+}
+
+// AttachmentResponse is the wire representation of an uploaded image
+type AttachmentResponse struct {
+	ID           string    `json:"id"`
+	URL          string    `json:"url" description:"Image with its metadata stripped"`
+	ThumbnailURL string    `json:"thumbnail_url" description:"JPEG fitting in 320x320 pixels"`
+	ContentType  string    `json:"content_type"`
+	Size         int       `json:"size" description:"Size of the image in bytes"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	AltText      string    `json:"alt_text"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MediaEnvelope wraps a single uploaded image, with a message for mutations
+type MediaEnvelope struct {
+	Message string             `json:"message,omitempty"`
+	Media   AttachmentResponse `json:"media"`
+}
+
+// UserResponse is the wire representation of a user
+type UserResponse struct {
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserEnvelope wraps a single user, with a message for mutations
+type UserEnvelope struct {
+	Message string       `json:"message,omitempty"`
+	User    UserResponse `json:"user"`
+}
+
+// MentionsPage is a page of the posts mentioning a user, newest first
+type MentionsPage struct {
+	User  UserResponse   `json:"user"`
+	Posts []PostResponse `json:"posts"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
+
+// UsersPage is a page of the followers or followed accounts of a user, most recent follow first
+type UsersPage struct {
+	User  UserResponse   `json:"user"`
+	Users []UserResponse `json:"users"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
+
+// TimelinePage is a page of a home timeline, newest first
+type TimelinePage struct {
+	Posts []PostResponse `json:"posts"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total" description:"Posts in the timeline, which holds the newest 800"`
+}
+
+// ScheduledPostsPage is a page of the drafts and scheduled posts of the caller
+type ScheduledPostsPage struct {
+	Posts []PostResponse `json:"posts" description:"Scheduled posts, the next to be published first, then drafts, the newest first"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
+
+// PinnedPostsResponse lists the posts pinned by a user, most recently pinned first
+type PinnedPostsResponse struct {
+	Posts []PostResponse `json:"posts"`
+}
+
+// BookmarksPage is a page of the bookmarks of the caller, most recently bookmarked first
+type BookmarksPage struct {
+	Posts      []PostResponse `json:"posts"`
+	Limit      int            `json:"limit"`
+	NextCursor string         `json:"next_cursor,omitempty" description:"Pass as cursor to get the next page, absent on the last page"`
+}
+
+// NotificationResponse is the wire representation of a notification
+type NotificationResponse struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type" description:"like, comment, mention or follow"`
+	PostID      int       `json:"post_id,omitempty" description:"Post the activity is about, absent for follows"`
+	ActorIDs    []int     `json:"actor_ids" description:"Users behind the most recent activity, most recent first, at most 3"`
+	OtherActors int       `json:"other_actors,omitempty" description:"Number of other users behind the activity"`
+	Anonymous   int       `json:"anonymous,omitempty" description:"Times anonymous callers did it"`
+	Summary     string    `json:"summary" description:"e.g. alice and 12 others liked your post"`
+	Read        bool      `json:"read"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NotificationsPage is a page of the notifications of the caller, most recently updated first
+type NotificationsPage struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	Page          int                    `json:"page"`
+	Limit         int                    `json:"limit"`
+	Total         int                    `json:"total"`
+	Unread        int                    `json:"unread" description:"Unread notifications in total"`
+}
+
+// NotificationEnvelope wraps a single notification, with a message for mutations
+type NotificationEnvelope struct {
+	Message      string               `json:"message,omitempty"`
+	Notification NotificationResponse `json:"notification"`
+}
+
+// NotificationsReadResponse reports how many notifications were marked as read
+type NotificationsReadResponse struct {
+	Message string `json:"message"`
+	Marked  int    `json:"marked"`
+}
+
+// PostEnvelope wraps a single post, with a message for mutations
+type PostEnvelope struct {
+	Message string       `json:"message,omitempty"`
+	Post    PostResponse `json:"post"`
+}
+
+// DeletedPostEvent is the data of a post.deleted event; other post events carry the whole post
+type DeletedPostEvent struct {
+	ID int `json:"id"`
+}
+
+// PostsPage is a page of posts; only the message and pagination fields are set when the page is empty
+type PostsPage struct {
+	Message string         `json:"message,omitempty"`
+	Posts   []PostResponse `json:"posts,omitempty"`
+	Page    int            `json:"page,omitempty"`
+	Limit   int            `json:"limit,omitempty"`
+	Total   int            `json:"total,omitempty"`
+}
+
+// SearchResult is a post matching a search query
+type SearchResult struct {
+	Post    PostResponse `json:"post"`
+	Score   float64      `json:"score" description:"Relevance of the post, higher is better"`
+	Snippet string       `json:"snippet" description:"HTML escaped excerpt with matches wrapped in <mark>"`
+}
+
+// SearchResponse is a page of search results, best match first
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+	Total   int            `json:"total"`
+}
+
+// TagPostsPage is a page of the posts using a hashtag, most recently tagged first
+type TagPostsPage struct {
+	Tag   string         `json:"tag"`
+	Posts []PostResponse `json:"posts"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int            `json:"total"`
+}
+
+// TrendingTag is a hashtag used more than usual in the trending window
+type TrendingTag struct {
+	Tag      string  `json:"tag"`
+	Count    int     `json:"count" description:"Posts tagged in the window"`
+	Expected float64 `json:"expected" description:"Usual number of posts tagged per window, averaged over the previous six windows"`
+	Velocity float64 `json:"velocity" description:"How far the count is above the usual rate, in standard deviations"`
+}
+
+// TrendingTagsResponse lists trending hashtags, fastest rising first
+type TrendingTagsResponse struct {
+	Window string        `json:"window"`
+	Tags   []TrendingTag `json:"tags"`
+}
+
+// ErrorResponse is returned for every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// RenderOptions selects optional representations of the text of posts and comments
+type RenderOptions struct {
+	HTML bool // Add the sanitized HTML rendering
+}
+
+// NewPostResponse maps a stored post to its wire representation
+func NewPostResponse(post models.Post, options RenderOptions) PostResponse {
+	comments := make([]CommentResponse, 0, len(post.Comments))
+	for _, comment := range post.Comments {
+		comments = append(comments, NewCommentResponse(comment, options))
+	}
+
+	entities := extractEntities(post.Content, post.Mentions)
+	response := PostResponse{
+		ID:          post.ID,
+		AuthorID:    post.AuthorID,
+		Visibility:  string(post.Visibility),
+		Status:      string(post.Status),
+		PublishAt:   post.PublishAt,
+		ExpiresAt:   post.ExpiresAt,
+		Content:     post.Content,
+		Hashtags:    append([]string{}, post.Hashtags...),
+		Mentions:    NewMentionResponses(post.Mentions),
+		Entities:    NewEntityResponses(entities),
+		Previews:    NewLinkPreviewResponses(post.Previews),
+		Attachments: NewAttachmentResponses(post.Attachments),
+		Likes:       post.Likes,
+		Reposts:     post.Reposts,
+		Quotes:      post.Quotes,
+		Comments:    comments,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		RepostOf:    post.RepostOf,
+		QuoteOf:     post.QuoteOf,
+	}
+	if options.HTML {
+		response.ContentHTML = renderHTML(post.Content, entities)
+	}
+	if post.Poll != nil {
+		poll := NewPollResponse(*post.Poll)
+		response.Poll = &poll
+	}
+	if post.Original != nil {
+		original := NewPostResponse(*post.Original, options)
+		response.Original = &original
+	} else if post.RepostOf != 0 || post.QuoteOf != 0 {
+		response.OriginalUnavailable = true
+	}
+	return response
+}
+
+// NewPostResponses maps a list of stored posts to their wire representation
+func NewPostResponses(posts []models.Post, options RenderOptions) []PostResponse {
+	responses := make([]PostResponse, 0, len(posts))
+	for _, post := range posts {
+		responses = append(responses, NewPostResponse(post, options))
+	}
+	return responses
+}
+
+// NewCommentResponse maps a stored comment to its wire representation
+func NewCommentResponse(comment models.Comment, options RenderOptions) CommentResponse {
+	entities := extractEntities(comment.Text, comment.Mentions)
+	response := CommentResponse{
+		ID:        comment.ID,
+		AuthorID:  comment.AuthorID,
+		Text:      comment.Text,
+		Mentions:  NewMentionResponses(comment.Mentions),
+		Entities:  NewEntityResponses(entities),
+		CreatedAt: comment.CreatedAt,
+	}
+	if options.HTML {
+		response.TextHTML = renderHTML(comment.Text, entities)
+	}
+	return response
+}
+
+// NewMentionResponses maps stored mentions to their wire representation
+func NewMentionResponses(mentions []models.Mention) []MentionResponse {
+	responses := make([]MentionResponse, 0, len(mentions))
+	for _, m := range mentions {
+		responses = append(responses, MentionResponse{
+			Username: m.Username,
+			UserID:   m.UserID,
+			Resolved: m.UserID != 0,
+			Start:    m.Start,
+			End:      m.End,
+		})
+	}
+	return responses
+}
+
+// NewLinkPreviewResponses maps stored link previews to their wire representation
+func NewLinkPreviewResponses(previews []models.LinkPreview) []LinkPreviewResponse {
+	responses := make([]LinkPreviewResponse, 0, len(previews))
+	for _, preview := range previews {
+		responses = append(responses, LinkPreviewResponse{
+			URL:         preview.URL,
+			Title:       preview.Title,
+			Description: preview.Description,
+			Image:       preview.Image,
+			SiteName:    preview.SiteName,
+		})
+	}
+	return responses
+}
+
+// NewUserResponses maps a list of stored users to their wire representation
+func NewUserResponses(users []models.User) []UserResponse {
+	responses := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, NewUserResponse(user))
+	}
+	return responses
+}
+
+// NewAttachmentResponse maps a stored attachment to its wire representation
+func NewAttachmentResponse(attachment models.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:           attachment.ID,
+		URL:          "/v1/media/" + attachment.ID,
+		ThumbnailURL: "/v1/media/" + attachment.ID + "/thumbnail",
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		Width:        attachment.Width,
+		Height:       attachment.Height,
+		AltText:      attachment.AltText,
+		CreatedAt:    attachment.CreatedAt,
+	}
+}
+
+// NewAttachmentResponses maps stored attachments to their wire representation
+func NewAttachmentResponses(attachments []models.Attachment) []AttachmentResponse {
+	responses := make([]AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		responses = append(responses, NewAttachmentResponse(attachment))
+	}
+	return responses
+}
+
+// NewPollResponse maps a poll, as read for the caller, to its wire representation
+// The votes are left out unless the caller may see the results
+func NewPollResponse(poll models.Poll) PollResponse {
+	response := PollResponse{
+		Options:     make([]PollOptionResponse, 0, len(poll.Options)),
+		Multiple:    poll.Multiple,
+		ClosesAt:    poll.ClosesAt,
+		Closed:      poll.Closed,
+		ShowResults: poll.ShowResults,
+		Voted:       poll.Voted,
+	}
+	for _, option := range poll.Options {
+		optionResponse := PollOptionResponse{Text: option.Text}
+		if poll.ShowResults {
+			votes := option.Votes
+			optionResponse.Votes = &votes
+		}
+		response.Options = append(response.Options, optionResponse)
+	}
+	if poll.ShowResults {
+		voters := poll.Voters
+		response.Voters = &voters
+	}
+	return response
+}
+
+// NewNotificationResponse maps a notification to its wire representation
+func NewNotificationResponse(notification models.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:          notification.ID,
+		Type:        string(notification.Type),
+		PostID:      notification.PostID,
+		ActorIDs:    append([]int{}, notification.ActorIDs...),
+		OtherActors: notification.OtherActors,
+		Anonymous:   notification.Anonymous,
+		Summary:     notification.Summary,
+		Read:        notification.Read,
+		CreatedAt:   notification.CreatedAt,
+		UpdatedAt:   notification.UpdatedAt,
+	}
+}
+
+// NewNotificationResponses maps a list of notifications to their wire representation
+func NewNotificationResponses(notifications []models.Notification) []NotificationResponse {
+	responses := make([]NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		responses = append(responses, NewNotificationResponse(notification))
+	}
+	return responses
+}
+
+// NewUserResponse maps a stored user to its wire representation
+func NewUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// BatchResponse reports the outcome of every operation of a batch, in request order
+type BatchResponse struct {
+	Atomic    bool              `json:"atomic"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult is the outcome of a single batch operation
+// Status is the HTTP status the operation would have had as a separate request,
+// or 424 (Failed Dependency) when it was rolled back because another operation of an atomic batch failed
+type BatchItemResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	Status int           `json:"status"`
+	Post   *PostResponse `json:"post,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"mini-social-media-api/models"
+	"mini-social-media-api/richtext"
+	"net/url"
+	"strconv"
+)
+
+// EntityResponse is the wire representation of an entity of a text
+type EntityResponse struct {
+	Type   richtext.EntityType `json:"type" description:"url, hashtag, mention, strong, emphasis or code"`
+	Start  int                 `json:"start" description:"Offset of the entity in the text, in characters, including any Markdown markers"`
+	End    int                 `json:"end" description:"Offset just after the entity, in characters"`
+	Value  string              `json:"value" description:"The URL, the normalized hashtag, the username, or the text between the Markdown markers"`
+	UserID int                 `json:"user_id,omitempty" description:"Mentioned user, for resolved mentions"`
+}
+
+// NewEntityResponses maps entities to their wire representation
+func NewEntityResponses(entities []richtext.Entity) []EntityResponse {
+	responses := make([]EntityResponse, 0, len(entities))
+	for _, e := range entities {
+		responses = append(responses, EntityResponse{Type: e.Type, Start: e.Start, End: e.End, Value: e.Value, UserID: e.UserID})
+	}
+	return responses
+}
+
+// htmlOptions links hashtags and resolved mentions to their API pages
+var htmlOptions = richtext.HTMLOptions{
+	HashtagURL: func(tag string) string {
+		return "/v1/tags/" + url.PathEscape(tag) + "/posts"
+	},
+	MentionURL: func(e richtext.Entity) string {
+		if e.UserID == 0 {
+			return ""
+		}
+		return "/v1/users/" + strconv.Itoa(e.UserID)
+	},
+}
+
+// extractEntities finds the entities of a text, resolving mentions with the mentions stored when the text was written
+func extractEntities(text string, mentions []models.Mention) []richtext.Entity {
+	userIDs := make(map[int]int, len(mentions))
+	for _, m := range mentions {
+		userIDs[m.Start] = m.UserID
+	}
+
+	entities := richtext.Extract(text)
+	for i := range entities {
+		if entities[i].Type == richtext.Mention {
+			entities[i].UserID = userIDs[entities[i].Start]
+		}
+	}
+	return entities
+}
+
+// renderHTML renders a text with its entities as sanitized HTML
+func renderHTML(text string, entities []richtext.Entity) string {
+	return richtext.RenderHTML(text, entities, htmlOptions)
+}
@@ -0,0 +1,128 @@
+package events
+
+import "sync"
+
+// Event is a message published to the subscribers of any of its keys
+type Event struct {
+	ID      uint64   // Increases with every published event, starting at 1
+	Type    string   // e.g. post.liked
+	Keys    []string // Subscribers of any of these keys receive the event, e.g. post:42
+	Payload any
+}
+
+// Hub is an in-process publish/subscribe hub that keeps the latest events for subscribers resuming after a disconnect
+// Publishing never blocks: a subscriber that falls a full buffer behind is dropped and has to resubscribe
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	replay      []Event // The latest events, oldest first, up to replaySize
+	replaySize  int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published to its keys until it is closed or dropped
+type Subscription struct {
+	hub    *Hub
+	keys   map[string]bool
+	events chan Event
+	from   uint64 // ID of the last event published before the subscription started
+}
+
+// NewHub creates a hub keeping the latest replaySize events, with a buffer of bufferSize events per subscriber
+func NewHub(replaySize, bufferSize int) *Hub {
+	return &Hub{replaySize: replaySize, bufferSize: bufferSize, subscribers: map[*Subscription]struct{}{}}
+}
+
+// Publish assigns the next ID to an event and delivers it to the matching subscribers
+func (h *Hub) Publish(eventType string, keys []string, payload any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, Keys: keys, Payload: payload}
+	h.replay = append(h.replay, event)
+	if len(h.replay) > h.replaySize {
+		h.replay = append(h.replay[:0:0], h.replay[len(h.replay)-h.replaySize:]...)
+	}
+
+	for subscription := range h.subscribers {
+		if !subscription.matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			h.dropLocked(subscription)
+		}
+	}
+	return event
+}
+
+// Subscribe starts receiving the events published to any of the keys
+// With a lastEventID, the kept events published after it are returned to be handled first; complete is false
+// when some of them are no longer kept, e.g. after a long disconnect or a restart, and nothing is replayed then
+// since the subscriber missed events and has to reload what it shows
+func (h *Hub) Subscribe(keys []string, lastEventID uint64) (subscription *Subscription, replay []Event, complete bool) {
+	subscription = &Subscription{hub: h, keys: map[string]bool{}, events: make(chan Event, h.bufferSize)}
+	for _, key := range keys {
+		subscription.keys[key] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 {
+		oldest := h.lastID + 1
+		if len(h.replay) > 0 {
+			oldest = h.replay[0].ID
+		}
+		complete = lastEventID <= h.lastID && lastEventID+1 >= oldest
+		for _, event := range h.replay {
+			if complete && event.ID > lastEventID && subscription.matches(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	subscription.from = h.lastID
+	h.subscribers[subscription] = struct{}{}
+	return subscription, replay, complete
+}
+
+// Events returns the channel of events, closed once the subscription is closed or dropped
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// From returns the ID of the last event published before the subscription started, 0 if there was none
+// Subscribers that reloaded what they show resume after it
+func (s *Subscription) From() uint64 {
+	return s.from
+}
+
+// Close stops the subscription; closing it twice is not an error
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.dropLocked(s)
+}
+
+func (s *Subscription) matches(event Event) bool {
+	for _, key := range event.Keys {
+		if s.keys[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// dropLocked removes a subscription and closes its channel
+// The caller must hold the hub mutex
+func (h *Hub) dropLocked(subscription *Subscription) {
+	if _, ok := h.subscribers[subscription]; ok {
+		delete(h.subscribers, subscription)
+		close(subscription.events)
+	}
+}
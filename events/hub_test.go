@@ -0,0 +1,96 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHubDelivery(t *testing.T) {
+	hub := NewHub(10, 2)
+	post, _, _ := hub.Subscribe([]string{"post:1"}, 0)
+	author, _, _ := hub.Subscribe([]string{"author:7", "author:8"}, 0)
+
+	hub.Publish("post.liked", []string{"post:1", "author:7"}, nil)
+	hub.Publish("post.created", []string{"post:2", "author:8"}, nil)
+
+	tests := []struct {
+		name         string
+		subscription *Subscription
+		wantIDs      []uint64
+	}{
+		{"Single key", post, []uint64{1}},
+		{"Any of several keys", author, []uint64{1, 2}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, wantID := range testCase.wantIDs {
+				if event := <-testCase.subscription.Events(); event.ID != wantID {
+					t.Errorf("Expected event %d, got %d", wantID, event.ID)
+				}
+			}
+			if pending := len(testCase.subscription.Events()); pending != 0 {
+				t.Errorf("Expected no other event, got %d", pending)
+			}
+		})
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := NewHub(3, 10)
+	for i := 0; i < 5; i++ {
+		hub.Publish("post.liked", []string{"post:1"}, nil) // IDs 1 to 5
+	}
+	hub.Publish("post.liked", []string{"post:2"}, nil) // ID 6, so 4 to 6 are kept
+
+	tests := []struct {
+		name         string
+		lastEventID  uint64
+		wantIDs      []uint64
+		wantComplete bool
+	}{
+		{"New subscriber", 0, nil, true},
+		{"Up to date", 6, nil, true},
+		{"Resumes after the last event seen", 4, []uint64{5}, true},
+		{"Oldest kept event is next", 3, []uint64{4, 5}, true},
+		{"Missed events that are no longer kept", 1, nil, false},
+		{"Event ID from before a restart", 99, nil, false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			subscription, replay, complete := hub.Subscribe([]string{"post:1"}, testCase.lastEventID)
+			defer subscription.Close()
+
+			var ids []uint64
+			for _, event := range replay {
+				ids = append(ids, event.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(testCase.wantIDs) {
+				t.Errorf("Expected replay %v, got %v", testCase.wantIDs, ids)
+			}
+			if complete != testCase.wantComplete {
+				t.Errorf("Expected complete %v, got %v", testCase.wantComplete, complete)
+			}
+			if subscription.From() != 6 {
+				t.Errorf("Expected the subscription to start after event 6, got %d", subscription.From())
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(10, 1)
+	slow, _, _ := hub.Subscribe([]string{"post:1"}, 0)
+
+	hub.Publish("post.liked", []string{"post:1"}, nil)
+	hub.Publish("post.liked", []string{"post:1"}, nil) // Buffer full, so the subscriber is dropped
+
+	if event, ok := <-slow.Events(); !ok || event.ID != 1 {
+		t.Errorf("Expected the buffered event first, got %v (open %v)", event, ok)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("Expected the channel of the dropped subscriber to be closed")
+	}
+	slow.Close() // Closing a dropped subscription is not an error
+}
@@ -0,0 +1,103 @@
+package hashtag
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the maximum number of characters of a hashtag, excluding the #
+const MaxLength = 50
+
+// Match is a hashtag found in a text
+// Offsets count characters (Unicode code points), not bytes, so clients can slice the text directly
+type Match struct {
+	Tag   string // Normalized tag, without the #
+	Start int    // Offset of the #
+	End   int    // Offset just after the last character of the tag
+}
+
+// Extract returns the normalized hashtags of a text in order of first appearance, without duplicates
+func Extract(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range Find(text) {
+		if !seen[match.Tag] {
+			seen[match.Tag] = true
+			tags = append(tags, match.Tag)
+		}
+	}
+	return tags
+}
+
+// Find returns every hashtag of a text in order of appearance
+// A hashtag is a # followed by letters, digits and underscores with at least one letter, e.g. #Go_2024
+// The # must not follow a word character or &, so "C#" and HTML entities like "&#39;" are not hashtags
+func Find(text string) []Match {
+	matches := []Match{}
+	chars := 0 // Characters before byte offset i
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r != '#' || (i > 0 && !canPrecede(lastRune(text[:i]))) {
+			i += size
+			chars++
+			continue
+		}
+
+		end := i + size
+		length := 1
+		for end < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[end:])
+			if !isTagRune(next) {
+				break
+			}
+			end += nextSize
+			length++
+		}
+
+		if tag, ok := Normalize(text[i+size : end]); ok {
+			matches = append(matches, Match{Tag: tag, Start: chars, End: chars + length})
+		}
+		chars += length
+		i = end
+	}
+	return matches
+}
+
+// Normalize lower cases a hashtag, with or without its leading #
+// Returns false if it is not a valid hashtag
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+
+	hasLetter := false
+	length := 0
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+		length++
+	}
+	if !hasLetter || length > MaxLength {
+		return "", false
+	}
+	return tag, true
+}
+
+// isTagRune reports whether r may appear in a hashtag after the #
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// canPrecede reports whether a # following r may start a hashtag
+func canPrecede(r rune) bool {
+	return r != '&' && r != '#' && !isTagRune(r)
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
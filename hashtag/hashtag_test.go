@@ -0,0 +1,140 @@
+package hashtag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"Lower cased in order", "Loving #GoLang and #gophers", []string{"golang", "gophers"}},
+		{"Duplicates removed", "#go #Go #GO", []string{"go"}},
+		{"Punctuation ends the tag", "Tonight: #pasta! (#dinner).", []string{"pasta", "dinner"}},
+		{"Underscores and digits", "#go_1_22 release", []string{"go_1_22"}},
+		{"Unicode letters", "#café #日本", []string{"café", "日本"}},
+		{"Numbers only are not tags", "We are #1", []string{}},
+		{"Hash inside a word", "C# and issue#12", []string{}},
+		{"HTML entity", "it&#39;s", []string{}},
+		{"Double hash", "##tag", []string{}},
+		{"Bare hash", "# alone", []string{}},
+		{"Too long", "#" + strings.Repeat("a", MaxLength+1), []string{}},
+		{"Longest allowed", "#" + strings.Repeat("a", MaxLength), []string{strings.Repeat("a", MaxLength)}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := Extract(testCase.text)
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("Expected %v, got %v", testCase.want, got)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	got := Find("¡Olé #Café! #go #Go")
+	want := []Match{{"café", 5, 10}, {"go", 12, 15}, {"go", 16, 19}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   string
+		wantOK bool
+	}{
+		{"#Pasta", "pasta", true},
+		{"pasta", "pasta", true},
+		{"pasta night", "", false},
+		{"2024", "", false},
+		{"", "", false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.tag, func(t *testing.T) {
+			got, ok := Normalize(testCase.tag)
+			if got != testCase.want || ok != testCase.wantOK {
+				t.Errorf("Expected %q %v, got %q %v", testCase.want, testCase.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestIndexPosts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ix := NewIndex()
+	ix.Set(1, []string{"go", "pasta"}, start)
+	ix.Set(2, []string{"go"}, start.Add(time.Minute))
+	ix.Set(3, []string{"go"}, start.Add(2*time.Minute))
+
+	// Updating keeps the original time of tags that remain and drops removed ones
+	ix.Set(1, []string{"go"}, start.Add(time.Hour))
+	ix.Remove(3)
+
+	if got := ix.Posts("go"); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("Expected posts [2 1], got %v", got)
+	}
+	if got := ix.Posts("pasta"); len(got) != 0 {
+		t.Errorf("Expected no posts for a removed tag, got %v", got)
+	}
+}
+
+func TestIndexTrending(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ix := NewIndex()
+	id := 0
+	tag := func(name string, count int, at time.Time) {
+		for i := 0; i < count; i++ {
+			id++
+			ix.Set(id, []string{name}, at)
+		}
+	}
+
+	tag("steady", 6, now.Add(-30*time.Minute)) // 6 per hour, as usual
+	tag("steady", 36, now.Add(-3*time.Hour))   // 6 per hour over the baseline
+	tag("rising", 5, now.Add(-10*time.Minute)) // New tag picking up
+	tag("spike", 12, now.Add(-5*time.Minute))  // Usually quiet tag spiking
+	tag("spike", 6, now.Add(-2*time.Hour))     // 1 per hour over the baseline
+	tag("old", 10, now.Add(-10*time.Hour))     // Outside both windows
+	tag("future", 3, now.Add(time.Minute))     // Not yet visible at now
+	tag("expired", 8, now.Add(-5*time.Minute)) // Posts that expired before now
+	for expiredID := id - 7; expiredID <= id; expiredID++ {
+		ix.Expire(expiredID, now.Add(-time.Minute))
+	}
+	tag("expiring", 4, now.Add(-5*time.Minute)) // Posts that expire after now still count
+	for expiringID := id - 3; expiringID <= id; expiringID++ {
+		ix.Expire(expiringID, now.Add(time.Minute))
+	}
+
+	tests := []struct {
+		name   string
+		window time.Duration
+		limit  int
+		want   []string
+	}{
+		{"Last hour", time.Hour, 10, []string{"spike", "rising", "expiring"}},
+		{"Limited", time.Hour, 1, []string{"spike"}},
+		{"Last day", 24 * time.Hour, 10, []string{"steady", "spike", "old", "rising", "expiring"}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			trends := ix.Trending(now, testCase.window, testCase.limit)
+			got := make([]string, 0, len(trends))
+			for _, trend := range trends {
+				got = append(got, trend.Tag)
+			}
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("Expected %v, got %v (%+v)", testCase.want, got, trends)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package hashtag
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// baselineWindows is the number of windows before the current one used to learn the usual rate of a tag
+const baselineWindows = 6
+
+// Trend is a tag used more often than usual in the current window
+type Trend struct {
+	Tag      string
+	Count    int     // Posts tagged in the current window
+	Expected float64 // Average posts tagged per window over the baseline windows
+	Velocity float64 // Standard deviations the count is above the expected count
+}
+
+// Index maps hashtags to the posts using them and when each post started using them
+// It is safe for concurrent use
+type Index struct {
+	mu      sync.RWMutex
+	tags    map[string]map[int]time.Time // Tag to post ID to the time the post was tagged
+	post    map[int][]string             // Post ID to its tags
+	expires map[int]time.Time            // Post ID to the time it stops counting, for posts that expire
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		tags:    map[string]map[int]time.Time{},
+		post:    map[int][]string{},
+		expires: map[int]time.Time{},
+	}
+}
+
+// Set replaces the tags of a post
+// Tags the post already had keep their original time, new tags are recorded at the given time
+func (ix *Index) Set(postID int, tags []string, at time.Time) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	keep := map[string]bool{}
+	for _, tag := range tags {
+		keep[tag] = true
+		if ix.tags[tag] == nil {
+			ix.tags[tag] = map[int]time.Time{}
+		}
+		if _, ok := ix.tags[tag][postID]; !ok {
+			ix.tags[tag][postID] = at
+		}
+	}
+	for _, tag := range ix.post[postID] {
+		if !keep[tag] {
+			ix.untag(tag, postID)
+		}
+	}
+
+	if len(tags) == 0 {
+		delete(ix.post, postID)
+		return
+	}
+	ix.post[postID] = append([]string(nil), tags...)
+}
+
+// Expire stops a post from counting towards trending from the given time on, before it is removed
+func (ix *Index) Expire(postID int, at time.Time) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.expires[postID] = at
+}
+
+// Remove drops a post from the index
+func (ix *Index) Remove(postID int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, tag := range ix.post[postID] {
+		ix.untag(tag, postID)
+	}
+	delete(ix.post, postID)
+	delete(ix.expires, postID)
+}
+
+// Reset empties the index
+func (ix *Index) Reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.tags = map[string]map[int]time.Time{}
+	ix.post = map[int][]string{}
+	ix.expires = map[int]time.Time{}
+}
+
+// untag removes a post from the posts of a tag
+// The caller must hold the write lock
+func (ix *Index) untag(tag string, postID int) {
+	delete(ix.tags[tag], postID)
+	if len(ix.tags[tag]) == 0 {
+		delete(ix.tags, tag)
+	}
+}
+
+// Posts returns the IDs of the posts using the tag, most recently tagged first
+func (ix *Index) Posts(tag string) []int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	tagged := ix.tags[tag]
+	ids := make([]int, 0, len(tagged))
+	for id := range tagged {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if !tagged[ids[i]].Equal(tagged[ids[j]]) {
+			return tagged[ids[i]].After(tagged[ids[j]])
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
+
+// Trending returns up to limit tags whose use in the window ending at now is above their usual rate, fastest rising first
+// The usual rate of a tag is its average count over the previous baselineWindows windows,
+// so a tag that is always busy does not trend while a quiet tag that suddenly picks up does
+// Posts that have expired at now do not count
+func (ix *Index) Trending(now time.Time, window time.Duration, limit int) []Trend {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	windowStart := now.Add(-window)
+	baselineStart := windowStart.Add(-baselineWindows * window)
+
+	trends := []Trend{}
+	for tag, tagged := range ix.tags {
+		count, baseline := 0, 0
+		for postID, at := range tagged {
+			if expiresAt, ok := ix.expires[postID]; ok && !expiresAt.After(now) {
+				continue
+			}
+			switch {
+			case at.After(now):
+			case at.After(windowStart):
+				count++
+			case at.After(baselineStart):
+				baseline++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		// Tag uses per window are roughly Poisson distributed, so the variance equals the mean
+		// The extra 1 keeps brand new tags from dividing by zero and damps tags with very little history
+		expected := float64(baseline) / baselineWindows
+		velocity := (float64(count) - expected) / math.Sqrt(expected+1)
+		if velocity <= 0 {
+			continue
+		}
+		trends = append(trends, Trend{Tag: tag, Count: count, Expected: expected, Velocity: velocity})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Velocity != trends[j].Velocity {
+			return trends[i].Velocity > trends[j].Velocity
+		}
+		if trends[i].Count != trends[j].Count {
+			return trends[i].Count > trends[j].Count
+		}
+		return trends[i].Tag < trends[j].Tag
+	})
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+	return trends
+}
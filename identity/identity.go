@@ -0,0 +1,17 @@
+// Package identity carries the ID of the calling user through request contexts
+package identity
+
+import "context"
+
+type userIDKey struct{}
+
+// NewContext returns a copy of ctx carrying the ID of the calling user
+func NewContext(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// FromContext returns the ID of the calling user stored in ctx, or 0 for an anonymous caller
+func FromContext(ctx context.Context) int {
+	userID, _ := ctx.Value(userIDKey{}).(int)
+	return userID
+}
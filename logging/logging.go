@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field names shared by every log entry so that log lines can be correlated and queried consistently
+const (
+	FieldRequestID = "request_id"
+	FieldRoute     = "route"
+	FieldPostID    = "post_id"
+	FieldUserID    = "user_id"
+	FieldMediaID   = "media_id"
+	FieldStatus    = "status"
+	FieldLatency   = "latency" // Request latency in milliseconds
+)
+
+type entryKey struct{}
+
+// NewContext returns a copy of ctx carrying the given request-scoped log entry
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns the request-scoped log entry stored in ctx, bound to ctx so trace IDs of the active span are logged
+// Falls back to the standard logger when ctx carries no entry (e.g. in tests or background jobs)
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry, ok := ctx.Value(entryKey{}).(*logrus.Entry)
+	if !ok {
+		return logrus.WithContext(ctx)
+	}
+	return entry.WithContext(ctx)
+}
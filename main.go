@@ -1,11 +1,87 @@
 package main
 
 import (
+	"context"
+	"mini-social-media-api/media"
 	"mini-social-media-api/routes"
+	"mini-social-media-api/services"
+	"mini-social-media-api/tracing"
+	"mini-social-media-api/unfurl"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	// Emit JSON logs so fields like request_id and post_id can be queried
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	// Configure OpenTelemetry tracing and include trace IDs in log entries
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		logrus.Fatalln("Failed to initialize tracing: " + err.Error())
+	}
+	defer shutdownTracing(context.Background())
+	logrus.AddHook(tracing.LogrusHook{})
+
+	// Choose whether mentions of unknown usernames are flagged or rejected
+	policy, err := services.ParseMentionPolicy(os.Getenv("UNKNOWN_MENTIONS"))
+	if err != nil {
+		logrus.Fatalln("Invalid UNKNOWN_MENTIONS: " + err.Error())
+	}
+	services.UnknownMentions = policy
+
+	// Fetch previews of links in posts in the background, unless disabled
+	if os.Getenv("LINK_PREVIEWS") != "off" {
+		unfurler := unfurl.New(unfurl.NewHTTPFetcher(unfurl.DefaultTimeout, unfurl.DefaultMaxBytes, nil), unfurl.Options{})
+		defer unfurler.Close()
+		services.LinkPreviews = unfurler
+	}
+
+	// Push posts of accounts with at most this many followers to home timelines, read larger accounts on request
+	if value := os.Getenv("TIMELINE_FANOUT_LIMIT"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			logrus.Fatalln("Invalid TIMELINE_FANOUT_LIMIT: expected a number of followers")
+		}
+		services.TimelineFanOutLimit = limit
+	}
+
+	// Store uploaded media on the local filesystem
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./data/media"
+	}
+	blobs, err := media.NewFileStore(mediaDir)
+	if err != nil {
+		logrus.Fatalln("Failed to create the media directory: " + err.Error())
+	}
+	services.Blobs = blobs
+
+	// Index any posts already in the store so they can be searched and browsed by tag
+	services.RebuildIndexes(context.Background())
+
+	// Publish scheduled posts when they are due and remove expired posts
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go services.RunScheduler(backgroundCtx, services.SchedulerInterval)
+	go services.RunSweeper(backgroundCtx, services.SweeperInterval)
+
+	// Give clients holding an API key their own rate limit budget instead of the one of their IP address
+	if value := os.Getenv("API_KEYS"); value != "" {
+		routes.RateLimitAPIKeys = strings.Split(value, ",")
+	}
+
+	// Read the client address from X-Forwarded-For only when it is set by one of these proxies, comma separated
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		for _, proxy := range strings.Split(value, ",") {
+			routes.TrustedProxies = append(routes.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+
 	// Initialize routes and start the HTTP server on port 8081
 	router := routes.InitRoutes()
-	router.Run(":8081")		
-}
\ No newline at end of file
+	router.Run(":8081")
+}
@@ -0,0 +1,211 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Errors returned when processing uploads
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrTooManyFrames   = errors.New("animation has too many frames")
+)
+
+// Limits of image processing
+const (
+	MaxPixels     = 40_000_000 // Rejects decompression bombs before decoding them; counts every frame of animations
+	MaxFrames     = 1000       // Frames of an animation, each decoded into its own image
+	ThumbnailSize = 320        // Thumbnails fit in a square of this many pixels
+	jpegQuality   = 90
+)
+
+// AllowedTypes are the media types accepted for upload, detected from the content and not from the client
+var AllowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Processed is an upload ready to be stored
+type Processed struct {
+	ContentType string
+	Data        []byte // Re-encoded image, without EXIF or other metadata
+	Width       int
+	Height      int
+	Thumbnail   []byte // JPEG fitting in ThumbnailSize x ThumbnailSize
+}
+
+// Process validates an uploaded image, strips its metadata and generates its thumbnail
+// The image is decoded and re-encoded, which drops EXIF (including GPS position), XMP,
+// comments and any data hidden after the image
+func Process(data []byte) (Processed, error) {
+	contentType := http.DetectContentType(data)
+	if !AllowedTypes[contentType] {
+		return Processed{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return Processed{}, ErrTooManyPixels
+	}
+
+	var out bytes.Buffer
+	var first image.Image
+	switch contentType {
+	case "image/gif":
+		// DecodeConfig only reports the logical screen, while DecodeAll allocates every frame
+		if err := checkGIFFrames(data); err != nil {
+			return Processed{}, err
+		}
+
+		// Keep every frame of animations; comments and application extensions are dropped
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Processed{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		if err := gif.EncodeAll(&out, animation); err != nil {
+			return Processed{}, err
+		}
+		first = animation.Image[0]
+	default:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Processed{}, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+		}
+		if contentType == "image/png" {
+			err = png.Encode(&out, img)
+		} else {
+			err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return Processed{}, err
+		}
+		first = img
+	}
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, Thumbnail(first, ThumbnailSize), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return Processed{}, err
+	}
+
+	return Processed{
+		ContentType: contentType,
+		Data:        out.Bytes(),
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnail:   thumbnail.Bytes(),
+	}, nil
+}
+
+// checkGIFFrames walks the blocks of a GIF without decoding them and rejects animations with more
+// than MaxFrames frames or whose frames add up to more than MaxPixels pixels
+func checkGIFFrames(data []byte) error {
+	const screenDescriptorEnd = 13 // Signature, version and logical screen descriptor
+	if len(data) < screenDescriptorEnd {
+		return fmt.Errorf("%w: truncated GIF", ErrUnsupportedType)
+	}
+	pos := screenDescriptorEnd + colorTableSize(data[10])
+
+	frames, pixels := 0, 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: label, then data sub-blocks
+			pos = skipSubBlocks(data, pos+2)
+		case 0x2C: // Image descriptor: position, size and flags, then LZW code size and data sub-blocks
+			if pos+10 > len(data) {
+				return fmt.Errorf("%w: truncated GIF", ErrUnsupportedType)
+			}
+			width := int(data[pos+5]) | int(data[pos+6])<<8
+			height := int(data[pos+7]) | int(data[pos+8])<<8
+			frames++
+			pixels += width * height
+			if frames > MaxFrames {
+				return ErrTooManyFrames
+			}
+			if pixels > MaxPixels {
+				return ErrTooManyPixels
+			}
+			pos = skipSubBlocks(data, pos+10+colorTableSize(data[pos+9])+1)
+		case 0x3B: // Trailer
+			return nil
+		default:
+			return fmt.Errorf("%w: unknown GIF block 0x%02x", ErrUnsupportedType, data[pos])
+		}
+	}
+	return nil
+}
+
+// colorTableSize is the size in bytes of the color table announced by the flags of a GIF descriptor
+func colorTableSize(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << (flags&0x07 + 1)
+}
+
+// skipSubBlocks returns the position after the data sub-blocks starting at pos, or the end of data when truncated
+func skipSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos
+		}
+		pos += size
+	}
+	return len(data)
+}
+
+// Thumbnail scales an image down to fit in a size x size square, averaging the pixels each
+// thumbnail pixel covers. Transparent areas are drawn on white; smaller images keep their size
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/bounds.Dx())
+		} else {
+			width, height = max(1, width*size/bounds.Dy()), size
+		}
+	}
+
+	// Flatten on white first so the averages do not depend on the colors of transparent pixels
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, src, bounds.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					offset := flat.PixOffset(sx, sy)
+					r += uint64(flat.Pix[offset])
+					g += uint64(flat.Pix[offset+1])
+					b += uint64(flat.Pix[offset+2])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255})
+		}
+	}
+	return dst
+}
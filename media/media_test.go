@@ -0,0 +1,213 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// jpegWithEXIF encodes a JPEG and inserts an APP1 EXIF segment holding a secret right after the start marker
+func jpegWithEXIF(t *testing.T, secret string) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(64, 48), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("Exif\x00\x00"), secret...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+// pngChunk builds a PNG chunk with its length and CRC
+func pngChunk(kind string, data []byte) []byte {
+	chunk := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithText encodes a PNG and inserts a tEXt chunk holding a secret right after the IHDR chunk
+func pngWithText(t *testing.T, secret string) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(40, 80)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	afterHeader := 8 + 25 // Signature, then IHDR: length, type, 13 bytes of data and CRC
+	text := pngChunk("tEXt", []byte("Comment\x00"+secret))
+	return append(append(append([]byte{}, data[:afterHeader]...), text...), data[afterHeader:]...)
+}
+
+// hugePNG is a valid PNG header claiming dimensions that would take gigabytes to decode
+func hugePNG() []byte {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], 100_000)
+	binary.BigEndian.PutUint32(header[4:], 100_000)
+	header[8], header[9] = 8, 2 // 8 bit RGB
+	data := append([]byte("\x89PNG\r\n\x1a\n"), pngChunk("IHDR", header)...)
+	return append(data, pngChunk("IDAT", []byte{0})...)
+}
+
+// animatedGIF encodes a GIF with a few frames
+func animatedGIF(t *testing.T, width, height, frames int) []byte {
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{color.Black, color.White})
+		frame.SetColorIndex(i%width, 0, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gifBomb is a GIF whose frames each cover a width x height logical screen, with almost no image data
+func gifBomb(width, height, frames int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, uint16(width))
+	data = binary.LittleEndian.AppendUint16(data, uint16(height))
+	data = append(data, 0x80, 0, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF) // Global color table of 2 colors
+	for i := 0; i < frames; i++ {
+		data = append(data, 0x2C, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(width))
+		data = binary.LittleEndian.AppendUint16(data, uint16(height))
+		data = append(data, 0, 2, 1, 0x44, 0) // No local color table, LZW code size, one data sub-block
+	}
+	return append(data, 0x3B)
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantType   string
+		wantWidth  int
+		wantHeight int
+		wantErr    error
+	}{
+		{"JPEG with EXIF", jpegWithEXIF(t, "GPS 48.8584,2.2945"), "image/jpeg", 64, 48, nil},
+		{"PNG with text chunk", pngWithText(t, "GPS 48.8584,2.2945"), "image/png", 40, 80, nil},
+		{"Plain text", []byte("hello world"), "", 0, 0, ErrUnsupportedType},
+		{"SVG with script", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), "", 0, 0, ErrUnsupportedType},
+		{"HTML disguised as an image", []byte("<html><script>alert(1)</script>"), "", 0, 0, ErrUnsupportedType},
+		{"Truncated JPEG", jpegWithEXIF(t, "x")[:40], "", 0, 0, ErrUnsupportedType},
+		{"Decompression bomb", hugePNG(), "", 0, 0, ErrTooManyPixels},
+		{"Animated GIF", animatedGIF(t, 30, 20, 3), "image/gif", 30, 20, nil},
+		{"GIF with too many frames", gifBomb(1, 1, MaxFrames+1), "", 0, 0, ErrTooManyFrames},
+		{"GIF whose frames add up to too many pixels", gifBomb(5000, 5000, 2), "", 0, 0, ErrTooManyPixels},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			processed, err := Process(testCase.data)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if processed.ContentType != testCase.wantType || processed.Width != testCase.wantWidth || processed.Height != testCase.wantHeight {
+				t.Errorf("Expected %s %dx%d, got %s %dx%d", testCase.wantType, testCase.wantWidth, testCase.wantHeight, processed.ContentType, processed.Width, processed.Height)
+			}
+			for _, leaked := range []string{"Exif", "GPS", "tEXt"} {
+				if bytes.Contains(processed.Data, []byte(leaked)) {
+					t.Errorf("Expected metadata %q to be stripped", leaked)
+				}
+			}
+			if _, _, err := image.Decode(bytes.NewReader(processed.Data)); err != nil {
+				t.Errorf("Expected a decodable image, got: %v", err)
+			}
+			thumbnail, err := jpeg.Decode(bytes.NewReader(processed.Thumbnail))
+			if err != nil {
+				t.Fatalf("Expected a JPEG thumbnail, got: %v", err)
+			}
+			if thumbnail.Bounds().Dx() != testCase.wantWidth || thumbnail.Bounds().Dy() != testCase.wantHeight {
+				t.Errorf("Expected small images to keep their size in the thumbnail, got %v", thumbnail.Bounds())
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		wantWidth, wantHeight int
+	}{
+		{"Landscape", 1000, 500, 320, 160},
+		{"Portrait", 300, 900, 106, 320},
+		{"Small", 100, 50, 100, 50},
+		{"Very thin", 2000, 1, 320, 1},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			bounds := Thumbnail(testImage(testCase.width, testCase.height), ThumbnailSize).Bounds()
+			if bounds.Dx() != testCase.wantWidth || bounds.Dy() != testCase.wantHeight {
+				t.Errorf("Expected %dx%d, got %dx%d", testCase.wantWidth, testCase.wantHeight, bounds.Dx(), bounds.Dy())
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "abc123", strings.NewReader("blob")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	r, err := store.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "blob" {
+		t.Errorf("Expected the stored blob, got %q", data)
+	}
+
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := store.Get(ctx, "abc123"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound, got: %v", err)
+	}
+
+	for _, key := range []string{"../escape", "a/b", "", ".hidden", "..", strings.Repeat("a", 200)} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected key %q to be rejected, got: %v", key, err)
+		}
+	}
+}
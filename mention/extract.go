@@ -0,0 +1,78 @@
+package mention
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxUsernameLength is the maximum number of characters of a username
+const MaxUsernameLength = 30
+
+// Mention is an @username found in a text
+// Offsets count characters (Unicode code points), not bytes, so clients can slice the text directly
+type Mention struct {
+	Username string // As written, without the @
+	Start    int    // Offset of the @
+	End      int    // Offset just after the last character of the username
+}
+
+// Extract returns the mentions of a text in order of appearance
+// A mention is an @ followed by 1-30 ASCII letters, digits or underscores
+// The @ must not follow a word character, so e-mail addresses like "me@example.com" are not mentions
+func Extract(text string) []Mention {
+	mentions := []Mention{}
+	chars := 0 // Characters before byte offset i
+	previous := rune(-1)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r != '@' || isWordRune(previous) {
+			previous = r
+			i += size
+			chars++
+			continue
+		}
+
+		end := i + size
+		for end < len(text) && isUsernameByte(text[end]) {
+			end++
+		}
+		length := end - i - size
+		next, _ := utf8.DecodeRuneInString(text[end:])
+
+		// A longer run of word characters or a second @ means this is not a username
+		if length == 0 || length > MaxUsernameLength || isWordRune(next) || next == '@' {
+			previous = r
+			i += size
+			chars++
+			continue
+		}
+
+		mentions = append(mentions, Mention{Username: text[i+size : end], Start: chars, End: chars + 1 + length})
+		chars += 1 + length
+		previous = rune(text[end-1])
+		i = end
+	}
+	return mentions
+}
+
+// ValidUsername reports whether a username can be mentioned
+func ValidUsername(username string) bool {
+	if len(username) == 0 || len(username) > MaxUsernameLength {
+		return false
+	}
+	for i := 0; i < len(username); i++ {
+		if !isUsernameByte(username[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUsernameByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,56 @@
+package mention
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Mention
+	}{
+		{"Single mention", "Hi @alice!", []Mention{{"alice", 3, 9}}},
+		{"Several mentions", "@bob and @Carol_2", []Mention{{"bob", 0, 4}, {"Carol_2", 9, 17}}},
+		{"Non ASCII username", "café @zoé", []Mention{}},
+		{"Offsets after multibyte text", "¡Hola @ana", []Mention{{"ana", 6, 10}}},
+		{"E-mail address", "mail me@example.com", []Mention{}},
+		{"Bare at sign", "meet @ noon", []Mention{}},
+		{"Too long", "@" + strings.Repeat("a", MaxUsernameLength+1), []Mention{}},
+		{"Longest allowed", "@" + strings.Repeat("a", MaxUsernameLength), []Mention{{strings.Repeat("a", MaxUsernameLength), 0, MaxUsernameLength + 1}}},
+		{"Repeated", "@dan @dan", []Mention{{"dan", 0, 4}, {"dan", 5, 9}}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := Extract(testCase.text)
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("Expected %v, got %v", testCase.want, got)
+			}
+		})
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"alice", true},
+		{"Bob_99", true},
+		{"", false},
+		{"has space", false},
+		{"zoé", false},
+		{strings.Repeat("a", MaxUsernameLength+1), false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.username, func(t *testing.T) {
+			if got := ValidUsername(testCase.username); got != testCase.want {
+				t.Errorf("Expected %v, got %v", testCase.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "mini_social_media"
+
+// HTTP metrics, labeled by method, route template (e.g. /posts/:postID) and response status
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Domain metrics, emitted from the services layer
+var (
+	PostsCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Total number of posts created.",
+	})
+
+	LikesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "likes_total",
+		Help:      "Total number of likes added to posts.",
+	})
+
+	CommentsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comments_total",
+		Help:      "Total number of comments added to posts.",
+	})
+
+	PollVotesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "poll_votes_total",
+		Help:      "Total number of votes cast in polls.",
+	})
+
+	// ValidationRejectionsTotal is labeled by the operation that rejected the input (e.g. create_post)
+	ValidationRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_rejections_total",
+		Help:      "Total number of requests rejected by request body or service-level validation.",
+	}, []string{"operation"})
+
+	PostsTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "posts",
+		Help:      "Current number of posts in the store.",
+	})
+
+	// StoreLockWaitSeconds observes every wait for the store lock, so contention shows in the distribution
+	// Uncontended waits take microseconds, hence buckets from 10µs to about 2.6s
+	StoreLockWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_lock_wait_seconds",
+		Help:      "Time spent waiting to acquire the post store lock in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	})
+)
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"mini-social-media-api/logging"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLog writes one structured log entry per request, replacing the default Gin logger
+// It must run after RequestID so that the entry carries the request ID and route
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := logging.FromContext(c.Request.Context()).WithFields(logrus.Fields{
+			logging.FieldStatus:  status,
+			logging.FieldLatency: float64(time.Since(start).Microseconds()) / 1000,
+			"method":             c.Request.Method,
+			"path":               c.Request.URL.Path,
+			"client_ip":          c.ClientIP(),
+		})
+		if postID, err := strconv.Atoi(c.Param("postID")); err == nil {
+			entry = entry.WithField(logging.FieldPostID, postID)
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Errorln("Request completed")
+		case status >= http.StatusBadRequest:
+			entry.Warnln("Request completed")
+		default:
+			entry.Infoln("Request completed")
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks every response as coming from a deprecated endpoint
+// It sends the Deprecation (RFC 9745) and Sunset (RFC 8594) headers and links to the successor endpoint under successorPrefix
+func Deprecated(deprecatedAt, sunsetAt time.Time, successorPrefix string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunset := sunsetAt.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunset)
+		c.Header("Link", "<"+successorPrefix+c.Request.URL.Path+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
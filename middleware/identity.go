@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Identity stores the user in the X-User-ID header in the request context, so services can apply
+// the blocks and mutes of the caller; requests without the header are anonymous
+// The API has no authentication, so the header is trusted as is; a malformed header is rejected with 401
+func Identity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(UserIDHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		userID, err := strconv.Atoi(header)
+		if err != nil || userID <= 0 {
+			logging.FromContext(c.Request.Context()).Warnln("Rejected malformed " + UserIDHeader + " header")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid " + UserIDHeader + " header, expected a user ID"})
+			return
+		}
+
+		ctx := identity.NewContext(c.Request.Context(), userID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
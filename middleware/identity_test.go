@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"mini-social-media-api/identity"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Identity())
+
+	var caller int
+	router.GET("/posts/", func(c *gin.Context) {
+		caller = identity.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantCaller int
+	}{
+		{"Anonymous", "", http.StatusOK, 0},
+		{"User ID", "42", http.StatusOK, 42},
+		{"Not a number", "alice", http.StatusUnauthorized, 0},
+		{"Not positive", "0", http.StatusUnauthorized, 0},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			caller = 0
+			req := httptest.NewRequest(http.MethodGet, "/posts/", nil)
+			if testCase.header != "" {
+				req.Header.Set(UserIDHeader, testCase.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != testCase.wantStatus {
+				t.Fatalf("Expected status %d, got %d", testCase.wantStatus, w.Code)
+			}
+			if caller != testCase.wantCaller {
+				t.Errorf("Expected caller %d, got %d", testCase.wantCaller, caller)
+			}
+		})
+	}
+}
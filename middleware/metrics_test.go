@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"mini-social-media-api/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsLabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/posts/:postID", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		status string
+	}{
+		{"Matched route uses template", "/posts/42", "/posts/:postID", "200"},
+		{"Unmatched route is grouped", "/unknown/42", "unmatched", "404"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			counter := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, testCase.route, testCase.status)
+			before := testutil.ToFloat64(counter)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, testCase.path, nil))
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("Expected request counter for route '%s' to increase by 1, got %v", testCase.route, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"math"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/ratelimit"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers used to identify the client for rate limiting
+const (
+	APIKeyHeader = "X-API-Key"
+	UserIDHeader = "X-User-ID"
+)
+
+// RateLimitConfig holds the separate budgets for read and write requests
+type RateLimitConfig struct {
+	Store  ratelimit.Store
+	Reads  ratelimit.Limit // GET, HEAD and OPTIONS requests
+	Writes ratelimit.Limit // Creating posts, commenting, liking and any other mutation
+	// APIKeys reports whether an X-API-Key header holds a key issued to a client; nil accepts no key
+	APIKeys func(key string) bool
+}
+
+// RateLimit enforces per-client token bucket quotas
+// Clients are identified by a valid API key, otherwise by IP address. X-User-ID is not authenticated and unknown
+// API keys are made up by the client, so neither selects a bucket; changing them on every request would bypass the limit
+// Every response carries RateLimit-* headers; rejected requests get 429 with Retry-After
+func RateLimit(config RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, class := config.Writes, "write"
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			limit, class = config.Reads, "read"
+		}
+
+		result := config.Store.Take(class+":"+clientKey(c, config.APIKeys), limit, time.Now())
+
+		c.Header("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(int(limit.Period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			logging.FromContext(c.Request.Context()).Warnln("Rate limit exceeded for " + class + " requests")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded. Retry after " + strconv.Itoa(retryAfter) + " seconds"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientKey identifies the caller by a valid API key, or by IP address
+func clientKey(c *gin.Context, validAPIKey func(string) bool) string {
+	if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" && validAPIKey != nil && validAPIKey(apiKey) {
+		return "key:" + apiKey
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// StaticAPIKeys accepts the given API keys, ignoring empty ones
+func StaticAPIKeys(keys ...string) func(string) bool {
+	valid := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			valid[key] = true
+		}
+	}
+	return func(key string) bool { return valid[key] }
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as required by the rate limit headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"mini-social-media-api/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(RateLimitConfig{
+		Store:   ratelimit.NewMemoryStore(),
+		Reads:   ratelimit.Limit{Burst: 2, Period: time.Minute},
+		Writes:  ratelimit.Limit{Burst: 1, Period: time.Minute},
+		APIKeys: StaticAPIKeys("client-a", "client-b"),
+	}))
+	router.GET("/posts/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/posts/", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	tests := []struct {
+		name          string
+		method        string
+		apiKey        string
+		userID        string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{"First write allowed", http.MethodPost, "client-a", "", http.StatusCreated, "0"},
+		{"Second write limited", http.MethodPost, "client-a", "", http.StatusTooManyRequests, "0"},
+		{"Reads have a separate budget", http.MethodGet, "client-a", "", http.StatusOK, "1"},
+		{"Other clients have their own budget", http.MethodPost, "client-b", "", http.StatusCreated, "0"},
+		{"Anonymous client limited by IP", http.MethodPost, "", "", http.StatusCreated, "0"},
+		{"Unknown API key falls back to the IP", http.MethodPost, "made-up", "", http.StatusTooManyRequests, "0"},
+		{"User ID does not select a bucket", http.MethodPost, "", "42", http.StatusTooManyRequests, "0"},
+		{"Changing user ID does not either", http.MethodPost, "", "43", http.StatusTooManyRequests, "0"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, "/posts/", nil)
+			req.Header.Set(APIKeyHeader, testCase.apiKey)
+			req.Header.Set(UserIDHeader, testCase.userID)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != testCase.wantStatus {
+				t.Fatalf("Expected status %d, got %d", testCase.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != testCase.wantRemaining {
+				t.Errorf("Expected RateLimit-Remaining '%s', got '%s'", testCase.wantRemaining, got)
+			}
+			if testCase.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Errorf("Expected Retry-After header on limited response")
+			}
+		})
+	}
+}
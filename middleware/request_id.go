@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"mini-social-media-api/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the header used to receive and return the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they cannot bloat the logs
+const maxRequestIDLength = 128
+
+// RequestID assigns every request an ID, reusing a valid X-Request-ID sent by the client
+// The ID is echoed in the response and attached to a request-scoped log entry stored in the request context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		entry := logrus.WithFields(logrus.Fields{
+			logging.FieldRequestID: requestID,
+			logging.FieldRoute:     route,
+		})
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), entry))
+		c.Next()
+	}
+}
+
+// newRequestID generates a random 128-bit hex encoded ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
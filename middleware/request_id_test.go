@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"mini-social-media-api/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+
+	var loggedID interface{}
+	router.GET("/posts/:postID", func(c *gin.Context) {
+		loggedID = logging.FromContext(c.Request.Context()).Data[logging.FieldRequestID]
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{"Client request ID is propagated", "abc-123", true},
+		{"Missing request ID is generated", "", false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+			if testCase.requestID != "" {
+				req.Header.Set(RequestIDHeader, testCase.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" {
+				t.Fatalf("Expected %s response header to be set", RequestIDHeader)
+			}
+			if testCase.wantSame && got != testCase.requestID {
+				t.Errorf("Expected request ID '%s', got '%s'", testCase.requestID, got)
+			}
+			if loggedID != got {
+				t.Errorf("Expected log entry request_id '%s', got '%v'", got, loggedID)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"mini-social-media-api/logging"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamLimit caps how many requests each client holds open at once, for long-lived responses such as event
+// streams that the rate limit only counts when they open
+// Clients are identified like in RateLimit; requests over the cap get 429 until one of the client's streams ends
+func StreamLimit(maxPerClient int, validAPIKey func(string) bool) gin.HandlerFunc {
+	var mutex sync.Mutex
+	open := map[string]int{} // Open requests by client; clients without any are removed
+
+	return func(c *gin.Context) {
+		key := clientKey(c, validAPIKey)
+
+		mutex.Lock()
+		if open[key] >= maxPerClient {
+			mutex.Unlock()
+			logging.FromContext(c.Request.Context()).Warnln("Too many open streams")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many open streams. At most " + strconv.Itoa(maxPerClient) + " may be open at once"})
+			return
+		}
+		open[key]++
+		mutex.Unlock()
+
+		defer func() {
+			mutex.Lock()
+			if open[key]--; open[key] == 0 {
+				delete(open, key)
+			}
+			mutex.Unlock()
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	release := make(chan struct{})
+	router.GET("/events", StreamLimit(2, StaticAPIKeys("client-a")), func(c *gin.Context) {
+		if c.Query("hold") != "" {
+			<-release
+		}
+		c.Status(http.StatusOK)
+	})
+
+	request := func(apiKey, query string) int {
+		req := httptest.NewRequest(http.MethodGet, "/events"+query, nil)
+		req.Header.Set(APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Hold two streams open for the IP address of the test requests
+	held := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { held <- request("", "?hold=1") }()
+	}
+	// Wait until both streams are open
+	for request("", "") != http.StatusTooManyRequests {
+	}
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+	}{
+		{"Third stream of the IP address rejected", "", http.StatusTooManyRequests},
+		{"Unknown API key falls back to the IP", "made-up", http.StatusTooManyRequests},
+		{"Client with an API key has its own cap", "client-a", http.StatusOK},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := request(testCase.apiKey, ""); got != testCase.wantStatus {
+				t.Errorf("Expected status %d, got %d", testCase.wantStatus, got)
+			}
+		})
+	}
+
+	// Closing the streams frees their slots
+	close(release)
+	for i := 0; i < 2; i++ {
+		if got := <-held; got != http.StatusOK {
+			t.Errorf("Expected held stream to end with status %d, got %d", http.StatusOK, got)
+		}
+	}
+	if got := request("", ""); got != http.StatusOK {
+		t.Errorf("Expected a new stream once the others closed, got status %d", got)
+	}
+}
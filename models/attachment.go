@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Attachment is an uploaded image; its blob and thumbnail are kept in the blob store under its ID
+type Attachment struct {
+	ID          string    `json:"id"`           // Random hex identifier, also the blob key
+	ContentType string    `json:"content_type"` // Detected from the content, not from the client
+	Size        int       `json:"size"`         // Size of the stored blob in bytes, after metadata was stripped
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	AltText     string    `json:"alt_text"` // Description of the image for screen readers
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -3,7 +3,9 @@ package models
 import "time"
 
 type Comment struct {
-	ID        int       `json:"id"`                              // Unique identifier for the comment
-	Text      string    `json:"text" binding:"required,max=150"` // The text of the comment (max 150 characters)
+	ID        int       `json:"id"`        // Unique identifier for the comment
+	AuthorID  int       `json:"author_id"` // User who wrote the comment, 0 for anonymous comments
+	Text      string    `json:"text"`      // The text of the comment (max 150 characters)
+	Mentions  []Mention `json:"mentions"`  // Users mentioned in the text
 	CreatedAt time.Time `json:"created_at"`
 }
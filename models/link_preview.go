@@ -0,0 +1,10 @@
+package models
+
+// LinkPreview is the metadata of a link in the content of a post, fetched in the background after the post is written
+type LinkPreview struct {
+	URL         string `json:"url"` // The link as written in the content
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"site_name"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// Mention is an @username in the text of a post or comment, resolved to a user when the text was written
+type Mention struct {
+	Username string `json:"username"` // As written, without the @
+	UserID   int    `json:"user_id"`  // 0 when no user had the username
+	Start    int    `json:"start"`    // Character offset of the @
+	End      int    `json:"end"`      // Character offset just after the username
+}
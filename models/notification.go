@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// NotificationType is the kind of activity a notification reports
+type NotificationType string
+
+const (
+	NotificationLike    NotificationType = "like"    // Likes of a post of the user
+	NotificationComment NotificationType = "comment" // Comments on a post of the user
+	NotificationMention NotificationType = "mention" // Mentions of the user in a post or its comments
+	NotificationFollow  NotificationType = "follow"  // New followers of the user
+)
+
+// Notification tells a user about activity concerning them
+// Unread notifications of the same type about the same post aggregate the activity of several users
+type Notification struct {
+	ID          int              `json:"id"`
+	Type        NotificationType `json:"type"`
+	PostID      int              `json:"post_id"`      // Post the activity is about, 0 for follows
+	ActorIDs    []int            `json:"actor_ids"`    // Distinct users behind the most recent activity, most recent first, at most 3
+	OtherActors int              `json:"other_actors"` // Number of other users behind the activity
+	Anonymous   int              `json:"anonymous"`    // Number of times anonymous callers did it, e.g. liked the post
+	Read        bool             `json:"read"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"` // When activity was last added
+
+	Summary string `json:"-"` // Human readable description, set when the notification is read
+}
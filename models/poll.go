@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Poll is a question attached to a post, with options to vote for until it closes
+// Votes replace the poll instead of modifying it, so posts previously returned to callers are not modified
+type Poll struct {
+	Options  []PollOption  `json:"options"`
+	Multiple bool          `json:"multiple"` // Voters may choose several options
+	ClosesAt time.Time     `json:"closes_at"`
+	Votes    map[int][]int `json:"votes"` // User ID to the indexes of the options they chose
+
+	// Set when the post is read, for the reader
+	Voters      int   `json:"-"` // Number of users who voted
+	Voted       []int `json:"-"` // Options chosen by the reader
+	Closed      bool  `json:"-"`
+	ShowResults bool  `json:"-"` // Whether the reader may see the votes, once they voted or the poll closed
+}
+
+// PollOption is one of the answers of a poll
+type PollOption struct {
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
@@ -4,10 +4,25 @@ import "time"
 
 // Post represents a social media post with content(text), likes, and associated comments
 type Post struct {
-	ID        int       `json:"id"` // Unique identifier for the post
-	Content   string    `json:"content" binding:"required,max=250"`
-	Likes     int       `json:"likes"`
-	Comments  []Comment `json:"comments"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int           `json:"id"`          // Unique identifier for the post
+	AuthorID    int           `json:"author_id"`   // User who wrote the post, 0 for anonymous posts
+	Visibility  Visibility    `json:"visibility"`  // Who can see the post; anonymous posts are always public
+	Status      PostStatus    `json:"status"`      // Whether the post is published, a draft or scheduled
+	PublishAt   *time.Time    `json:"publish_at"`  // When a scheduled post is published, nil otherwise
+	ExpiresAt   *time.Time    `json:"expires_at"`  // When the post disappears, nil for posts that do not expire
+	RepostOf    int           `json:"repost_of"`   // Post reshared as is, 0 when the post is not a repost
+	QuoteOf     int           `json:"quote_of"`    // Post quoted with new content, 0 when the post does not quote another
+	Content     string        `json:"content"`     // The text of the post (max 250 characters)
+	Hashtags    []string      `json:"hashtags"`    // Normalized hashtags found in the content
+	Mentions    []Mention     `json:"mentions"`    // Users mentioned in the content
+	Previews    []LinkPreview `json:"previews"`    // Previews of the links in the content, added once fetched
+	Attachments []Attachment  `json:"attachments"` // Images attached when the post was created
+	Poll        *Poll         `json:"poll"`        // Poll attached when the post was created, nil for none
+	Likes       int           `json:"likes"`
+	Reposts     int           `json:"reposts"` // Number of reposts of this post
+	Quotes      int           `json:"quotes"`  // Number of posts quoting this post
+	Comments    []Comment     `json:"comments"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Original    *Post         `json:"-"` // The reposted or quoted post as the reader may see it, set when the post is read
 }
@@ -0,0 +1,15 @@
+package models
+
+// PostStatus tells whether a post is published or still being prepared by its author
+type PostStatus string
+
+const (
+	StatusPublished PostStatus = "published" // Seen according to the visibility of the post
+	StatusDraft     PostStatus = "draft"     // Only seen by the author until published
+	StatusScheduled PostStatus = "scheduled" // Only seen by the author until published at PublishAt
+)
+
+// Published reports whether s is the status of a published post; posts stored without a status are published
+func (s PostStatus) Published() bool {
+	return s == "" || s == StatusPublished
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// User is an account that can be mentioned in posts and comments
+type User struct {
+	ID          int       `json:"id"`       // Unique identifier for the user
+	Username    string    `json:"username"` // Unique, case insensitive handle used in @mentions
+	DisplayName string    `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Visibility controls who can see a post
+type Visibility string
+
+const (
+	VisibilityPublic    Visibility = "public"    // Everyone, in every listing
+	VisibilityUnlisted  Visibility = "unlisted"  // Everyone with the link and the followers' home timelines, but no public listing
+	VisibilityFollowers Visibility = "followers" // Only the author and their followers
+	VisibilityPrivate   Visibility = "private"   // Only the author
+)
+
+// Valid reports whether v is one of the visibility levels
+func (v Visibility) Valid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityFollowers, VisibilityPrivate:
+		return true
+	}
+	return false
+}
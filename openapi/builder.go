@@ -0,0 +1,385 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route documents a single registered route
+type Route struct {
+	Method  string
+	Path    string // Gin path template, e.g. /posts/:postID
+	Summary string
+	Tags    []string
+	Params  []Parameter // Path parameters not listed here are documented as strings
+	Request interface{} // Value whose type describes the JSON request body, nil when the route takes none
+	// RequestBodies documents request bodies in other content types, keyed by content type
+	RequestBodies map[string]interface{}
+	Responses     []ResponseSpec
+	Deprecated    bool
+}
+
+// ResponseSpec documents one possible response of a route
+type ResponseSpec struct {
+	Status      int
+	Description string
+	Body        interface{} // Value whose type describes the body, a *Schema, or nil for an empty body
+	ContentType string      // Defaults to application/json
+}
+
+// Builder assembles an OpenAPI document from routes, deriving schemas from Go types
+type Builder struct {
+	// StrictRequests documents request bodies as closed objects, for APIs that reject unknown fields
+	StrictRequests bool
+
+	doc   Document
+	types map[string]reflect.Type // Component name to the type it was generated from
+}
+
+// NewBuilder creates a builder for a document with the given info
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      map[string]PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		types: map[string]reflect.Type{},
+	}
+}
+
+// Document returns the assembled document
+func (b *Builder) Document() Document {
+	return b.doc
+}
+
+// Add documents the given routes under prefix (e.g. /v1)
+func (b *Builder) Add(prefix string, routes ...Route) {
+	for _, route := range routes {
+		fullPath := JoinPath(prefix, route.Path)
+		specPath, pathParams := ConvertPath(fullPath)
+
+		op := &Operation{
+			OperationID: operationID(route.Method, fullPath),
+			Summary:     route.Summary,
+			Tags:        route.Tags,
+			Responses:   map[string]Response{},
+			Deprecated:  route.Deprecated,
+		}
+
+		// Documented parameters first, then any undocumented path parameters
+		op.Parameters = append(op.Parameters, route.Params...)
+		for _, name := range pathParams {
+			if !hasParameter(route.Params, name, "path") {
+				op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+			}
+		}
+
+		bodies := map[string]interface{}{}
+		for contentType, body := range route.RequestBodies {
+			bodies[contentType] = body
+		}
+		if route.Request != nil {
+			bodies["application/json"] = route.Request
+		}
+		if len(bodies) > 0 {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+			for contentType, body := range bodies {
+				schema := b.schemaOf(body)
+				if b.StrictRequests {
+					b.close(schema)
+				}
+				op.RequestBody.Content[contentType] = MediaType{Schema: schema}
+			}
+		}
+
+		for _, spec := range route.Responses {
+			response := Response{Description: spec.Description}
+			if response.Description == "" {
+				response.Description = http.StatusText(spec.Status)
+			}
+			if spec.Body != nil {
+				contentType := spec.ContentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				response.Content = map[string]MediaType{contentType: {Schema: b.schemaOf(spec.Body)}}
+			}
+			op.Responses[strconv.Itoa(spec.Status)] = response
+		}
+
+		item, exists := b.doc.Paths[specPath]
+		if !exists {
+			item = PathItem{}
+			b.doc.Paths[specPath] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+}
+
+// JoinPath joins a prefix and a route path, keeping the trailing slash of the route as Gin does
+func JoinPath(prefix, routePath string) string {
+	joined := path.Join("/", prefix, routePath)
+	if strings.HasSuffix(routePath, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+// ConvertPath turns a Gin path template (/posts/:postID) into an OpenAPI one (/posts/{postID})
+// and returns the names of its path parameters
+func ConvertPath(ginPath string) (string, []string) {
+	var params []string
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable operation ID such as get_v1_posts_postID
+func operationID(method, fullPath string) string {
+	replacer := strings.NewReplacer("/", "_", ":", "", "*", "", "{", "", "}", "")
+	id := strings.Trim(replacer.Replace(fullPath), "_")
+	if id == "" {
+		id = "root"
+	}
+	return strings.ToLower(method) + "_" + id
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// close disallows additional properties on an object schema or the component it references
+func (b *Builder) close(schema *Schema) {
+	if schema.Ref != "" {
+		schema = b.doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	if schema != nil && schema.Type == "object" {
+		schema.AdditionalProperties = false
+	}
+}
+
+// schemaOf returns the schema for a documented value, which may already be a *Schema
+func (b *Builder) schemaOf(v interface{}) *Schema {
+	if schema, ok := v.(*Schema); ok {
+		return schema
+	}
+	return b.SchemaFor(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor derives a schema from a Go type using its json and binding struct tags
+// Named struct types are added to the components and referenced
+func (b *Builder) SchemaFor(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Ptr:
+		schema := b.SchemaFor(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		copied := *schema
+		copied.Nullable = true
+		return &copied
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.SchemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.SchemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := b.componentName(t)
+		if _, exists := b.doc.Components.Schemas[name]; !exists {
+			b.doc.Components.Schemas[name] = &Schema{} // Placeholder so recursive types terminate
+			b.doc.Components.Schemas[name] = b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{} // Any value
+}
+
+// componentName returns the component name of a named type, qualifying it with its package on collisions
+func (b *Builder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if existing, exists := b.types[name]; exists && existing != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	b.types[name] = t
+	return name
+}
+
+// structSchema builds an object schema from the exported fields of a struct
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a JSON name are flattened, as encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := b.structSchema(field.Type)
+			for propName, prop := range embedded.Properties {
+				schema.Properties[propName] = prop
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := b.SchemaFor(field.Type)
+		required := applyBinding(&prop, field.Tag.Get("binding"))
+		if description := field.Tag.Get("description"); description != "" {
+			prop = withDescription(prop, description)
+		}
+		schema.Properties[name] = prop
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// jsonName returns the JSON property name of a field, or an empty string when the tag does not set one
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// applyBinding translates Gin binding rules (required, min, max, len, oneof) into schema constraints
+// Rules after "dive" apply to elements and are not translated
+// Returns whether the field is required
+func applyBinding(prop **Schema, binding string) bool {
+	if binding == "" {
+		return false
+	}
+
+	// Constraints must not modify shared component references
+	schema := **prop
+	if schema.Ref != "" {
+		return hasRule(binding, "required")
+	}
+
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key == "dive" {
+			break
+		}
+		switch key {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			setBound(&schema, key, n)
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		}
+	}
+
+	// A required string must not be empty
+	if required && schema.Type == "string" && schema.MinLength == nil {
+		one := 1
+		schema.MinLength = &one
+	}
+
+	*prop = &schema
+	return required
+}
+
+// setBound applies a min, max or len rule according to the schema type
+func setBound(schema *Schema, rule string, n int) {
+	lower, upper := rule == "min" || rule == "len", rule == "max" || rule == "len"
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &n
+		}
+		if upper {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &n
+		}
+		if upper {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if lower {
+			schema.Minimum = &f
+		}
+		if upper {
+			schema.Maximum = &f
+		}
+	}
+}
+
+func hasRule(binding, rule string) bool {
+	for _, r := range strings.Split(binding, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// withDescription returns a copy of the schema with a description
+// References are returned unchanged since sibling keywords of $ref are ignored in OpenAPI 3.0
+func withDescription(schema *Schema, description string) *Schema {
+	if schema.Ref != "" {
+		return schema
+	}
+	copied := *schema
+	copied.Description = description
+	return &copied
+}
@@ -0,0 +1,66 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testComment struct {
+	ID   int    `json:"id"`
+	Text string `json:"text" binding:"required,max=150"`
+}
+
+type testPost struct {
+	ID        int           `json:"id"`
+	Content   string        `json:"content" binding:"required,max=250"`
+	Tags      []string      `json:"tags" binding:"max=5,dive,max=20"`
+	Comments  []testComment `json:"comments"`
+	CreatedAt time.Time     `json:"created_at"`
+	internal  string
+}
+
+func TestSchemaForStruct(t *testing.T) {
+	builder := NewBuilder(Info{Title: "test", Version: "1"})
+
+	ref := builder.SchemaFor(reflect.TypeOf(testPost{}))
+	if ref.Ref != "#/components/schemas/testPost" {
+		t.Fatalf("Expected reference to testPost, got '%s'", ref.Ref)
+	}
+
+	schema := builder.Document().Components.Schemas["testPost"]
+	if len(schema.Properties) != 5 {
+		t.Errorf("Expected 5 properties, got %d", len(schema.Properties))
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"content"}) {
+		t.Errorf("Expected only content to be required, got %v", schema.Required)
+	}
+
+	content := schema.Properties["content"]
+	if content.MaxLength == nil || *content.MaxLength != 250 || content.MinLength == nil || *content.MinLength != 1 {
+		t.Errorf("Expected content length constraints 1-250, got %+v", content)
+	}
+
+	tags := schema.Properties["tags"]
+	if tags.MaxItems == nil || *tags.MaxItems != 5 || tags.Items.MaxLength != nil {
+		t.Errorf("Expected tags to allow at most 5 items without element constraints, got %+v", tags)
+	}
+
+	if format := schema.Properties["created_at"].Format; format != "date-time" {
+		t.Errorf("Expected created_at format date-time, got '%s'", format)
+	}
+
+	if comments := schema.Properties["comments"]; comments.Items.Ref != "#/components/schemas/testComment" {
+		t.Errorf("Expected comments to reference testComment, got %+v", comments.Items)
+	}
+}
+
+func TestConvertPath(t *testing.T) {
+	specPath, params := ConvertPath("/v1/posts/:postID/comments")
+	if specPath != "/v1/posts/{postID}/comments" {
+		t.Errorf("Expected /v1/posts/{postID}/comments, got %s", specPath)
+	}
+	if !reflect.DeepEqual(params, []string{"postID"}) {
+		t.Errorf("Expected params [postID], got %v", params)
+	}
+}
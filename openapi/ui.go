@@ -0,0 +1,23 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed redoc.html
+var redocPage []byte
+
+// Handler serves the document as JSON
+func Handler(doc Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// UIHandler serves a Redoc page that renders the document published at /openapi.json
+func UIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", redocPage)
+}
@@ -0,0 +1,216 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ErrTestFailed is returned when a "test" operation does not match the document
+var ErrTestFailed = errors.New("test operation failed")
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to a JSON document
+// The operations are applied in order and the patch fails as a whole if any operation fails
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, errors.New("invalid JSON patch: " + err.Error())
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for _, operation := range operations {
+		var err error
+		target, err = apply(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// apply performs a single operation and returns the resulting document
+func apply(doc interface{}, operation Operation) (interface{}, error) {
+	tokens, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(doc, tokens, value)
+		case "replace":
+			return replace(doc, tokens, value)
+		default:
+			current, err := get(doc, tokens)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, tokens)
+		return doc, err
+	case "move", "copy":
+		fromTokens, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == "copy" {
+			value, err := get(doc, fromTokens)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, tokens, deepCopy(value))
+		}
+
+		if operation.Path == operation.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, value, err := remove(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, tokens, value)
+	default:
+		return nil, errors.New("unknown operation")
+	}
+}
+
+// add inserts value at the location referenced by tokens, appending to arrays for the "-" token
+func add(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return mutate(doc, tokens, func(container interface{}, token string) (interface{}, error) {
+		switch parent := container.(type) {
+		case map[string]interface{}:
+			parent[token] = value
+			return parent, nil
+		case []interface{}:
+			if token == "-" {
+				return append(parent, value), nil
+			}
+			index, err := arrayIndex(token, len(parent))
+			if err != nil {
+				return nil, err
+			}
+			parent = append(parent, nil)
+			copy(parent[index+1:], parent[index:])
+			parent[index] = value
+			return parent, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// replace sets the existing value referenced by tokens
+func replace(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	if _, err := get(doc, tokens); err != nil {
+		return nil, err
+	}
+	return mutate(doc, tokens, func(container interface{}, token string) (interface{}, error) {
+		switch parent := container.(type) {
+		case map[string]interface{}:
+			parent[token] = value
+			return parent, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(parent)-1)
+			if err != nil {
+				return nil, err
+			}
+			parent[index] = value
+			return parent, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// remove deletes the value referenced by tokens and returns the resulting document and the removed value
+func remove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+
+	var removed interface{}
+	doc, err := mutate(doc, tokens, func(container interface{}, token string) (interface{}, error) {
+		switch parent := container.(type) {
+		case map[string]interface{}:
+			value, exists := parent[token]
+			if !exists {
+				return nil, ErrPathNotFound
+			}
+			removed = value
+			delete(parent, token)
+			return parent, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(parent)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = parent[index]
+			return append(parent[:index:index], parent[index+1:]...), nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+	return doc, removed, err
+}
+
+// deepCopy copies decoded JSON values so that copied subtrees do not share maps or slices
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, child := range v {
+			copied[i] = deepCopy(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
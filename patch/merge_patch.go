@@ -0,0 +1,42 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ApplyMergePatch applies an RFC 7396 JSON Merge Patch to a JSON document
+// Members set to null in the patch are removed, objects are merged recursively and any other value replaces the target
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	var target, mergePatch interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &mergePatch); err != nil {
+		return nil, errors.New("invalid merge patch: " + err.Error())
+	}
+
+	return json.Marshal(mergeValue(target, mergePatch))
+}
+
+// mergeValue implements the MergePatch function from RFC 7396 section 2
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
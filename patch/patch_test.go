@@ -0,0 +1,98 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// assertJSONEqual compares two JSON documents ignoring formatting and member order
+func assertJSONEqual(t *testing.T, want, got string) {
+	t.Helper()
+	var wantValue, gotValue interface{}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("Invalid expected JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+		t.Fatalf("Invalid result JSON: %v", err)
+	}
+	if !reflect.DeepEqual(wantValue, gotValue) {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	// Examples from RFC 7396 appendix A
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"Replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"Add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"Remove member", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"Remove one of two members", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"Array replaces array", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"Nested merge", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"Array is replaced not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"Non object patch replaces document", `{"a":"foo"}`, `["c"]`, `["c"]`},
+		{"Nested null creates no member", `{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{"Object created for non object target", `[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := ApplyMergePatch([]byte(testCase.doc), []byte(testCase.patch))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assertJSONEqual(t, testCase.want, string(got))
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	// Examples from RFC 6902 appendix A
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{"Add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, false},
+		{"Add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, false},
+		{"Append to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`, false},
+		{"Remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, false},
+		{"Remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, false},
+		{"Replace value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, false},
+		{"Move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, false},
+		{"Move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, false},
+		{"Copy value", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, false},
+		{"Test success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, false},
+		{"Escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, false},
+		{"Add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`, false},
+		{"Test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ``, true},
+		{"Add to nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ``, true},
+		{"Remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ``, true},
+		{"Replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, ``, true},
+		{"Array index out of bounds", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":1}]`, ``, true},
+		{"Leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, ``, true},
+		{"Missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ``, true},
+		{"Unknown operation", `{"foo":"bar"}`, `[{"op":"merge","path":"/foo","value":1}]`, ``, true},
+		{"Move into own child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ``, true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch([]byte(testCase.doc), []byte(testCase.patch))
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if !testCase.wantErr {
+				assertJSONEqual(t, testCase.want, string(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package patch
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is returned when a JSON pointer does not resolve to a value
+var ErrPathNotFound = errors.New("path not found")
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil // The whole document
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("invalid JSON pointer: " + pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value referenced by tokens
+func get(doc interface{}, tokens []string) (interface{}, error) {
+	current := doc
+	for _, token := range tokens {
+		switch container := current.(type) {
+		case map[string]interface{}:
+			value, exists := container[token]
+			if !exists {
+				return nil, ErrPathNotFound
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = container[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return current, nil
+}
+
+// mutate applies fn to the container holding the last token and returns the resulting document
+// Arrays may be reallocated by fn, so every container on the way is reassigned into its parent
+func mutate(doc interface{}, tokens []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, exists := container[tokens[0]]
+		if !exists {
+			return nil, ErrPathNotFound
+		}
+		updated, err := mutate(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = updated
+		return container, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := mutate(container[index], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// arrayIndex parses an array index token, which must be within 0..max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.New("invalid array index: " + token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, errors.New("invalid array index: " + token)
+	}
+	if index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: up to Burst requests at once, refilled evenly over Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// interval returns the time it takes to refill a single token
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Burst)
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token is available, zero when allowed
+}
+
+// Store keeps token bucket state per key
+// The in-memory implementation is process local; a shared backend (e.g. Redis) can implement the same interface
+type Store interface {
+	Take(key string, limit Limit, now time.Time) Result
+}
+
+type bucket struct {
+	key      string
+	tokens   float64
+	lastSeen time.Time
+}
+
+// DefaultMaxBuckets bounds the number of clients tracked by a memory store created with NewMemoryStore
+const DefaultMaxBuckets = 10000
+
+// MemoryStore is an in-memory, concurrency safe Store
+// It tracks at most maxBuckets keys; beyond that the least recently used bucket is evicted, so clients
+// cycling through keys cannot grow it without bound
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*list.Element // Key to its element in recent
+	recent     *list.List               // Buckets, most recently used first
+	maxBuckets int
+}
+
+// NewMemoryStore creates an empty in-memory store tracking up to DefaultMaxBuckets keys
+func NewMemoryStore() *MemoryStore {
+	return NewBoundedMemoryStore(DefaultMaxBuckets)
+}
+
+// NewBoundedMemoryStore creates an empty in-memory store tracking up to maxBuckets keys
+func NewBoundedMemoryStore(maxBuckets int) *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*list.Element), recent: list.New(), maxBuckets: maxBuckets}
+}
+
+// Take refills the bucket for key based on the time elapsed since it was last used and consumes one token if available
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capacity := float64(limit.Burst)
+	perToken := limit.interval()
+
+	var b *bucket
+	if element, exists := s.buckets[key]; exists {
+		s.recent.MoveToFront(element)
+		b = element.Value.(*bucket)
+	} else {
+		// An evicted client starts again with a full bucket, so the least recently used one loses the least
+		for len(s.buckets) >= s.maxBuckets {
+			oldest := s.recent.Back()
+			s.recent.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: capacity, lastSeen: now}
+		s.buckets[key] = s.recent.PushFront(b)
+	}
+
+	// Refill tokens earned since the last request
+	elapsed := now.Sub(b.lastSeen)
+	if elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(perToken))
+		b.lastSeen = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+	return result
+}
+
+// Len returns the number of tracked keys
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Burst: 2, Period: 2 * time.Second} // One token per second
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		key           string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{"First request allowed", "a", 0, true, 1, 0},
+		{"Burst exhausted", "a", 0, true, 0, 0},
+		{"Rejected when empty", "a", 0, false, 0, time.Second},
+		{"Rejected with partial refill", "a", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		{"Allowed after refill", "a", time.Second, true, 0, 0},
+		{"Other key has its own bucket", "b", time.Second, true, 1, 0},
+		{"Refill is capped at burst", "a", time.Hour, true, 1, 0},
+	}
+
+	store := NewMemoryStore()
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := store.Take(testCase.key, limit, start.Add(testCase.at))
+
+			if result.Allowed != testCase.wantAllowed {
+				t.Fatalf("Expected allowed = %v, got = %v", testCase.wantAllowed, result.Allowed)
+			}
+			if result.Remaining != testCase.wantRemaining {
+				t.Errorf("Expected remaining = %d, got = %d", testCase.wantRemaining, result.Remaining)
+			}
+			if result.RetryAfter != testCase.wantRetry {
+				t.Errorf("Expected retry after = %v, got = %v", testCase.wantRetry, result.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	limit := Limit{Burst: 1, Period: time.Hour}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewBoundedMemoryStore(2)
+
+	store.Take("a", limit, start)
+	store.Take("b", limit, start)
+	store.Take("a", limit, start) // a is now more recently used than b
+	for i := 0; i < 100; i++ {
+		store.Take(fmt.Sprintf("rotated-%d", i), limit, start)
+	}
+
+	if store.Len() != 2 {
+		t.Errorf("Expected the store to stay at 2 buckets, got %d", store.Len())
+	}
+
+	tests := []struct {
+		name        string
+		key         string
+		wantAllowed bool
+	}{
+		{"Most recent key is kept", "rotated-99", false},
+		{"Evicted key starts with a full bucket", "a", true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := store.Take(testCase.key, limit, start); result.Allowed != testCase.wantAllowed {
+				t.Errorf("Expected allowed = %v, got = %v", testCase.wantAllowed, result.Allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package richtext
+
+import (
+	"mini-social-media-api/hashtag"
+	"mini-social-media-api/mention"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// EntityType identifies what an entity of a text is
+type EntityType string
+
+const (
+	URL      EntityType = "url"
+	Hashtag  EntityType = "hashtag"
+	Mention  EntityType = "mention"
+	Emphasis EntityType = "emphasis" // *text* or _text_
+	Strong   EntityType = "strong"   // **text**
+	Code     EntityType = "code"     // `text`
+)
+
+// Entity is a span of a text with a meaning
+// Offsets count characters (Unicode code points) and include any Markdown markers
+type Entity struct {
+	Type   EntityType
+	Start  int
+	End    int
+	Value  string // The URL, the normalized tag, the username, or the text between the Markdown markers
+	UserID int    // Mentions only: the mentioned user, 0 if unknown; set by the caller
+}
+
+// markerLength returns the number of characters of each Markdown marker around the entity
+func (e Entity) markerLength() int {
+	switch e.Type {
+	case Strong:
+		return 2
+	case Emphasis, Code:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Extract returns the entities of a text sorted by start offset, outer entities first
+// Code spans are extracted first and hide everything inside them. URLs, mentions and hashtags
+// are atomic: a # or @ inside a URL is not a hashtag or mention, and Markdown markers inside
+// them are literal. Emphasis and strong text may contain atomic entities and each other
+func Extract(text string) []Entity {
+	runes := []rune(text)
+	protected := make([]bool, len(runes)+1)
+	var entities []Entity
+
+	claim := func(e Entity) bool {
+		for i := e.Start; i < e.End; i++ {
+			if protected[i] {
+				return false
+			}
+		}
+		for i := e.Start; i < e.End; i++ {
+			protected[i] = true
+		}
+		entities = append(entities, e)
+		return true
+	}
+
+	for _, e := range findCode(runes) {
+		claim(e)
+	}
+	for _, e := range findURLs(runes) {
+		claim(e)
+	}
+	for _, m := range mention.Extract(text) {
+		claim(Entity{Type: Mention, Start: m.Start, End: m.End, Value: m.Username})
+	}
+	for _, m := range hashtag.Find(text) {
+		claim(Entity{Type: Hashtag, Start: m.Start, End: m.End, Value: m.Tag})
+	}
+	entities = append(entities, findEmphasis(runes, protected, 0, len(runes))...)
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Start != entities[j].Start {
+			return entities[i].Start < entities[j].Start
+		}
+		return entities[i].End > entities[j].End
+	})
+	return entities
+}
+
+// findCode returns the `code` spans of the text; a span does not cross lines
+func findCode(runes []rune) []Entity {
+	var spans []Entity
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '`' {
+			continue
+		}
+		for j := i + 1; j < len(runes) && runes[j] != '\n'; j++ {
+			if runes[j] == '`' {
+				if j > i+1 {
+					spans = append(spans, Entity{Type: Code, Start: i, End: j + 1, Value: string(runes[i+1 : j])})
+				}
+				i = j
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// findURLs returns the http and https URLs of the text
+// Trailing punctuation is not part of a URL, nor is a closing parenthesis without a matching opening one
+func findURLs(runes []rune) []Entity {
+	var urls []Entity
+	for i := 0; i < len(runes); i++ {
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+		rest := strings.ToLower(string(runes[i:min(i+8, len(runes))]))
+		var scheme int
+		switch {
+		case strings.HasPrefix(rest, "https://"):
+			scheme = 8
+		case strings.HasPrefix(rest, "http://"):
+			scheme = 7
+		default:
+			continue
+		}
+
+		end := i + scheme
+		for end < len(runes) && isURLRune(runes[end]) {
+			end++
+		}
+		for end > i+scheme {
+			last := runes[end-1]
+			if strings.ContainsRune(".,:;!?*~", last) || (last == ')' && !hasOpeningParen(runes[i:end-1])) {
+				end--
+				continue
+			}
+			break
+		}
+		if end == i+scheme {
+			continue // A scheme without a host
+		}
+
+		urls = append(urls, Entity{Type: URL, Start: i, End: end, Value: string(runes[i:end])})
+		i = end - 1
+	}
+	return urls
+}
+
+// findEmphasis returns the strong and emphasis spans between lo and hi, including nested ones
+// Markers must hug the text (**bold**, not ** bold **), spans do not cross lines or protected
+// characters, and underscores only count at word boundaries so snake_case stays literal
+func findEmphasis(runes []rune, protected []bool, lo, hi int) []Entity {
+	var spans []Entity
+	for i := lo; i < hi; i++ {
+		if protected[i] || (runes[i] != '*' && runes[i] != '_') {
+			continue
+		}
+		marker := runes[i]
+		width := 1
+		if marker == '*' && i+1 < hi && runes[i+1] == '*' && !protected[i+1] {
+			width = 2
+		}
+		if marker == '_' && i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		end := closingMarker(runes, protected, i+width, hi, marker, width)
+		if end < 0 && width == 2 {
+			width = 1 // A lone ** may still open a single * span
+			end = closingMarker(runes, protected, i+width, hi, marker, width)
+		}
+		if end < 0 {
+			continue
+		}
+
+		entityType := Emphasis
+		if width == 2 {
+			entityType = Strong
+		}
+		spans = append(spans, Entity{Type: entityType, Start: i, End: end + width, Value: string(runes[i+width : end])})
+		spans = append(spans, findEmphasis(runes, protected, i+width, end)...)
+		i = end + width - 1
+	}
+	return spans
+}
+
+// closingMarker returns the offset of the marker closing a span whose text starts at from, or -1
+func closingMarker(runes []rune, protected []bool, from, hi int, marker rune, width int) int {
+	if from >= hi || unicode.IsSpace(runes[from]) {
+		return -1
+	}
+	for j := from + 1; j+width <= hi; j++ {
+		if runes[j] == '\n' {
+			return -1
+		}
+		if !hasMarker(runes, protected, j, marker, width) || unicode.IsSpace(runes[j-1]) {
+			continue
+		}
+		after := j + width
+		if marker == '_' && after < len(runes) && isWordRune(runes[after]) {
+			continue
+		}
+		if width == 1 && marker == '*' && after < hi && runes[after] == '*' {
+			j++ // Part of a ** marker
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// hasMarker reports whether width unprotected marker runes start at j
+func hasMarker(runes []rune, protected []bool, j int, marker rune, width int) bool {
+	for k := j; k < j+width; k++ {
+		if k >= len(runes) || runes[k] != marker || protected[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// isURLRune reports whether r may appear in a URL found in text
+// Quotes, angle brackets and backticks end a URL so it can never break out of an HTML attribute
+func isURLRune(r rune) bool {
+	return r > ' ' && r != 0x7f && !unicode.IsSpace(r) && !strings.ContainsRune("\"'<>`", r)
+}
+
+// hasOpeningParen reports whether the URL text has more ( than )
+func hasOpeningParen(runes []rune) bool {
+	depth := 0
+	for _, r := range runes {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,103 @@
+package richtext
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// HTMLOptions configures the links RenderHTML creates for hashtags and mentions
+type HTMLOptions struct {
+	HashtagURL func(tag string) string // Link of a hashtag; no link when nil or empty
+	MentionURL func(e Entity) string   // Link of a mention; no link when nil or empty, e.g. for unknown users
+}
+
+// RenderHTML renders a text as HTML using its entities
+// Only a limited Markdown subset is supported: **strong**, *emphasis* or _emphasis_, `code`,
+// links for http and https URLs, hashtags and mentions, and line breaks. Everything else,
+// including any HTML in the text, is escaped, so the output is safe to insert into a page
+func RenderHTML(text string, entities []Entity, options HTMLOptions) string {
+	runes := []rune(text)
+	var out strings.Builder
+	renderRange(&out, runes, entities, 0, len(runes), options)
+	return out.String()
+}
+
+// renderRange renders the text between lo and hi, with entities sorted by start offset, outer entities first
+func renderRange(out *strings.Builder, runes []rune, entities []Entity, lo, hi int, options HTMLOptions) {
+	pos := lo
+	for i := 0; i < len(entities); i++ {
+		e := entities[i]
+		if e.Start < pos || e.End > hi {
+			continue // Nested in an entity already rendered, or outside this range
+		}
+
+		writeText(out, runes[pos:e.Start])
+		inner := entities[i+1:]
+		switch e.Type {
+		case Strong, Emphasis:
+			tag := "em"
+			if e.Type == Strong {
+				tag = "strong"
+			}
+			out.WriteString("<" + tag + ">")
+			renderRange(out, runes, inner, e.Start+e.markerLength(), e.End-e.markerLength(), options)
+			out.WriteString("</" + tag + ">")
+		case Code:
+			out.WriteString("<code>")
+			out.WriteString(html.EscapeString(e.Value))
+			out.WriteString("</code>")
+		case URL:
+			writeLink(out, safeURL(e.Value), string(runes[e.Start:e.End]), "url")
+		case Hashtag:
+			href := ""
+			if options.HashtagURL != nil {
+				href = options.HashtagURL(e.Value)
+			}
+			writeLink(out, href, string(runes[e.Start:e.End]), "hashtag")
+		case Mention:
+			href := ""
+			if options.MentionURL != nil {
+				href = options.MentionURL(e)
+			}
+			writeLink(out, href, string(runes[e.Start:e.End]), "mention")
+		default:
+			writeText(out, runes[e.Start:e.End])
+		}
+		pos = e.End
+	}
+	writeText(out, runes[pos:hi])
+}
+
+// writeLink writes an anchor, or a span when there is no link
+func writeLink(out *strings.Builder, href, text, class string) {
+	if href == "" {
+		out.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + `</span>`)
+		return
+	}
+	out.WriteString(`<a class="` + class + `" href="` + html.EscapeString(href) + `"`)
+	if class == "url" {
+		out.WriteString(` rel="nofollow noopener noreferrer" target="_blank"`)
+	}
+	out.WriteString(`>` + html.EscapeString(text) + `</a>`)
+}
+
+// writeText escapes text and turns line breaks into <br>
+func writeText(out *strings.Builder, runes []rune) {
+	lines := strings.Split(string(runes), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			out.WriteString("<br>")
+		}
+		out.WriteString(html.EscapeString(line))
+	}
+}
+
+// safeURL returns the URL if it is an absolute http or https URL, and an empty string otherwise
+func safeURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+	return parsed.String()
+}
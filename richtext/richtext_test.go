@@ -0,0 +1,146 @@
+package richtext
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Entity
+	}{
+		{"URL with trailing punctuation", "See https://example.com/a?b=1.", []Entity{
+			{Type: URL, Start: 4, End: 29, Value: "https://example.com/a?b=1"},
+		}},
+		{"URL with balanced parentheses", "(https://en.wikipedia.org/wiki/Go_(language))", []Entity{
+			{Type: URL, Start: 1, End: 44, Value: "https://en.wikipedia.org/wiki/Go_(language)"},
+		}},
+		{"Hash and at inside a URL", "https://example.com/@bob/#top", []Entity{
+			{Type: URL, Start: 0, End: 29, Value: "https://example.com/@bob/#top"},
+		}},
+		{"Hashtag and mention", "#Go by @rob", []Entity{
+			{Type: Hashtag, Start: 0, End: 3, Value: "go"},
+			{Type: Mention, Start: 7, End: 11, Value: "rob"},
+		}},
+		{"Strong containing a hashtag", "**big #news**", []Entity{
+			{Type: Strong, Start: 0, End: 13, Value: "big #news"},
+			{Type: Hashtag, Start: 6, End: 11, Value: "news"},
+		}},
+		{"Nested emphasis", "**very _nested_ text**", []Entity{
+			{Type: Strong, Start: 0, End: 22, Value: "very _nested_ text"},
+			{Type: Emphasis, Start: 7, End: 15, Value: "nested"},
+		}},
+		{"Code hides everything", "`#no @one *here*`", []Entity{
+			{Type: Code, Start: 0, End: 17, Value: "#no @one *here*"},
+		}},
+		{"Snake case stays literal", "use snake_case_names", nil},
+		{"Markers must hug the text", "2 * 3 * 4", nil},
+		{"Underscores in a URL", "https://example.com/_x_", []Entity{
+			{Type: URL, Start: 0, End: 23, Value: "https://example.com/_x_"},
+		}},
+		{"Spans do not cross lines", "*one\ntwo*", nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := Extract(testCase.text)
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("Expected %+v, got %+v", testCase.want, got)
+			}
+		})
+	}
+}
+
+var testOptions = HTMLOptions{
+	HashtagURL: func(tag string) string { return "/tags/" + tag },
+	MentionURL: func(e Entity) string {
+		if e.UserID == 0 {
+			return ""
+		}
+		return "/users/" + strconv.Itoa(e.UserID)
+	},
+}
+
+func render(text string) string {
+	entities := Extract(text)
+	for i := range entities {
+		if entities[i].Type == Mention && entities[i].Value == "rob" {
+			entities[i].UserID = 7
+		}
+	}
+	return RenderHTML(text, entities, testOptions)
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"Plain text", "hello\nworld", "hello<br>world"},
+		{"Markdown subset", "**bold** *it* _it_ `x < y`", "<strong>bold</strong> <em>it</em> <em>it</em> <code>x &lt; y</code>"},
+		{"Links", "#Go @rob @nobody https://go.dev", `<a class="hashtag" href="/tags/go">#Go</a> <a class="mention" href="/users/7">@rob</a> <span class="mention">@nobody</span> <a class="url" href="https://go.dev" rel="nofollow noopener noreferrer" target="_blank">https://go.dev</a>`},
+		{"Nested", "**a #b**", `<strong>a <a class="hashtag" href="/tags/b">#b</a></strong>`},
+		{"Unsupported Markdown is literal", "# Title\n[x](https://a.b)", `# Title<br>[x](<a class="url" href="https://a.b" rel="nofollow noopener noreferrer" target="_blank">https://a.b</a>)`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := render(testCase.text); got != testCase.want {
+				t.Errorf("Expected %q, got %q", testCase.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderHTMLEscapesUntrustedInput(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"Script tag", "<script>alert(1)</script>"},
+		{"Image with handler", `<img src=x onerror="alert(1)">`},
+		{"Script in strong", "**<script>alert(1)</script>**"},
+		{"Script in code", "`<script>alert(1)</script>`"},
+		{"Quote breaking out of href", `https://example.com/"onmouseover="alert(1)`},
+		{"Single quote breaking out of href", `https://example.com/'onmouseover='alert(1)`},
+		{"Angle bracket after URL", "https://example.com/<script>alert(1)</script>"},
+		{"Javascript scheme", "javascript:alert(1)"},
+		{"Markdown link with javascript scheme", "[click](javascript:alert(1))"},
+		{"Data URL", "data:text/html,<script>alert(1)</script>"},
+		{"Entity encoded script", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"Null byte and markers", "*\x00<b>*"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := render(testCase.text)
+
+			if strings.Contains(testCase.text, "<script>") && !strings.Contains(got, "&lt;script&gt;") {
+				t.Errorf("Expected the script tag to be escaped and kept as text, got %q", got)
+			}
+			assertOnlyAllowedTags(t, got)
+		})
+	}
+}
+
+// allowedTag matches every tag the renderer may produce; attribute values cannot contain quotes or angle brackets
+var allowedTag = regexp.MustCompile(`^(<(/?(strong|em|code|a|span)|br)>|<span class="(hashtag|mention)">|<a class="(hashtag|mention)" href="/[^"<>]*">|<a class="url" href="https?://[^"'<>]*" rel="nofollow noopener noreferrer" target="_blank">)`)
+
+// assertOnlyAllowedTags fails if the HTML contains a tag or attribute the renderer never produces
+func assertOnlyAllowedTags(t *testing.T, out string) {
+	t.Helper()
+	for i := strings.Index(out, "<"); i >= 0; i = strings.Index(out, "<") {
+		tag := allowedTag.FindString(out[i:])
+		if tag == "" {
+			t.Errorf("Unexpected tag in %q", out[i:])
+			return
+		}
+		out = out[i+len(tag):]
+	}
+}
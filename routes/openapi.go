@@ -0,0 +1,528 @@
+package routes
+
+import (
+	"fmt"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/openapi"
+	"mini-social-media-api/patch"
+	"mini-social-media-api/services"
+	"net/http"
+)
+
+var postIDParam = openapi.Parameter{Name: "postID", In: "path", Required: true, Description: "ID of the post", Schema: &openapi.Schema{Type: "integer"}}
+
+var userIDParam = openapi.Parameter{Name: "userID", In: "path", Required: true, Description: "ID of the user", Schema: &openapi.Schema{Type: "integer"}}
+
+var mediaIDParam = openapi.Parameter{Name: "mediaID", In: "path", Required: true, Description: "ID of the uploaded media", Schema: &openapi.Schema{Type: "string"}}
+
+// callerParam identifies the calling user, whose blocks and mutes apply; the API has no authentication, so the header is trusted
+var callerParam = openapi.Parameter{Name: "X-User-ID", In: "header", Description: "ID of the calling user", Schema: &openapi.Schema{Type: "integer"}}
+
+// requiredCallerParam is callerParam for routes acting on behalf of a user
+var requiredCallerParam = openapi.Parameter{Name: "X-User-ID", In: "header", Required: true, Description: "ID of the calling user", Schema: &openapi.Schema{Type: "integer"}}
+
+// pageParams are the pagination parameters of listing routes
+var pageParams = []openapi.Parameter{
+	{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer"}},
+	{Name: "limit", In: "query", Description: "Number of items per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+}
+
+// renderParam is accepted by every route returning posts
+var renderParam = openapi.Parameter{Name: "render", In: "query", Description: "Set to html to add the sanitized HTML rendering of posts and comments", Schema: &openapi.Schema{Type: "string", Enum: []string{"html"}}}
+
+// lastEventIDParams resume an event stream; EventSource sends the header when it reconnects
+var lastEventIDParams = []openapi.Parameter{
+	{Name: "Last-Event-ID", In: "header", Description: "ID of the last event received, to resume after it", Schema: &openapi.Schema{Type: "integer"}},
+	{Name: "last_event_id", In: "query", Description: "Last-Event-ID for clients that cannot set headers", Schema: &openapi.Schema{Type: "integer"}},
+}
+
+// eventStreamDescription documents the format of the event stream routes
+const eventStreamDescription = "Server-Sent Events named post.created, post.updated, post.liked, post.commented (data: the post) " +
+	"and post.deleted (data: its ID), a resync event when missed events are no longer kept, and keepalive comments"
+
+// errorResponses documents the error bodies shared by the API routes
+func errorResponses(statuses ...int) []openapi.ResponseSpec {
+	specs := make([]openapi.ResponseSpec, 0, len(statuses)+1)
+	for _, status := range statuses {
+		specs = append(specs, openapi.ResponseSpec{Status: status, Body: dto.ErrorResponse{}})
+	}
+	return append(specs, openapi.ResponseSpec{Status: http.StatusTooManyRequests, Description: "Rate limit exceeded, see Retry-After", Body: dto.ErrorResponse{}})
+}
+
+// streamErrorResponses documents the error bodies of the event stream routes, which also cap the streams open at once
+func streamErrorResponses(statuses ...int) []openapi.ResponseSpec {
+	specs := errorResponses(statuses...)
+	specs[len(specs)-1].Description = "Rate limit exceeded, see Retry-After, or too many event streams open at once"
+	return specs
+}
+
+// v1Docs documents every route registered by registerV1Routes, relative to the version prefix
+func v1Docs() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodPost, Path: "/posts/", Summary: "Create a post, attributed to the calling user if any", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{callerParam, renderParam},
+			Request: dto.CreatePostRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusCreated, Description: "Post created", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header does not name a user", Body: dto.ErrorResponse{}},
+				{Status: http.StatusNotFound, Description: "An attachment or the quoted post does not exist", Body: dto.ErrorResponse{}},
+				{Status: http.StatusConflict, Description: "An attachment is already used by another post", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodPut, Path: "/posts/:postID", Summary: "Update the content of a post; only its author may", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{postIDParam, callerParam, renderParam},
+			Request: dto.UpdatePostRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post updated", Body: dto.PostEnvelope{}},
+				{Status: http.StatusForbidden, Description: "Only the author of the post may change it", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodPatch, Path: "/posts/:postID", Summary: "Partially update a post with a JSON Merge Patch or JSON Patch applied to the post as returned by GET", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, callerParam, renderParam},
+			RequestBodies: map[string]interface{}{
+				string(services.MergePatch): dto.PostMergePatch{},
+				string(services.JSONPatch):  []patch.Operation{},
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post patched", Body: dto.PostEnvelope{}},
+				{Status: http.StatusForbidden, Description: "Only the author of the post may change it", Body: dto.ErrorResponse{}},
+				{Status: http.StatusConflict, Description: "A JSON Patch test operation failed", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnsupportedMediaType, Description: "Unsupported patch format", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The patch changes a read-only field or produces an invalid post", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPut, Path: "/posts/:postID/visibility", Summary: "Change who can see a post; only its author may", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Request: dto.UpdateVisibilityRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Visibility changed", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodPut, Path: "/posts/:postID/schedule", Summary: "Choose when a draft or scheduled post of the caller is published", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Request: dto.SchedulePostRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post scheduled, or turned back into a draft", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The post is already published or the time is not in the future", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/posts/:postID/schedule", Summary: "Cancel the publication of a scheduled post of the caller, which becomes a draft", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post turned back into a draft", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The post is already published", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/publish", Summary: "Publish a draft or scheduled post of the caller now", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The published post", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The post is already published", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/posts/scheduled", Summary: "List the drafts and scheduled posts of the caller, the next to be published first", Tags: []string{"posts"},
+			Params: append(append([]openapi.Parameter{requiredCallerParam}, pageParams...), renderParam),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of unpublished posts", Body: dto.ScheduledPostsPage{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, streamErrorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/posts/:postID", Summary: "Delete a post and its comments; only its author may", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, callerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post deleted", Body: dto.PostEnvelope{}},
+				{Status: http.StatusForbidden, Description: "Only the author of the post may delete it", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts:batch", Summary: "Create, update, delete and like many posts in one request", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{callerParam, renderParam},
+			Request: dto.BatchRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Result of every operation", Body: dto.BatchResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "Atomic batch rolled back, see the per-operation results", Body: dto.BatchResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/posts/", Summary: "List posts with pagination", Tags: []string{"posts"},
+			Params: []openapi.Parameter{
+				{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "limit", In: "query", Description: "Number of posts per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+				callerParam, renderParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of posts", Body: dto.PostsPage{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/posts/:postID", Summary: "Get a post with its likes and comments", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, callerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The post", Body: dto.PostEnvelope{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/posts/:postID/events", Summary: "Stream the changes to a post until it is deleted", Tags: []string{"posts"},
+			Params: append(append([]openapi.Parameter{postIDParam, callerParam}, lastEventIDParams...), renderParam),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: eventStreamDescription, Body: &openapi.Schema{Type: "string"}, ContentType: "text/event-stream"},
+			}, streamErrorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/like", Summary: "Like a post", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, callerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post liked", Body: dto.PostEnvelope{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/poll/votes", Summary: "Vote in the poll of a post, once per user; the results are shown once voted", Tags: []string{"posts"},
+			Params:  []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Request: dto.PollVoteRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Vote recorded, with the results", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+				{Status: http.StatusNotFound, Description: "The post does not exist or has no poll", Body: dto.ErrorResponse{}},
+				{Status: http.StatusConflict, Description: "The poll is closed or the caller already voted", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The choices do not match the options of the poll", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/pin", Summary: fmt.Sprintf("Pin a post of the caller to the top of their profile, at most %d", services.MaxPinnedPosts), Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post pinned", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "The post is not published or too many posts are pinned", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/posts/:postID/pin", Summary: "Unpin a post of the caller", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post unpinned", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+				{Status: http.StatusForbidden, Description: "The caller is not the author of the post", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/bookmark", Summary: "Privately bookmark a post", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Post bookmarked", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/posts/:postID/bookmark", Summary: "Remove a post from the bookmarks of the caller", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Bookmark removed", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/repost", Summary: "Reshare a public or unlisted post with the caller's followers; reposting a repost reshares its original", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusCreated, Description: "The repost, with the original embedded", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+				{Status: http.StatusConflict, Description: "The caller already reposted the post", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnprocessableEntity, Description: "Only public and unlisted posts can be reposted", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/posts/:postID/repost", Summary: "Undo the caller's repost of a post", Tags: []string{"posts"},
+			Params: []openapi.Parameter{postIDParam, requiredCallerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The deleted repost", Body: dto.PostEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/posts/:postID/comments", Summary: "Comment on a post", Tags: []string{"comments"},
+			Params:  []openapi.Parameter{postIDParam, callerParam, renderParam},
+			Request: dto.CreateCommentRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Comment added", Body: dto.PostEnvelope{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/search", Summary: "Search the content and comments of posts", Tags: []string{"search"},
+			Params: []openapi.Parameter{
+				{Name: "q", In: "query", Required: true, Description: "Words to match after stemming; wrap words in double quotes to match a phrase", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "limit", In: "query", Description: "Number of results per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+				callerParam, renderParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of matching posts, best match first", Body: dto.SearchResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/media/", Summary: "Upload a JPEG, PNG or GIF image to attach to a post; metadata such as EXIF is stripped", Tags: []string{"media"},
+			RequestBodies: map[string]interface{}{
+				"multipart/form-data": &openapi.Schema{
+					Type:     "object",
+					Required: []string{"file"},
+					Properties: map[string]*openapi.Schema{
+						"file":     {Type: "string", Format: "binary", Description: "The image, at most 5 MiB; its type is detected from the content"},
+						"alt_text": {Type: "string", Description: "Description of the image for screen readers, at most 1000 characters"},
+					},
+				},
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusCreated, Description: "Media uploaded", Body: dto.MediaEnvelope{}},
+				{Status: http.StatusRequestEntityTooLarge, Description: "The file exceeds 5 MiB", Body: dto.ErrorResponse{}},
+				{Status: http.StatusUnsupportedMediaType, Description: "The file is not a JPEG, PNG or GIF image", Body: dto.ErrorResponse{}},
+				{Status: http.StatusServiceUnavailable, Description: "Media storage is not configured", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/media/:mediaID", Summary: "Download an uploaded image attached to a post the caller can see, or uploaded by the caller", Tags: []string{"media"},
+			Params: []openapi.Parameter{mediaIDParam, callerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The image, with the content type it was detected as", ContentType: "image/*", Body: &openapi.Schema{Type: "string", Format: "binary"}},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/media/:mediaID/thumbnail", Summary: "Download the thumbnail of an uploaded image attached to a post the caller can see, or uploaded by the caller", Tags: []string{"media"},
+			Params: []openapi.Parameter{mediaIDParam, callerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "JPEG fitting in 320x320 pixels", ContentType: "image/jpeg", Body: &openapi.Schema{Type: "string", Format: "binary"}},
+			}, errorResponses(http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/users/", Summary: "Register a user that can be mentioned", Tags: []string{"users"},
+			Request: dto.CreateUserRequest{},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusCreated, Description: "User created", Body: dto.UserEnvelope{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:userID", Summary: "Get a user", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The user", Body: dto.UserEnvelope{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:userID/mentions", Summary: "List the posts mentioning a user in their content or comments, newest first", Tags: []string{"users"},
+			Params: []openapi.Parameter{
+				userIDParam,
+				{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "limit", In: "query", Description: "Number of posts per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+				callerParam, renderParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of posts", Body: dto.MentionsPage{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/users/:userID/follow", Summary: "Follow a user; following a user twice is not an error", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The followed user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/users/:userID/follow", Summary: "Stop following a user", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The unfollowed user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/users/:userID/block", Summary: "Block a user: both users stop following each other and no longer see each other's posts and comments; the blocked user cannot comment on or like the blocker's posts", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The blocked user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/users/:userID/block", Summary: "Unblock a user; follows removed by the block are not restored", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The unblocked user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/users/:userID/mute", Summary: "Mute a user: their posts and comments are hidden from the caller's listings without telling them", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The muted user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity)...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/users/:userID/mute", Summary: "Unmute a user", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The unmuted user", Body: dto.UserEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:userID/followers", Summary: "List the followers of a user, most recent follow first", Tags: []string{"users"},
+			Params: append([]openapi.Parameter{userIDParam}, pageParams...),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of followers", Body: dto.UsersPage{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:userID/following", Summary: "List the users a user follows, most recent follow first", Tags: []string{"users"},
+			Params: append([]openapi.Parameter{userIDParam}, pageParams...),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of followed users", Body: dto.UsersPage{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:userID/pinned", Summary: "List the posts pinned by a user that the caller can see, most recently pinned first", Tags: []string{"users"},
+			Params: []openapi.Parameter{userIDParam, callerParam, renderParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "The pinned posts", Body: dto.PinnedPostsResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/me/bookmarks", Summary: "List the bookmarks of the caller, most recently bookmarked first", Tags: []string{"posts"},
+			Params: []openapi.Parameter{
+				requiredCallerParam,
+				{Name: "cursor", In: "query", Description: "next_cursor of the previous page, absent for the first page", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "limit", In: "query", Description: "Number of posts per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+				renderParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of bookmarked posts", Body: dto.BookmarksPage{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/notifications/", Summary: "List the notifications of the caller, most recently updated first", Tags: []string{"notifications"},
+			Params: append(append([]openapi.Parameter{requiredCallerParam}, pageParams...),
+				openapi.Parameter{Name: "unread", In: "query", Description: "Set to true to list unread notifications only", Schema: &openapi.Schema{Type: "boolean"}}),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of notifications", Body: dto.NotificationsPage{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/notifications/read", Summary: "Mark every notification of the caller as read", Tags: []string{"notifications"},
+			Params: []openapi.Parameter{requiredCallerParam},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Number of notifications marked as read", Body: dto.NotificationsReadResponse{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodPost, Path: "/notifications/:notificationID/read", Summary: "Mark a notification of the caller as read; later activity starts a new notification", Tags: []string{"notifications"},
+			Params: []openapi.Parameter{
+				{Name: "notificationID", In: "path", Required: true, Description: "ID of the notification", Schema: &openapi.Schema{Type: "integer"}},
+				requiredCallerParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Notification marked as read", Body: dto.NotificationEnvelope{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/timeline/home", Summary: "List the posts of the calling user and of the accounts they follow, newest first", Tags: []string{"timeline"},
+			Params: append(append([]openapi.Parameter{requiredCallerParam}, pageParams...), renderParam),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of the home timeline", Body: dto.TimelinePage{}},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/timeline/home/events", Summary: "Stream the changes to the posts of the calling user and of the accounts they followed when the stream started", Tags: []string{"timeline"},
+			Params: append(append([]openapi.Parameter{requiredCallerParam}, lastEventIDParams...), renderParam),
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: eventStreamDescription, Body: &openapi.Schema{Type: "string"}, ContentType: "text/event-stream"},
+				{Status: http.StatusUnauthorized, Description: "The X-User-ID header is missing or does not name a user", Body: dto.ErrorResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/tags/trending", Summary: "List the hashtags used more than usual, fastest rising first", Tags: []string{"tags"},
+			Params: []openapi.Parameter{
+				{Name: "window", In: "query", Description: "Sliding window to count tag use over: 1h (default) or 24h", Schema: &openapi.Schema{Type: "string", Enum: []string{"1h", "24h"}}},
+				{Name: "limit", In: "query", Description: "Maximum number of tags, 1-50 (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "Trending tags", Body: dto.TrendingTagsResponse{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+		{
+			Method: http.MethodGet, Path: "/tags/:tag/posts", Summary: "List the posts using a hashtag, most recently tagged first", Tags: []string{"tags"},
+			Params: []openapi.Parameter{
+				{Name: "tag", In: "path", Required: true, Description: "Hashtag, case insensitive, without the leading #", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "limit", In: "query", Description: "Number of posts per page (default 10)", Schema: &openapi.Schema{Type: "integer"}},
+				callerParam, renderParam,
+			},
+			Responses: append([]openapi.ResponseSpec{
+				{Status: http.StatusOK, Description: "A page of tagged posts", Body: dto.TagPostsPage{}},
+			}, errorResponses(http.StatusBadRequest)...),
+		},
+	}
+}
+
+// operationalDocs documents the unversioned operational routes
+func operationalDocs() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus metrics", Tags: []string{"operations"},
+			Responses: []openapi.ResponseSpec{{Status: http.StatusOK, ContentType: "text/plain", Body: &openapi.Schema{Type: "string"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/openapi.json", Summary: "This OpenAPI document", Tags: []string{"operations"},
+			Responses: []openapi.ResponseSpec{{Status: http.StatusOK, Body: &openapi.Schema{Type: "object"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/docs", Summary: "API reference rendered from this document", Tags: []string{"operations"},
+			Responses: []openapi.ResponseSpec{{Status: http.StatusOK, ContentType: "text/html", Body: &openapi.Schema{Type: "string"}}},
+		},
+	}
+}
+
+// buildOpenAPI assembles the OpenAPI document for every route registered by InitRoutes
+func buildOpenAPI() openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:       "Mini Social Media API",
+		Description: "Create, update, like, comment on and search posts.",
+		Version:     "1.0.0",
+	})
+	builder.StrictRequests = true // Controllers reject unknown request fields
+
+	builder.Add("", operationalDocs()...)
+	builder.Add("/v1", v1Docs()...)
+
+	// The unversioned aliases share the v1 documentation
+	deprecated := v1Docs()
+	for i := range deprecated {
+		deprecated[i].Deprecated = true
+	}
+	builder.Add("", deprecated...)
+
+	return builder.Document()
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"encoding/json"
+	"mini-social-media-api/openapi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := InitRoutes()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode OpenAPI document: %v", err)
+	}
+
+	for _, route := range router.Routes() {
+		specPath, _ := openapi.ConvertPath(route.Path)
+		item, exists := doc.Paths[specPath]
+		if !exists || item[strings.ToLower(route.Method)] == nil {
+			t.Errorf("Route %s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+}
@@ -1,25 +1,73 @@
 package routes
 
 import (
-	"mini-social-media-api/controllers"
+	"mini-social-media-api/middleware"
+	"mini-social-media-api/openapi"
+	"mini-social-media-api/ratelimit"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
+// Lifecycle of the unversioned routes, kept as aliases of /v1 for existing clients
+var (
+	unversionedDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	unversionedSunsetAt     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// MaxStreamsPerClient is how many event streams each client may hold open at once
+const MaxStreamsPerClient = 5
+
+// RateLimitAPIKeys are the API keys issued to clients, each with its own rate limit budget
+// Requests without a valid key are limited per IP address
+var RateLimitAPIKeys []string
+
+// TrustedProxies are the addresses or CIDR ranges of the reverse proxies in front of the server
+// X-Forwarded-For is only read from them; by default no proxy is trusted and clients are identified
+// by the address they connect from, so they cannot pick their rate limit bucket with the header
+var TrustedProxies []string
+
 // InitRoutes initializes all the application routes and returns the configured Gin router
 func InitRoutes() *gin.Engine {
-	router := gin.Default()
-
-	// Grouping routes related to posts for better organization
-	postRoutes := router.Group("/posts")
-	{
-		postRoutes.POST("/", controllers.CreatePostHandler)                 // Route to create a new post
-		postRoutes.PUT("/:postID", controllers.UpdatePostHandler)           // Route to update an existing post
-		postRoutes.GET("/", controllers.GetAllPostsHandlerWithPagination)   // Route to get all posts
-		postRoutes.GET("/:postID", controllers.GetPostDetailsHandler)       // Route to get details of a specific post by ID
-		postRoutes.POST("/:postID/like", controllers.LikePostHandler)       // Route to like a specific post
-		postRoutes.POST("/:postID/comments", controllers.AddCommentHandler) // Route to add a comment to a specific post
+	router := gin.New()
+	if err := router.SetTrustedProxies(TrustedProxies); err != nil {
+		logrus.Fatalln("Invalid trusted proxies: " + err.Error())
 	}
 
+	// Tracing runs first so the request ID entry and access log carry the trace ID
+	router.Use(
+		gin.Recovery(),
+		middleware.Tracing(),
+		middleware.RequestID(),
+		middleware.AccessLog(),
+		middleware.Metrics(),
+	)
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))        // Route to expose Prometheus metrics
+	router.GET("/openapi.json", openapi.Handler(buildOpenAPI())) // Route to get the OpenAPI document
+	router.GET("/docs", openapi.UIHandler)                       // Route to browse the API reference
+
+	// Per-client quotas apply to the API routes, not to the metrics endpoint
+	apiKeys := middleware.StaticAPIKeys(RateLimitAPIKeys...)
+	rateLimit := middleware.RateLimit(middleware.RateLimitConfig{
+		Store:   ratelimit.NewMemoryStore(),
+		Reads:   ratelimit.Limit{Burst: 120, Period: time.Minute},
+		Writes:  ratelimit.Limit{Burst: 30, Period: time.Minute},
+		APIKeys: apiKeys,
+	})
+
+	// Event streams stay open, so the rate limit alone does not bound how many a client holds
+	// Both route prefixes share the cap
+	streamLimit := middleware.StreamLimit(MaxStreamsPerClient, apiKeys)
+
+	// Each API version registers its own handlers, so a new version can change response shapes
+	// without affecting clients of the previous one
+	registerV1Routes(router.Group("/v1", rateLimit, middleware.Identity()), streamLimit)
+
+	// Deprecated aliases of /v1 without the version prefix
+	registerV1Routes(router.Group("/", rateLimit, middleware.Identity(), middleware.Deprecated(unversionedDeprecatedAt, unversionedSunsetAt, "/v1")), streamLimit)
+
 	return router
 }
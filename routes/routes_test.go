@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersionedAndDeprecatedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := InitRoutes()
+
+	tests := []struct {
+		name           string
+		path           string
+		wantDeprecated bool
+		wantSuccessor  string
+	}{
+		{"Versioned route", "/v1/posts/", false, ""},
+		{"Unversioned alias", "/posts/", true, "</v1/posts/>; rel=\"successor-version\""},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, testCase.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Deprecation") != ""; got != testCase.wantDeprecated {
+				t.Errorf("Expected Deprecation header present = %v, got = %v", testCase.wantDeprecated, got)
+			}
+			if got := w.Header().Get("Sunset") != ""; got != testCase.wantDeprecated {
+				t.Errorf("Expected Sunset header present = %v, got = %v", testCase.wantDeprecated, got)
+			}
+			if got := w.Header().Get("Link"); got != testCase.wantSuccessor {
+				t.Errorf("Expected Link header '%s', got '%s'", testCase.wantSuccessor, got)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := InitRoutes()
+
+	// Clients connect directly, so a new X-Forwarded-For on every request must not give them a new budget
+	throttled := 0
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/posts/", nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusTooManyRequests {
+			throttled++
+		}
+	}
+
+	if throttled != 20 {
+		t.Errorf("Expected 20 of 50 writes over the budget of 30 to be throttled, got %d", throttled)
+	}
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"mini-social-media-api/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerV1Routes registers the version 1 API routes on the given group
+// streamLimit caps the event streams each client holds open
+func registerV1Routes(api *gin.RouterGroup, streamLimit gin.HandlerFunc) {
+	// Custom method on the posts collection; Gin matches ":batch" as a parameter holding the literal suffix
+	api.POST("/posts:batch", controllers.BatchPostsHandler) // Route to apply many post operations at once
+
+	// Grouping routes related to posts for better organization
+	postRoutes := api.Group("/posts")
+	{
+		postRoutes.POST("/", controllers.CreatePostHandler)                                 // Route to create a new post
+		postRoutes.PUT("/:postID", controllers.UpdatePostHandler)                           // Route to update an existing post
+		postRoutes.PATCH("/:postID", controllers.PatchPostHandler)                          // Route to partially update an existing post
+		postRoutes.PUT("/:postID/visibility", controllers.UpdatePostVisibilityHandler)      // Route to change who can see a post
+		postRoutes.PUT("/:postID/schedule", controllers.SchedulePostHandler)                // Route to choose when an unpublished post is published
+		postRoutes.DELETE("/:postID/schedule", controllers.UnschedulePostHandler)           // Route to turn a scheduled post back into a draft
+		postRoutes.POST("/:postID/publish", controllers.PublishPostHandler)                 // Route to publish a draft or scheduled post now
+		postRoutes.DELETE("/:postID", controllers.DeletePostHandler)                        // Route to delete a post
+		postRoutes.GET("/", controllers.GetAllPostsHandlerWithPagination)                   // Route to get all posts
+		postRoutes.GET("/scheduled", controllers.GetScheduledPostsHandler)                  // Route to get the drafts and scheduled posts of the caller
+		postRoutes.GET("/:postID", controllers.GetPostDetailsHandler)                       // Route to get details of a specific post by ID
+		postRoutes.GET("/:postID/events", streamLimit, controllers.StreamPostEventsHandler) // Route to stream the changes to a post
+		postRoutes.POST("/:postID/like", controllers.LikePostHandler)                       // Route to like a specific post
+		postRoutes.POST("/:postID/comments", controllers.AddCommentHandler)                 // Route to add a comment to a specific post
+		postRoutes.POST("/:postID/repost", controllers.RepostPostHandler)                   // Route to reshare a post
+		postRoutes.POST("/:postID/poll/votes", controllers.VotePollHandler)                 // Route to vote in the poll of a post
+		postRoutes.POST("/:postID/pin", controllers.PinPostHandler)                         // Route to pin a post to the profile of its author
+		postRoutes.DELETE("/:postID/pin", controllers.UnpinPostHandler)                     // Route to unpin a post
+		postRoutes.POST("/:postID/bookmark", controllers.BookmarkPostHandler)               // Route to bookmark a post
+		postRoutes.DELETE("/:postID/bookmark", controllers.UnbookmarkPostHandler)           // Route to remove a bookmark
+		postRoutes.DELETE("/:postID/repost", controllers.UndoRepostHandler)                 // Route to undo a repost
+	}
+
+	api.GET("/search", controllers.SearchPostsHandler) // Route to search posts and comments
+
+	// Grouping routes related to uploaded media
+	mediaRoutes := api.Group("/media")
+	{
+		mediaRoutes.POST("/", controllers.UploadMediaHandler)                        // Route to upload an image to attach to a post
+		mediaRoutes.GET("/:mediaID", controllers.GetMediaHandler)                    // Route to download an image
+		mediaRoutes.GET("/:mediaID/thumbnail", controllers.GetMediaThumbnailHandler) // Route to download the thumbnail of an image
+	}
+
+	// Grouping routes related to users
+	userRoutes := api.Group("/users")
+	{
+		userRoutes.POST("/", controllers.CreateUserHandler)                     // Route to register a new user
+		userRoutes.GET("/:userID", controllers.GetUserHandler)                  // Route to get a user by ID
+		userRoutes.GET("/:userID/mentions", controllers.GetUserMentionsHandler) // Route to get the posts mentioning a user
+		userRoutes.POST("/:userID/follow", controllers.FollowUserHandler)       // Route to follow a user
+		userRoutes.DELETE("/:userID/follow", controllers.UnfollowUserHandler)   // Route to stop following a user
+		userRoutes.POST("/:userID/block", controllers.BlockUserHandler)         // Route to block a user
+		userRoutes.DELETE("/:userID/block", controllers.UnblockUserHandler)     // Route to unblock a user
+		userRoutes.POST("/:userID/mute", controllers.MuteUserHandler)           // Route to mute a user
+		userRoutes.DELETE("/:userID/mute", controllers.UnmuteUserHandler)       // Route to unmute a user
+		userRoutes.GET("/:userID/followers", controllers.GetFollowersHandler)   // Route to get the followers of a user
+		userRoutes.GET("/:userID/following", controllers.GetFollowingHandler)   // Route to get the users a user follows
+		userRoutes.GET("/:userID/pinned", controllers.GetPinnedPostsHandler)    // Route to get the posts pinned by a user
+	}
+
+	api.GET("/timeline/home", controllers.GetHomeTimelineHandler)                          // Route to get the posts of the followed accounts
+	api.GET("/timeline/home/events", streamLimit, controllers.StreamTimelineEventsHandler) // Route to stream the changes to the home timeline
+	api.GET("/me/bookmarks", controllers.GetBookmarksHandler)                              // Route to get the bookmarks of the caller
+
+	// Grouping routes related to the notifications of the caller
+	notificationRoutes := api.Group("/notifications")
+	{
+		notificationRoutes.GET("/", controllers.GetNotificationsHandler)                          // Route to get the notifications of the caller
+		notificationRoutes.POST("/read", controllers.MarkAllNotificationsReadHandler)             // Route to mark every notification as read
+		notificationRoutes.POST("/:notificationID/read", controllers.MarkNotificationReadHandler) // Route to mark a notification as read
+	}
+
+	// Grouping routes related to hashtags
+	tagRoutes := api.Group("/tags")
+	{
+		tagRoutes.GET("/trending", controllers.GetTrendingTagsHandler) // Route to get the fastest rising hashtags
+		tagRoutes.GET("/:tag/posts", controllers.GetPostsByTagHandler) // Route to get the posts using a hashtag
+	}
+}
@@ -0,0 +1,334 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// fieldGap separates the positions of consecutive fields so phrases never match across fields
+const fieldGap = 100
+
+// Document is a unit of search, such as a post with its comments
+type Document struct {
+	ID     int
+	Fields []string // e.g. the post content followed by the text of each comment
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	ID      int
+	Score   float64
+	Snippet string // HTML escaped text of the best matching field, with matches wrapped in <mark>
+}
+
+type indexedDocument struct {
+	fields []string
+	length int // Number of indexed terms
+	terms  map[string][]int
+}
+
+// Index is an in-memory inverted index ranking documents with BM25
+// It is safe for concurrent use
+type Index struct {
+	mu          sync.RWMutex
+	postings    map[string]map[int][]int // Term to document ID to positions
+	docs        map[int]*indexedDocument
+	totalLength int
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[int][]int{},
+		docs:     map[int]*indexedDocument{},
+	}
+}
+
+// Add indexes a document, replacing any previous version with the same ID
+func (ix *Index) Add(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(doc.ID)
+
+	indexed := newIndexedDocument(doc.Fields)
+	for term, positions := range indexed.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[int][]int{}
+		}
+		ix.postings[term][doc.ID] = positions
+	}
+	ix.docs[doc.ID] = indexed
+	ix.totalLength += indexed.length
+}
+
+// newIndexedDocument tokenizes the fields of a document
+func newIndexedDocument(fields []string) *indexedDocument {
+	indexed := &indexedDocument{fields: fields, terms: map[string][]int{}}
+	base := 0
+	for _, field := range fields {
+		tokens := Tokenize(field)
+		for _, token := range tokens {
+			if token.Stop {
+				continue
+			}
+			indexed.terms[token.Term] = append(indexed.terms[token.Term], base+token.Position)
+			indexed.length++
+		}
+		base += len(tokens) + fieldGap
+	}
+	return indexed
+}
+
+// Remove deletes a document from the index
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Rebuild replaces the content of the index with the given documents
+func (ix *Index) Rebuild(docs []Document) {
+	ix.mu.Lock()
+	ix.postings = map[string]map[int][]int{}
+	ix.docs = map[int]*indexedDocument{}
+	ix.totalLength = 0
+	ix.mu.Unlock()
+
+	for _, doc := range docs {
+		ix.Add(doc)
+	}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// remove deletes a document; the caller must hold the write lock
+func (ix *Index) remove(id int) {
+	existing, exists := ix.docs[id]
+	if !exists {
+		return
+	}
+	for term := range existing.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLength -= existing.length
+	delete(ix.docs, id)
+}
+
+// Search returns the documents matching the query, best first
+func (ix *Index) Search(q string) []Hit {
+	return ix.SearchVisible(q, nil)
+}
+
+// SearchVisible is Search for a searcher who may only see some fields, e.g. not the comments of users they blocked
+// visible reports whether the searcher may see a field of a document, by its index in Document.Fields;
+// documents only match, score and get their snippet from the fields the searcher may see
+func (ix *Index) SearchVisible(q string, visible func(id, field int) bool) []Hit {
+	query := ParseQuery(q)
+	if query.IsEmpty() {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	candidates := ix.candidates(query)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	terms := query.allTerms()
+	n := float64(len(ix.docs))
+	avgLength := float64(ix.totalLength) / n
+
+	hits := make([]Hit, 0, len(candidates))
+	for id := range candidates {
+		doc := ix.docs[id]
+		if visible != nil {
+			if doc = visibleDocument(doc, id, visible); !doc.matches(query) {
+				continue
+			}
+		}
+		score := 0.0
+		for _, term := range terms {
+			frequency := float64(len(doc.terms[term]))
+			if frequency == 0 {
+				continue
+			}
+			df := float64(len(ix.postings[term]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * frequency * (k1 + 1) / (frequency + k1*(1-b+b*float64(doc.length)/avgLength))
+		}
+		hits = append(hits, Hit{ID: id, Score: score, Snippet: snippet(doc.fields, terms)})
+	}
+
+	// Best score first, newest document first on ties
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits
+}
+
+// candidates returns the documents containing every phrase of the query, or any term when it has no phrases
+// The caller must hold the read lock
+func (ix *Index) candidates(query Query) map[int]bool {
+	matches := map[int]bool{}
+
+	if len(query.Phrases) == 0 {
+		for _, term := range query.Terms {
+			for id := range ix.postings[term] {
+				matches[id] = true
+			}
+		}
+		return matches
+	}
+
+	for id := range ix.postings[query.Phrases[0].Terms[0]] {
+		matches[id] = true
+	}
+	for id := range matches {
+		if !ix.docs[id].matches(query) {
+			delete(matches, id)
+		}
+	}
+	return matches
+}
+
+// visibleDocument returns the document without the fields the searcher may not see, or the document itself
+func visibleDocument(doc *indexedDocument, id int, visible func(id, field int) bool) *indexedDocument {
+	fields := make([]string, 0, len(doc.fields))
+	for i, field := range doc.fields {
+		if visible(id, i) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == len(doc.fields) {
+		return doc
+	}
+	return newIndexedDocument(fields)
+}
+
+// matches reports whether the document contains every phrase of the query, or any term when it has no phrases
+func (doc *indexedDocument) matches(query Query) bool {
+	if len(query.Phrases) == 0 {
+		for _, term := range query.Terms {
+			if len(doc.terms[term]) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	for _, phrase := range query.Phrases {
+		if !doc.containsPhrase(phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPhrase reports whether the phrase terms appear at their relative offsets in the document
+func (doc *indexedDocument) containsPhrase(phrase Phrase) bool {
+	for _, start := range doc.terms[phrase.Terms[0]] {
+		matched := true
+		for i := 1; i < len(phrase.Terms) && matched; i++ {
+			matched = containsInt(doc.terms[phrase.Terms[i]], start+phrase.Offsets[i])
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt reports whether the sorted positions contain p
+func containsInt(positions []int, p int) bool {
+	i := sort.SearchInts(positions, p)
+	return i < len(positions) && positions[i] == p
+}
+
+// Snippet windows, in bytes around the first highlighted match
+const (
+	snippetBefore = 60
+	snippetAfter  = 140
+)
+
+// snippet highlights the query terms in the field with the most matches
+func snippet(fields []string, terms []string) string {
+	wanted := map[string]bool{}
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	bestField, bestTokens, bestCount := "", []Token(nil), -1
+	for _, field := range fields {
+		var matched []Token
+		for _, token := range Tokenize(field) {
+			if !token.Stop && wanted[token.Term] {
+				matched = append(matched, token)
+			}
+		}
+		if len(matched) > bestCount {
+			bestField, bestTokens, bestCount = field, matched, len(matched)
+		}
+	}
+	if bestCount <= 0 {
+		return ""
+	}
+
+	// Window around the first match, aligned on spaces
+	start, end := 0, len(bestField)
+	if first := bestTokens[0].Start; first > snippetBefore {
+		start = strings.LastIndexByte(bestField[:first-snippetBefore], ' ') + 1
+	}
+	if start+snippetBefore+snippetAfter < end {
+		end = start + snippetBefore + snippetAfter
+		if space := strings.LastIndexByte(bestField[:end], ' '); space > bestTokens[0].End {
+			end = space
+		}
+		for end < len(bestField) && !utf8.RuneStart(bestField[end]) {
+			end++ // Never cut a character in half
+		}
+	}
+
+	var out strings.Builder
+	if start > 0 {
+		out.WriteString("…")
+	}
+	cursor := start
+	for _, token := range bestTokens {
+		if token.Start < cursor || token.End > end {
+			continue
+		}
+		out.WriteString(html.EscapeString(bestField[cursor:token.Start]))
+		out.WriteString("<mark>")
+		out.WriteString(html.EscapeString(bestField[token.Start:token.End]))
+		out.WriteString("</mark>")
+		cursor = token.End
+	}
+	out.WriteString(html.EscapeString(bestField[cursor:end]))
+	if end < len(bestField) {
+		out.WriteString("…")
+	}
+	return out.String()
+}
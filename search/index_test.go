@@ -0,0 +1,141 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestIndex() *Index {
+	ix := NewIndex()
+	ix.Rebuild([]Document{
+		{ID: 1, Fields: []string{"Running in the park this morning", "Great run!"}},
+		{ID: 2, Fields: []string{"The state of the art in search engines"}},
+		{ID: 3, Fields: []string{"Art exhibition downtown", "state fair next week"}},
+		{ID: 4, Fields: []string{"Cooking pasta tonight"}},
+		{ID: 5, Fields: []string{"<script>alert(1)</script> pasta pasta pasta"}},
+	})
+	return ix
+}
+
+func hitIDs(hits []Hit) []int {
+	ids := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := newTestIndex()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []int
+	}{
+		{"Stemmed term", "runs", []int{1}},
+		{"Term in comment", "fair", []int{3}},
+		{"Term frequency ranks higher", "pasta", []int{5, 4}},
+		{"Any term matches", "pasta exhibition", []int{5, 3, 4}},
+		{"Phrase with stop words", `"state of the art"`, []int{2}},
+		{"Phrase does not cross fields", `"downtown state"`, nil},
+		{"Phrase and term", `"search engines" art`, []int{2}},
+		{"Only stop words", "the of and", nil},
+		{"Case insensitive", "COOKING", []int{4}},
+		{"No match", "bicycle", nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := hitIDs(ix.Search(testCase.query))
+			if len(got) == 0 && len(testCase.wantIDs) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, testCase.wantIDs) {
+				t.Errorf("Expected hits %v, got %v", testCase.wantIDs, got)
+			}
+		})
+	}
+}
+
+func TestIndexSearchVisible(t *testing.T) {
+	ix := newTestIndex()
+	hideComments := func(id, field int) bool { return field == 0 }
+
+	tests := []struct {
+		name        string
+		query       string
+		wantIDs     []int
+		wantSnippet string
+	}{
+		{"Term only in a hidden field", "fair", nil, ""},
+		{"Phrase only in a hidden field", `"state fair"`, nil, ""},
+		{"Snippet from a visible field", "run", []int{1}, "<mark>Running</mark> in the park this morning"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			hits := ix.SearchVisible(testCase.query, hideComments)
+			if got := hitIDs(hits); len(got)+len(testCase.wantIDs) > 0 && !reflect.DeepEqual(got, testCase.wantIDs) {
+				t.Fatalf("Expected hits %v, got %v", testCase.wantIDs, got)
+			}
+			if len(hits) > 0 && hits[0].Snippet != testCase.wantSnippet {
+				t.Errorf("Expected snippet %q, got %q", testCase.wantSnippet, hits[0].Snippet)
+			}
+		})
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	ix := newTestIndex()
+
+	ix.Add(Document{ID: 4, Fields: []string{"Baking bread tonight"}})
+	if got := hitIDs(ix.Search("pasta")); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("Expected replaced document to no longer match, got %v", got)
+	}
+	if got := hitIDs(ix.Search("bread")); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("Expected replaced document to match its new content, got %v", got)
+	}
+
+	ix.Remove(5)
+	if got := ix.Search("pasta"); len(got) != 0 {
+		t.Errorf("Expected removed document to no longer match, got %v", hitIDs(got))
+	}
+	if ix.Len() != 4 {
+		t.Errorf("Expected 4 documents, got %d", ix.Len())
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	ix := newTestIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Highlights stemmed matches", "run", "<mark>Running</mark> in the park this morning"},
+		{"Escapes HTML", "pasta", "&lt;script&gt;alert(1)&lt;/script&gt; <mark>pasta</mark> <mark>pasta</mark> <mark>pasta</mark>"},
+		{"Uses the best matching field", "fair", "state <mark>fair</mark> next week"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			hits := ix.Search(testCase.query)
+			if len(hits) == 0 {
+				t.Fatalf("Expected hits for '%s'", testCase.query)
+			}
+			if hits[0].Snippet != testCase.want {
+				t.Errorf("Expected snippet '%s', got '%s'", testCase.want, hits[0].Snippet)
+			}
+		})
+	}
+
+	long := NewIndex()
+	long.Add(Document{ID: 1, Fields: []string{strings.Repeat("filler ", 30) + "needle " + strings.Repeat("filler ", 40)}})
+	got := long.Search("needle")[0].Snippet
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "<mark>needle</mark>") {
+		t.Errorf("Expected a trimmed snippet around the match, got '%s'", got)
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import "strings"
+
+// Query is a parsed search query
+// Documents must contain every phrase and, when the query has no phrases, at least one term
+type Query struct {
+	Terms   []string
+	Phrases []Phrase
+}
+
+// Phrase is a sequence of terms that must appear in order in the same field
+type Phrase struct {
+	Terms   []string
+	Offsets []int // Position of each term relative to the first one, accounting for stop words
+}
+
+// ParseQuery parses a query of words and "quoted phrases"
+// Stop words are ignored, except that they keep the spacing of phrase terms
+func ParseQuery(q string) Query {
+	var query Query
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		tokens := Tokenize(part)
+		inPhrase := i%2 == 1 && i < len(parts)-1 // An unterminated quote is treated as plain words
+
+		if !inPhrase {
+			for _, token := range tokens {
+				if !token.Stop {
+					query.Terms = append(query.Terms, token.Term)
+				}
+			}
+			continue
+		}
+
+		var phrase Phrase
+		first := -1
+		for _, token := range tokens {
+			if token.Stop {
+				continue
+			}
+			if first < 0 {
+				first = token.Position
+			}
+			phrase.Terms = append(phrase.Terms, token.Term)
+			phrase.Offsets = append(phrase.Offsets, token.Position-first)
+		}
+		switch len(phrase.Terms) {
+		case 0:
+		case 1:
+			query.Terms = append(query.Terms, phrase.Terms[0]) // A single word phrase is a plain term
+		default:
+			query.Phrases = append(query.Phrases, phrase)
+		}
+	}
+	return query
+}
+
+// IsEmpty reports whether the query has nothing to search for
+func (q Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
+
+// allTerms returns every term of the query, including phrase terms
+func (q Query) allTerms() []string {
+	terms := append([]string(nil), q.Terms...)
+	for _, phrase := range q.Phrases {
+		terms = append(terms, phrase.Terms...)
+	}
+	return terms
+}
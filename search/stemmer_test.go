@@ -0,0 +1,29 @@
+package search
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	// Expected stems from the reference Porter stemmer vocabulary
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"caresses", "caress"}, {"ponies", "poni"}, {"ties", "ti"}, {"caress", "caress"}, {"cats", "cat"},
+		{"feed", "feed"}, {"agreed", "agre"}, {"plastered", "plaster"}, {"bled", "bled"}, {"motoring", "motor"},
+		{"sing", "sing"}, {"conflated", "conflat"}, {"troubled", "troubl"}, {"sized", "size"}, {"hopping", "hop"},
+		{"tanned", "tan"}, {"falling", "fall"}, {"hissing", "hiss"}, {"fizzed", "fizz"}, {"failing", "fail"},
+		{"filing", "file"}, {"happy", "happi"}, {"sky", "sky"}, {"relational", "relat"}, {"conditional", "condit"},
+		{"rational", "ration"}, {"valenci", "valenc"}, {"digitizer", "digit"}, {"conformabli", "conform"},
+		{"generalization", "gener"}, {"running", "run"}, {"connection", "connect"}, {"connected", "connect"},
+		{"hopefulness", "hope"}, {"adoption", "adopt"}, {"controlling", "control"}, {"rolling", "roll"},
+		{"go", "go"}, {"café", "café"}, {"2024", "2024"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.word, func(t *testing.T) {
+			if got := Stem(testCase.word); got != testCase.want {
+				t.Errorf("Expected stem of '%s' to be '%s', got '%s'", testCase.word, testCase.want, got)
+			}
+		})
+	}
+}
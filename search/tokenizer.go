@@ -0,0 +1,77 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a word found in a text
+type Token struct {
+	Term     string // Normalized term: lower case and stemmed
+	Position int    // Index of the word in the text, counting stop words
+	Start    int    // Byte offset of the word in the text
+	End      int    // Byte offset just after the word
+	Stop     bool   // Stop words keep their position but are not indexed
+}
+
+// stopWords are frequent English words that carry little meaning for search
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"no": true, "not": true, "of": true, "on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "were": true, "will": true, "with": true, "i": true, "me": true, "my": true, "we": true,
+	"our": true, "you": true, "your": true, "he": true, "she": true, "him": true, "her": true, "so": true,
+	"do": true, "does": true, "did": true, "have": true, "has": true, "had": true, "from": true,
+}
+
+// IsStopWord reports whether the lower case word is a stop word
+func IsStopWord(word string) bool {
+	return stopWords[word]
+}
+
+// Tokenize splits text into words made of letters and digits, normalizing each into a term
+// Apostrophes inside words are dropped so "don't" and "dont" match
+func Tokenize(text string) []Token {
+	var tokens []Token
+	position := 0
+	start := -1
+	var word strings.Builder
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		lower := word.String()
+		tokens = append(tokens, Token{
+			Term:     Stem(lower),
+			Position: position,
+			Start:    start,
+			End:      end,
+			Stop:     IsStopWord(lower),
+		})
+		position++
+		start = -1
+		word.Reset()
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if start < 0 {
+				start = i
+			}
+			word.WriteRune(unicode.ToLower(r))
+		case (r == '\'' || r == '’') && start >= 0:
+			// Skip apostrophes inside a word
+		default:
+			flush(i)
+		}
+		i += size
+	}
+	flush(len(text))
+
+	return tokens
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxBatchSize is the maximum number of operations accepted in a single batch
+const MaxBatchSize = 100
+
+// BatchOp identifies the kind of a batch operation
+type BatchOp string
+
+// Supported batch operations
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+	BatchLike   BatchOp = "like"
+)
+
+// Errors returned for a batch as a whole
+var (
+	ErrEmptyBatch       = errors.New("batch must contain at least one operation")
+	ErrBatchTooLarge    = fmt.Errorf("batch exceeds maximum size of %d operations", MaxBatchSize)
+	ErrBatchRolledBack  = errors.New("atomic batch rolled back because an operation failed")
+	ErrUnknownOperation = errors.New("unknown batch operation")
+)
+
+// BatchOperation is a single operation of a batch
+// PostID is used by update, delete and like; Content by create and update
+type BatchOperation struct {
+	Op      BatchOp
+	PostID  int
+	Content string
+}
+
+// BatchResult is the outcome of a single batch operation
+type BatchResult struct {
+	Op         BatchOp
+	Post       models.Post // The post after the operation, or the deleted post
+	Err        error
+	RolledBack bool // Set when the operation was undone or skipped because an atomic batch failed
+}
+
+// ExecuteBatch applies the operations in order while holding the store lock, so no other request observes a partial batch.
+// When atomic is true, the first failing operation stops the batch and every change is rolled back.
+// Otherwise each operation succeeds or fails on its own.
+// Returns a result per operation, and ErrBatchRolledBack if an atomic batch was rolled back.
+func ExecuteBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "services.ExecuteBatch", trace.WithAttributes(
+		attribute.Int("batch.size", len(operations)),
+		attribute.Bool("batch.atomic", atomic),
+	))
+	defer span.End()
+
+	if len(operations) == 0 {
+		return nil, recordError(span, ErrEmptyBatch)
+	}
+	if len(operations) > MaxBatchSize {
+		return nil, recordError(span, ErrBatchTooLarge)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	var snapshot storeSnapshot
+	if atomic {
+		snapshot = takeSnapshot()
+	}
+
+	results := make([]BatchResult, len(operations))
+	changes := make([]change, 0, len(operations))
+	failed := false
+	for i, operation := range operations {
+		results[i].Op = operation.Op
+		if failed && atomic {
+			results[i].RolledBack = true // Skipped after the failure
+			continue
+		}
+
+		post, kind, err := applyBatchOperation(ctx, operation)
+		results[i].Post, results[i].Err = post, err
+		if err != nil {
+			failed = true
+			continue
+		}
+		changes = append(changes, change{kind, post})
+	}
+
+	if failed && atomic {
+		snapshot.restore()
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Post = models.Post{}
+				results[i].RolledBack = true
+			}
+		}
+		return results, recordError(span, ErrBatchRolledBack)
+	}
+
+	commitChanges(ctx, changes...)
+	return results, nil
+}
+
+// applyBatchOperation validates and applies a single operation
+// The caller must hold the post mutex
+func applyBatchOperation(ctx context.Context, operation BatchOperation) (models.Post, changeKind, error) {
+	switch operation.Op {
+	case BatchCreate:
+		if err := validatePostContent("create_post", operation.Content, identity.FromContext(ctx)); err != nil {
+			return models.Post{}, postCreated, err
+		}
+		// Posts are written by the caller, as when created on their own
+		authorID := identity.FromContext(ctx)
+		if authorID != 0 {
+			if _, err := GetUserByID(ctx, authorID); err != nil {
+				return models.Post{}, postCreated, ErrUnknownCaller
+			}
+		}
+		return createPostLocked(ctx, operation.Content, models.Post{AuthorID: authorID}), postCreated, nil
+	case BatchUpdate:
+		if err := validatePostContent("update_post", operation.Content, identity.FromContext(ctx)); err != nil {
+			return models.Post{}, postUpdated, err
+		}
+		post, err := updatePostLocked(ctx, operation.PostID, operation.Content)
+		return post, postUpdated, err
+	case BatchDelete:
+		post, err := deletePostLocked(ctx, operation.PostID)
+		return post, postDeleted, err
+	case BatchLike:
+		post, err := likePostLocked(ctx, operation.PostID)
+		return newViewerFilter(ctx).present(post), postLiked, err
+	default:
+		return models.Post{}, 0, fmt.Errorf("%w: %s", ErrUnknownOperation, operation.Op)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bookmark is a post saved by a user, ordered by seq
+type bookmark struct {
+	postID int
+	seq    int
+}
+
+// Bookmarks, guarded by the post mutex like the posts they reference
+var bookmarksOf = map[int][]bookmark{}    // User ID to their bookmarks, oldest first
+var bookmarkedBy = map[int]map[int]bool{} // Post ID to the users who bookmarked it, to clean up deleted posts
+var bookmarkSeq = 0                       // Last assigned bookmark sequence number
+
+// BookmarkPost privately saves a post for the calling user; bookmarking a bookmarked post is not an error.
+// Returns the post, or an error if the caller is unknown, cannot see the post or the post is not published.
+func BookmarkPost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.BookmarkPost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return models.Post{}, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if !posts[i].Status.Published() {
+		return models.Post{}, recordError(span, errNotPublished("bookmark_post"))
+	}
+
+	if !bookmarkedBy[id][userID] {
+		bookmarkSeq++
+		bookmarksOf[userID] = append(bookmarksOf[userID], bookmark{postID: id, seq: bookmarkSeq})
+		if bookmarkedBy[id] == nil {
+			bookmarkedBy[id] = map[int]bool{}
+		}
+		bookmarkedBy[id][userID] = true
+	}
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// UnbookmarkPost removes a post from the bookmarks of the calling user; removing a missing bookmark is not an error.
+// Returns the post, or ErrPostNotFound if the caller cannot see it.
+func UnbookmarkPost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.UnbookmarkPost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+
+	removeBookmarkLocked(identity.FromContext(ctx), id)
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// GetBookmarks retrieves the bookmarks of the calling user, most recently bookmarked first.
+// The cursor is empty for the first page, then the next cursor returned with the previous page.
+// Bookmarked posts the caller can no longer see are skipped.
+// Returns the page of posts, the cursor of the next page, empty after the last page, or an error
+// if the caller is unknown or the cursor is invalid.
+func GetBookmarks(ctx context.Context, cursor string, limit int) ([]models.Post, string, error) {
+	ctx, span := tracer.Start(ctx, "services.GetBookmarks", trace.WithAttributes(attribute.Int("page.limit", limit)))
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, "", recordError(span, ErrUnknownCaller)
+	}
+	before, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", recordError(span, err)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := newViewerFilter(ctx)
+	saved := bookmarksOf[userID]
+	page := []models.Post{}
+	next, last := "", 0
+	for i := len(saved) - 1; i >= 0; i-- {
+		if before > 0 && saved[i].seq >= before {
+			continue
+		}
+		post, ok := filter.lookup.find(saved[i].postID)
+		if !ok || !filter.canSee(post) {
+			continue
+		}
+		if len(page) == limit {
+			// Another visible bookmark follows, so the next page starts after the last one returned
+			next = encodeCursor(last)
+			break
+		}
+		page = append(page, filter.present(post))
+		last = saved[i].seq
+	}
+	return page, next, nil
+}
+
+// removeBookmarkLocked removes the bookmark of a post by a user, if any
+// The caller must hold the post mutex
+func removeBookmarkLocked(userID, postID int) {
+	if !bookmarkedBy[postID][userID] {
+		return
+	}
+	delete(bookmarkedBy[postID], userID)
+	if len(bookmarkedBy[postID]) == 0 {
+		delete(bookmarkedBy, postID)
+	}
+	kept := make([]bookmark, 0, len(bookmarksOf[userID]))
+	for _, saved := range bookmarksOf[userID] {
+		if saved.postID != postID {
+			kept = append(kept, saved)
+		}
+	}
+	bookmarksOf[userID] = kept
+}
+
+// forgetBookmarksLocked removes a deleted post from the bookmarks of every user
+// The caller must hold the post mutex
+func forgetBookmarksLocked(post models.Post) {
+	for userID := range bookmarkedBy[post.ID] {
+		removeBookmarkLocked(userID, post.ID)
+	}
+}
+
+// encodeCursor returns an opaque cursor for the bookmarks older than the given sequence number
+func encodeCursor(before int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(before)))
+}
+
+// decodeCursor returns the sequence number of a cursor, 0 for the empty cursor of the first page
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	before, err := strconv.Atoi(string(raw))
+	if err != nil || before <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return before, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/metrics"
+	"mini-social-media-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// changeKind identifies a committed change to the store
+type changeKind int
+
+const (
+	postCreated changeKind = iota
+	postUpdated
+	postDeleted
+	postLiked
+	commentAdded
+	pollVoted
+)
+
+// change describes a committed change and the post as it is after the change
+type change struct {
+	kind changeKind
+	post models.Post
+}
+
+// RebuildIndexes rebuilds the search and tag indexes and the home timelines from the posts in the store, e.g. at startup.
+func RebuildIndexes(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "services.RebuildIndexes")
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	rebuildSearchIndexLocked(ctx)
+	rebuildTagIndexLocked(ctx)
+	rebuildTimelinesLocked(ctx)
+	span.SetAttributes(attribute.Int("post.count", len(posts)))
+}
+
+// commitChanges runs the side effects of changes that are now part of the store
+// Mutations inside a batch only commit once the whole batch succeeded, so rolled back work has no side effects
+// The caller must hold the post mutex so that changes are applied in commit order
+func commitChanges(ctx context.Context, changes ...change) {
+	for _, c := range changes {
+		// Unpublished posts have no side effects until they are published
+		if !c.post.Status.Published() && c.kind != postDeleted {
+			continue
+		}
+
+		switch c.kind {
+		case postCreated:
+			metrics.PostsCreatedTotal.Inc()
+		case postLiked:
+			metrics.LikesTotal.Inc()
+		case commentAdded:
+			metrics.CommentsTotal.Inc()
+		case pollVoted:
+			metrics.PollVotesTotal.Inc()
+		}
+
+		// Keep the search and tag indexes in sync with the content and comments of the post
+		// Only public posts count towards tags; searches filter hidden posts when they run
+		switch c.kind {
+		case postCreated, postUpdated:
+			searchIndex.Add(searchDocument(c.post))
+			if c.post.Visibility == models.VisibilityPublic {
+				tagIndex.Set(c.post.ID, c.post.Hashtags, clock())
+				expireTagsAt(c.post)
+			} else {
+				tagIndex.Remove(c.post.ID)
+			}
+		case commentAdded:
+			searchIndex.Add(searchDocument(c.post))
+		case postDeleted:
+			searchIndex.Remove(c.post.ID)
+			tagIndex.Remove(c.post.ID)
+		}
+
+		// Fetch previews of the links of new content in the background
+		if c.kind == postCreated || c.kind == postUpdated {
+			enqueuePreviews(ctx, c.post)
+		}
+
+		// Attachments belong to a single post, so they go away with it
+		if c.kind == postDeleted {
+			releaseAttachments(ctx, c.post)
+		}
+
+		// Deliver new posts to the home timelines of the followers of their author
+		switch c.kind {
+		case postCreated:
+			fanOutLocked(c.post)
+		case postDeleted:
+			forgetPostLocked(c.post)
+		}
+
+		// Tell the author about activity on the post and users about mentions of them
+		notifyChangeLocked(ctx, c)
+
+		// Pins and bookmarks do not outlive the post
+		if c.kind == postDeleted {
+			forgetPinsLocked(c.post)
+			forgetBookmarksLocked(c.post)
+		}
+
+		// Stream the change to the clients watching the post or the timelines of its author
+		publishChangeLocked(c)
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"mini-social-media-api/metrics"
+)
+
+// Errors returned by the services, so controllers can map them to status codes
+var (
+	ErrPostNotFound         = errors.New("post not found")
+	ErrInvalidPatch         = errors.New("invalid patch")
+	ErrPatchConflict        = errors.New("patch test failed")
+	ErrFieldNotPatchable    = errors.New("field cannot be modified")
+	ErrEmptySearchQuery     = errors.New("search query cannot be empty")
+	ErrInvalidTag           = errors.New("invalid hashtag")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUsernameTaken        = errors.New("username is already taken")
+	ErrMediaNotFound        = errors.New("media not found")
+	ErrUnsupportedMedia     = errors.New("unsupported media type")
+	ErrMediaTooLarge        = errors.New("media is too large")
+	ErrAttachmentInUse      = errors.New("media is already attached to a post")
+	ErrMediaUnavailable     = errors.New("media storage is not configured")
+	ErrUnknownCaller        = errors.New("calling user does not exist")
+	ErrNotAuthor            = errors.New("only the author of the post can do this")
+	ErrAlreadyReposted      = errors.New("post is already reposted")
+	ErrPollNotFound         = errors.New("post has no poll")
+	ErrPollClosed           = errors.New("poll is closed")
+	ErrAlreadyVoted         = errors.New("already voted in this poll")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// ValidationError is returned when input fails service-level validation
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// rejectValidation records a validation failure for the given operation and returns it as a ValidationError
+func rejectValidation(operation string, err error) error {
+	metrics.ValidationRejectionsTotal.WithLabelValues(operation).Inc()
+	return &ValidationError{Err: err}
+}
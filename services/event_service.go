@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mini-social-media-api/events"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Types of the events streamed to clients
+const (
+	EventPostCreated   = "post.created"
+	EventPostUpdated   = "post.updated"
+	EventPostLiked     = "post.liked"
+	EventPostCommented = "post.commented"
+	EventPostDeleted   = "post.deleted"
+	// EventResync tells a client resuming a stream that it missed events, so it should reload what it shows
+	EventResync = "resync"
+)
+
+// EventReplaySize is how many of the latest events are kept for clients resuming a stream with Last-Event-ID
+const EventReplaySize = 1000
+
+// eventBufferSize is how many events a stream may fall behind before it is closed, so a slow client never delays writes
+const eventBufferSize = 64
+
+// eventHub delivers the changes committed to the store to the open streams
+var eventHub = events.NewHub(EventReplaySize, eventBufferSize)
+
+// PostEvent is a change to a post, with the post as the subscriber sees it
+// Deleted posts only carry their ID, and resync events carry no post
+type PostEvent struct {
+	ID   uint64
+	Type string
+	Post models.Post
+}
+
+// postSnapshot is the payload of a published event: the post as it is right after the change, with the post
+// it reposts or quotes, so subscribers present it without the post mutex
+type postSnapshot struct {
+	kind     changeKind
+	post     models.Post
+	original *models.Post
+}
+
+// StreamPost streams the changes to a post the calling user can see, starting after lastEventID when it is not 0.
+// The stream ends when ctx is done, once the post is deleted, or when the subscriber falls too far behind.
+// Returns the stream, or ErrPostNotFound if the post does not exist or is hidden from the calling user.
+func StreamPost(ctx context.Context, postID int, lastEventID uint64) (<-chan PostEvent, error) {
+	ctx, span := tracer.Start(ctx, "services.StreamPost", trace.WithAttributes(attribute.Int("post.id", postID)))
+	defer span.End()
+
+	viewerID := identity.FromContext(ctx)
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	if i := findPostIndex(ctx, postID); i < 0 || !filterFor(viewerID).canSee(posts[i]) {
+		return nil, recordError(span, ErrPostNotFound)
+	}
+
+	// Subscribing under the post mutex means no change committed after the check is missed
+	subscription, replay, complete := eventHub.Subscribe([]string{postKey(postID)}, lastEventID)
+	return streamEvents(ctx, subscription, replay, complete, viewerID, postID, func(filter viewerFilter, post models.Post) bool {
+		return filter.canSee(post)
+	}), nil
+}
+
+// StreamTimeline streams the changes to the posts of the home timeline of the calling user, starting after
+// lastEventID when it is not 0. The accounts followed when the stream starts are streamed; the stream stops
+// showing accounts the user unfollows, but a new stream is needed to see newly followed accounts.
+// Returns the stream, or ErrUnknownCaller.
+func StreamTimeline(ctx context.Context, lastEventID uint64) (<-chan PostEvent, error) {
+	ctx, span := tracer.Start(ctx, "services.StreamTimeline")
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Reading the followed accounts and subscribing under the post mutex means no post committed
+	// after the accounts are read is missed, as in StreamPost
+	keys := []string{authorKey(userID)}
+	for _, followeeID := range followeeIDs(userID) {
+		keys = append(keys, authorKey(followeeID))
+	}
+	span.SetAttributes(attribute.Int("timeline.authors", len(keys)))
+
+	subscription, replay, complete := eventHub.Subscribe(keys, lastEventID)
+	return streamEvents(ctx, subscription, replay, complete, userID, 0, func(filter viewerFilter, post models.Post) bool {
+		return (post.AuthorID == userID || filter.following[post.AuthorID]) && filter.inTimeline(post)
+	}), nil
+}
+
+// publishChangeLocked publishes a committed change to the streams of the post and of its author
+// The caller must hold the post mutex so that events are numbered in commit order
+func publishChangeLocked(c change) {
+	var eventType string
+	switch c.kind {
+	case postCreated:
+		eventType = EventPostCreated
+	case postUpdated, pollVoted:
+		eventType = EventPostUpdated
+	case postLiked:
+		eventType = EventPostLiked
+	case commentAdded:
+		eventType = EventPostCommented
+	case postDeleted:
+		eventType = EventPostDeleted
+	default:
+		return
+	}
+
+	keys := []string{postKey(c.post.ID)}
+	if c.post.AuthorID != 0 {
+		keys = append(keys, authorKey(c.post.AuthorID))
+	}
+	snapshot := postSnapshot{kind: c.kind, post: c.post}
+	if id := originalID(c.post); id != 0 && c.kind != postDeleted {
+		if original, ok := (&postLookup{}).find(id); ok {
+			snapshot.original = &original
+		}
+	}
+	eventHub.Publish(eventType, keys, snapshot)
+}
+
+// streamEvents forwards the events of a subscription that the viewer may see, as they see them, until ctx is done
+// or the post streamedPostID, when it is not 0, is deleted
+// Visibility is checked when each event is delivered, so blocks and visibility changes apply to open streams;
+// the filter of the viewer is only rebuilt once follows, blocks or mutes changed
+func streamEvents(ctx context.Context, subscription *events.Subscription, replay []events.Event, complete bool, viewerID, streamedPostID int, show func(viewerFilter, models.Post) bool) <-chan PostEvent {
+	stream := make(chan PostEvent)
+
+	go func() {
+		defer close(stream)
+		defer subscription.Close()
+
+		send := func(event PostEvent) bool {
+			select {
+			case stream <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var filter viewerFilter
+		var filterVersion uint64
+		built := false
+
+		// deliver sends an event if the viewer may see it and reports whether the stream goes on
+		deliver := func(event events.Event) bool {
+			if version := socialGraphVersion.Load(); !built || version != filterVersion {
+				filter, filterVersion, built = filterFor(viewerID), version, true
+			}
+			snapshot := event.Payload.(postSnapshot)
+			postEvent, ok := presentEvent(event, snapshot, filter, show)
+			if ok && !send(postEvent) {
+				return false
+			}
+			// A stream of a single post ends with the post
+			return !(snapshot.kind == postDeleted && snapshot.post.ID == streamedPostID)
+		}
+
+		// Missed events are not replayed; the client reloads instead and resumes after the latest event
+		if !complete && !send(PostEvent{ID: subscription.From(), Type: EventResync}) {
+			return
+		}
+		for _, event := range replay {
+			if !deliver(event) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-subscription.Events():
+				if !ok || !deliver(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return stream
+}
+
+// presentEvent returns the event as the viewer of the filter sees it, or false if the viewer may not see the post
+// Only the snapshot is read, so the post mutex is not needed
+func presentEvent(event events.Event, snapshot postSnapshot, filter viewerFilter, show func(viewerFilter, models.Post) bool) (PostEvent, bool) {
+	filter.now = clock()
+	filter.lookup = &postLookup{detached: map[int]models.Post{}}
+	if snapshot.original != nil {
+		filter.lookup.detached[snapshot.original.ID] = *snapshot.original
+	}
+
+	if snapshot.kind == postDeleted {
+		// Tell the viewer about the deletion of a post they saw, even when it was deleted because it expired
+		filter.now = time.Time{}
+		if !show(filter, snapshot.post) {
+			return PostEvent{}, false
+		}
+		return PostEvent{ID: event.ID, Type: event.Type, Post: models.Post{ID: snapshot.post.ID}}, true
+	}
+
+	if !show(filter, snapshot.post) {
+		return PostEvent{}, false
+	}
+	return PostEvent{ID: event.ID, Type: event.Type, Post: filter.present(snapshot.post)}, true
+}
+
+func postKey(postID int) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+func authorKey(userID int) string {
+	return fmt.Sprintf("author:%d", userID)
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Limits of the lifetime of expiring posts
+const (
+	MinPostTTL = time.Minute
+	MaxPostTTL = 7 * 24 * time.Hour
+)
+
+// SweeperInterval is how often expired posts are removed from the store; reads hide them as soon as they expire
+const SweeperInterval = 30 * time.Second
+
+// SweepExpiredPosts deletes the posts that have expired, along with their comments and attachments.
+// Returns the deleted posts.
+func SweepExpiredPosts(ctx context.Context) []models.Post {
+	ctx, span := tracer.Start(ctx, "services.SweepExpiredPosts")
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Collect the IDs first, since removing a post shifts the ones after it
+	current := clock()
+	var ids []int
+	for _, post := range listPosts(ctx) {
+		if expired(post, current) {
+			ids = append(ids, post.ID)
+		}
+	}
+
+	swept := make([]models.Post, 0, len(ids))
+	for _, id := range ids {
+		post := removePost(ctx, findPostIndex(ctx, id))
+		releaseShareLocked(ctx, post)
+		commitChanges(ctx, change{postDeleted, post})
+		swept = append(swept, post)
+	}
+	span.SetAttributes(attribute.Int("post.count", len(swept)))
+	return swept
+}
+
+// RunSweeper deletes expired posts every interval until ctx is done.
+func RunSweeper(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		for _, post := range SweepExpiredPosts(ctx) {
+			logging.FromContext(ctx).WithField(logging.FieldPostID, post.ID).Infoln("Deleted expired post")
+		}
+	})
+}
+
+// expired reports whether a post has expired at the given time
+func expired(post models.Post, at time.Time) bool {
+	return post.ExpiresAt != nil && !post.ExpiresAt.After(at)
+}
+
+// validateTTL checks the lifetime of a new post; only published posts can expire, since the lifetime starts when they are created
+func validateTTL(operation string, ttl time.Duration, status models.PostStatus) error {
+	if ttl == 0 {
+		return nil
+	}
+	if ttl < MinPostTTL || ttl > MaxPostTTL {
+		return rejectValidation(operation, fmt.Errorf("post lifetime must be between %v and %v", MinPostTTL, MaxPostTTL))
+	}
+	if !status.Published() {
+		return rejectValidation(operation, errors.New("drafts and scheduled posts cannot expire"))
+	}
+	return nil
+}
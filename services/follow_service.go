@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mini-social-media-api/models"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The follow graph is stored in both directions; each edge holds the sequence number of the follow, to list newest first
+var followingOf = map[int]map[int]int{} // Follower ID to the users they follow
+var followersOf = map[int]map[int]int{} // User ID to their followers
+var followSeq = 0                       // Increases with every follow
+var followMutex = &sync.RWMutex{}       // Guards the follow graph; may be acquired while holding the post mutex
+
+// FollowUser makes the follower follow another user; following a user twice is not an error.
+// Returns the followed user, ErrUnknownCaller if the follower does not exist, or ErrUserNotFound.
+func FollowUser(ctx context.Context, followerID, followeeID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.FollowUser", trace.WithAttributes(attribute.Int("user.id", followerID), attribute.Int("followee.id", followeeID)))
+	defer span.End()
+
+	if followerID == followeeID {
+		return models.User{}, recordError(span, rejectValidation("follow_user", errors.New("users cannot follow themselves")))
+	}
+
+	// The post mutex keeps the home timeline caches consistent with the follow graph
+	lockPosts()
+	defer postMutex.Unlock()
+
+	followee, err := lookupFollowPair(ctx, followerID, followeeID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	followMutex.Lock()
+	_, already := followingOf[followerID][followeeID]
+	if !already {
+		followSeq++
+		addEdge(followingOf, followerID, followeeID, followSeq)
+		addEdge(followersOf, followeeID, followerID, followSeq)
+	}
+	followMutex.Unlock()
+	socialGraphChanged()
+
+	if !already {
+		backfillTimelineLocked(followerID, followeeID)
+		notifyLocked(followeeID, models.NotificationFollow, 0, followerID)
+	}
+	return followee, nil
+}
+
+// UnfollowUser stops the follower from following another user; unfollowing a user not followed is not an error.
+// Returns the unfollowed user, ErrUnknownCaller if the follower does not exist, or ErrUserNotFound.
+func UnfollowUser(ctx context.Context, followerID, followeeID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.UnfollowUser", trace.WithAttributes(attribute.Int("user.id", followerID), attribute.Int("followee.id", followeeID)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	followee, err := lookupFollowPair(ctx, followerID, followeeID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	followMutex.Lock()
+	delete(followingOf[followerID], followeeID)
+	delete(followersOf[followeeID], followerID)
+	followMutex.Unlock()
+	socialGraphChanged()
+
+	purgeTimelineLocked(followerID, followeeID)
+	return followee, nil
+}
+
+// GetFollowers retrieves the users following a user, most recent follow first.
+// Returns the requested page of users, the total number of followers, or ErrUserNotFound.
+func GetFollowers(ctx context.Context, userID, page, limit int) ([]models.User, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetFollowers", trace.WithAttributes(attribute.Int("user.id", userID)))
+	defer span.End()
+
+	users, total, err := listFollows(ctx, followersOf, userID, page, limit)
+	if err != nil {
+		return nil, 0, recordError(span, err)
+	}
+	return users, total, nil
+}
+
+// GetFollowing retrieves the users a user follows, most recent follow first.
+// Returns the requested page of users, the total number of followed users, or ErrUserNotFound.
+func GetFollowing(ctx context.Context, userID, page, limit int) ([]models.User, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetFollowing", trace.WithAttributes(attribute.Int("user.id", userID)))
+	defer span.End()
+
+	users, total, err := listFollows(ctx, followingOf, userID, page, limit)
+	if err != nil {
+		return nil, 0, recordError(span, err)
+	}
+	return users, total, nil
+}
+
+// lookupFollowPair checks that both users of a follow exist and returns the followed user
+func lookupFollowPair(ctx context.Context, followerID, followeeID int) (models.User, error) {
+	if _, err := GetUserByID(ctx, followerID); err != nil {
+		return models.User{}, ErrUnknownCaller
+	}
+	return GetUserByID(ctx, followeeID)
+}
+
+// listFollows returns a page of the users on the other side of the edges of a user, newest edge first
+func listFollows(ctx context.Context, edges map[int]map[int]int, userID, page, limit int) ([]models.User, int, error) {
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+
+	followMutex.RLock()
+	ids := sortedFollows(edges[userID])
+	followMutex.RUnlock()
+
+	userMutex.RLock()
+	defer userMutex.RUnlock()
+
+	paged := pageOf(ids, page, limit)
+	listed := make([]models.User, 0, len(paged))
+	for _, id := range paged {
+		if i := findUserIndex(id); i >= 0 {
+			listed = append(listed, users[i])
+		}
+	}
+	return listed, len(ids), nil
+}
+
+// sortedFollows returns the user IDs of a set of edges, newest edge first
+// The caller must hold the follow mutex
+func sortedFollows(edges map[int]int) []int {
+	ids := make([]int, 0, len(edges))
+	for id := range edges {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return edges[ids[i]] > edges[ids[j]] })
+	return ids
+}
+
+// followerIDs returns the IDs of the followers of a user, in no particular order
+func followerIDs(userID int) []int {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+
+	ids := make([]int, 0, len(followersOf[userID]))
+	for id := range followersOf[userID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// followeeIDs returns the IDs of the users a user follows, in no particular order
+func followeeIDs(userID int) []int {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+
+	ids := make([]int, 0, len(followingOf[userID]))
+	for id := range followingOf[userID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// addEdge adds an edge to one direction of the follow graph
+// The caller must hold the follow mutex
+func addEdge(edges map[int]map[int]int, from, to int, seq int) {
+	if edges[from] == nil {
+		edges[from] = map[int]int{}
+	}
+	edges[from][to] = seq
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/media"
+	"mini-social-media-api/models"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Limits of media attachments
+const (
+	MaxUploadBytes        = 5 << 20 // Largest accepted upload
+	MaxAttachmentsPerPost = 4
+	MaxAltTextLength      = 1000
+)
+
+// Blobs stores uploaded media and their thumbnails; nil disables uploads
+var Blobs media.BlobStore
+
+// mediaRecord is an uploaded attachment, the user who uploaded it and the post using it, 0 until it is attached
+type mediaRecord struct {
+	attachment models.Attachment
+	uploaderID int
+	postID     int
+}
+
+var mediaItems = map[string]*mediaRecord{} // Uploaded attachments by ID
+var mediaMutex = &sync.Mutex{}             // Guards mediaItems; may be acquired while holding the post mutex
+
+// MediaContent is the blob of an attachment the calling user may see
+type MediaContent struct {
+	Attachment models.Attachment
+	Body       io.ReadCloser // The image or its thumbnail, which the caller must close
+	Public     bool          // Anyone may see the attachment for as long as it exists, so shared caches may keep it
+}
+
+// AttachmentRef references an uploaded attachment when creating a post
+type AttachmentRef struct {
+	ID      string
+	AltText string // Replaces the alt text given at upload when not empty
+}
+
+// PostOptions holds the optional parts of a new post
+type PostOptions struct {
+	AuthorID    int               // 0 for an anonymous post
+	Visibility  models.Visibility // Defaults to public
+	QuoteOf     int               // Post quoted by the new post, 0 for none
+	Status      models.PostStatus // Draft or scheduled to publish later; defaults to published, or scheduled with PublishAt
+	PublishAt   *time.Time        // When to publish a scheduled post
+	TTL         time.Duration     // How long the post lives before it disappears, 0 for posts that do not expire
+	Poll        *PollSpec         // Poll to attach, nil for none
+	Attachments []AttachmentRef
+}
+
+// thumbnailKey is the blob key of the thumbnail of an attachment
+func thumbnailKey(id string) string {
+	return id + ".thumb"
+}
+
+// newMediaID returns a random identifier that cannot be guessed from other uploads
+func newMediaID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validateAltText checks that alt text fits in MaxAltTextLength characters
+func validateAltText(operation, altText string) error {
+	if len([]rune(altText)) > MaxAltTextLength {
+		return rejectValidation(operation, fmt.Errorf("alt text exceeds maximum length of %d characters", MaxAltTextLength))
+	}
+	return nil
+}
+
+// UploadMedia validates an uploaded image, strips its metadata and stores it with a thumbnail.
+// Returns the attachment, which can then be referenced once when creating a post.
+func UploadMedia(ctx context.Context, data []byte, altText string) (models.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "services.UploadMedia", trace.WithAttributes(attribute.Int("media.size", len(data))))
+	defer span.End()
+
+	if Blobs == nil {
+		return models.Attachment{}, recordError(span, ErrMediaUnavailable)
+	}
+	if len(data) > MaxUploadBytes {
+		return models.Attachment{}, recordError(span, ErrMediaTooLarge)
+	}
+	if err := validateAltText("upload_media", altText); err != nil {
+		return models.Attachment{}, recordError(span, err)
+	}
+
+	processed, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		return models.Attachment{}, recordError(span, fmt.Errorf("%w: only JPEG, PNG and GIF images are accepted", ErrUnsupportedMedia))
+	case errors.Is(err, media.ErrTooManyPixels), errors.Is(err, media.ErrTooManyFrames):
+		return models.Attachment{}, recordError(span, rejectValidation("upload_media", err))
+	case err != nil:
+		return models.Attachment{}, recordError(span, err)
+	}
+
+	id, err := newMediaID()
+	if err != nil {
+		return models.Attachment{}, recordError(span, err)
+	}
+	if err := Blobs.Put(ctx, id, bytes.NewReader(processed.Data)); err != nil {
+		return models.Attachment{}, recordError(span, err)
+	}
+	if err := Blobs.Put(ctx, thumbnailKey(id), bytes.NewReader(processed.Thumbnail)); err != nil {
+		deleteBlobs(ctx, id)
+		return models.Attachment{}, recordError(span, err)
+	}
+
+	attachment := models.Attachment{
+		ID:          id,
+		ContentType: processed.ContentType,
+		Size:        len(processed.Data),
+		Width:       processed.Width,
+		Height:      processed.Height,
+		AltText:     altText,
+		CreatedAt:   clock(),
+	}
+	span.SetAttributes(attribute.String("media.id", id))
+
+	mediaMutex.Lock()
+	mediaItems[id] = &mediaRecord{attachment: attachment, uploaderID: identity.FromContext(ctx)}
+	mediaMutex.Unlock()
+	return attachment, nil
+}
+
+// GetMedia retrieves an uploaded attachment by its ID.
+// Attachments follow the visibility of their post; unattached uploads are only seen by whoever uploaded them.
+// Returns the attachment, or ErrMediaNotFound if it does not exist or is hidden from the calling user.
+func GetMedia(ctx context.Context, id string) (models.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "services.GetMedia", trace.WithAttributes(attribute.String("media.id", id)))
+	defer span.End()
+
+	attachment, _, err := findVisibleMedia(ctx, id)
+	if err != nil {
+		return models.Attachment{}, recordError(span, err)
+	}
+	return attachment, nil
+}
+
+// OpenMedia opens the blob of an attachment the calling user may see, or of its JPEG thumbnail.
+// Returns the attachment and its content, or ErrMediaNotFound if it does not exist or is hidden from the calling user.
+func OpenMedia(ctx context.Context, id string, thumbnail bool) (MediaContent, error) {
+	ctx, span := tracer.Start(ctx, "services.OpenMedia", trace.WithAttributes(attribute.String("media.id", id), attribute.Bool("media.thumbnail", thumbnail)))
+	defer span.End()
+
+	if Blobs == nil {
+		return MediaContent{}, recordError(span, ErrMediaUnavailable)
+	}
+	attachment, public, err := findVisibleMedia(ctx, id)
+	if err != nil {
+		return MediaContent{}, recordError(span, err)
+	}
+
+	key := id
+	if thumbnail {
+		key = thumbnailKey(id)
+		attachment.ContentType = "image/jpeg"
+	}
+	r, err := Blobs.Get(ctx, key)
+	if errors.Is(err, media.ErrBlobNotFound) {
+		err = ErrMediaNotFound
+	}
+	if err != nil {
+		return MediaContent{}, recordError(span, err)
+	}
+	return MediaContent{Attachment: attachment, Body: r, Public: public}, nil
+}
+
+// findVisibleMedia returns an attachment the calling user may see, and whether anyone may see it
+// An attachment is public while it belongs to a published public post that does not expire
+func findVisibleMedia(ctx context.Context, id string) (models.Attachment, bool, error) {
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Build the filter before taking the media mutex, which comes before the safety mutex in the lock order
+	filter := newViewerFilter(ctx)
+
+	mediaMutex.Lock()
+	defer mediaMutex.Unlock()
+
+	record, ok := mediaItems[id]
+	if !ok {
+		return models.Attachment{}, false, ErrMediaNotFound
+	}
+	if record.postID == 0 {
+		if record.uploaderID != filter.viewerID {
+			return models.Attachment{}, false, ErrMediaNotFound
+		}
+		return record.attachment, false, nil
+	}
+
+	i := findPostIndex(ctx, record.postID)
+	if i < 0 || !filter.canSee(posts[i]) {
+		return models.Attachment{}, false, ErrMediaNotFound
+	}
+	post := posts[i]
+	public := post.Status.Published() && post.Visibility == models.VisibilityPublic && post.ExpiresAt == nil
+	return record.attachment, public, nil
+}
+
+// validateAttachmentRefs checks the number of attachments of a new post and their alt text
+func validateAttachmentRefs(operation string, refs []AttachmentRef) error {
+	if len(refs) > MaxAttachmentsPerPost {
+		return rejectValidation(operation, fmt.Errorf("a post can have at most %d attachments", MaxAttachmentsPerPost))
+	}
+	for _, ref := range refs {
+		if err := validateAltText(operation, ref.AltText); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimAttachmentsLocked resolves the referenced attachments and marks them as used by the post
+// An attachment can only be used by one post, so deleting the post can delete its blobs
+// The caller must hold the post mutex and the media mutex
+func claimAttachmentsLocked(postID int, refs []AttachmentRef) ([]models.Attachment, error) {
+	attachments := make([]models.Attachment, 0, len(refs))
+	for i, ref := range refs {
+		record, ok := mediaItems[ref.ID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMediaNotFound, ref.ID)
+		}
+		if record.postID != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrAttachmentInUse, ref.ID)
+		}
+		for _, previous := range refs[:i] {
+			if previous.ID == ref.ID {
+				return nil, rejectValidation("create_post", fmt.Errorf("attachment %s is listed twice", ref.ID))
+			}
+		}
+
+		attachment := record.attachment
+		if ref.AltText != "" {
+			attachment.AltText = ref.AltText
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	for i, ref := range refs {
+		mediaItems[ref.ID].postID = postID
+		mediaItems[ref.ID].attachment = attachments[i]
+	}
+	return attachments, nil
+}
+
+// releaseAttachments deletes the attachments of a deleted post along with their blobs
+// The caller must hold the post mutex
+func releaseAttachments(ctx context.Context, post models.Post) {
+	if len(post.Attachments) == 0 {
+		return
+	}
+
+	mediaMutex.Lock()
+	for _, attachment := range post.Attachments {
+		delete(mediaItems, attachment.ID)
+	}
+	mediaMutex.Unlock()
+
+	for _, attachment := range post.Attachments {
+		deleteBlobs(ctx, attachment.ID)
+	}
+}
+
+// deleteBlobs removes the blob and thumbnail of an attachment, logging failures since the record is already gone
+func deleteBlobs(ctx context.Context, id string) {
+	if Blobs == nil {
+		return
+	}
+	for _, key := range []string{id, thumbnailKey(id)} {
+		if err := Blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).WithField(logging.FieldMediaID, id).Warnln("Failed to delete media blob: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Notification limits
+const (
+	maxNotificationsPerUser = 500 // How many of their newest notifications a user keeps
+	maxRecentActors         = 3   // Actors listed by a notification; the others are only counted
+)
+
+// Notifications, guarded by the post mutex since they are created by the changes to posts
+var notificationsOf = map[int][]models.Notification{} // User ID to their notifications, most recently updated first
+var notificationSeq = 0                               // Last assigned notification ID
+
+// GetNotifications retrieves the notifications of the calling user, most recently updated first.
+// Notifications about posts that were deleted or that the user can no longer see are left out.
+// Returns the requested page, the total number of notifications listed, the number of unread ones,
+// or ErrUnknownCaller.
+func GetNotifications(ctx context.Context, unreadOnly bool, page, limit int) ([]models.Notification, int, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetNotifications", trace.WithAttributes(attribute.Bool("notifications.unread_only", unreadOnly)))
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, 0, 0, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := filterFor(userID)
+	listed := []models.Notification{}
+	unread := 0
+	for _, notification := range notificationsOf[userID] {
+		if notification.PostID != 0 {
+			if post, ok := filter.lookup.find(notification.PostID); !ok || !filter.canSee(post) {
+				continue
+			}
+		}
+		if !notification.Read {
+			unread++
+		} else if unreadOnly {
+			continue
+		}
+		listed = append(listed, notification)
+	}
+
+	paged := pageOf(listed, page, limit)
+	for i := range paged {
+		paged[i].Summary = summarize(ctx, paged[i])
+	}
+	return paged, len(listed), unread, nil
+}
+
+// MarkNotificationRead marks a notification of the calling user as read; later activity starts a new notification.
+// Returns the notification or ErrNotificationNotFound.
+func MarkNotificationRead(ctx context.Context, id int) (models.Notification, error) {
+	ctx, span := tracer.Start(ctx, "services.MarkNotificationRead", trace.WithAttributes(attribute.Int("notification.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	userID := identity.FromContext(ctx)
+	for i, notification := range notificationsOf[userID] {
+		if notification.ID == id {
+			notificationsOf[userID][i].Read = true
+			notification.Read = true
+			notification.Summary = summarize(ctx, notification)
+			return notification, nil
+		}
+	}
+	return models.Notification{}, recordError(span, ErrNotificationNotFound)
+}
+
+// MarkAllNotificationsRead marks every notification of the calling user as read.
+// Returns the number of notifications that were unread.
+func MarkAllNotificationsRead(ctx context.Context) int {
+	_, span := tracer.Start(ctx, "services.MarkAllNotificationsRead")
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	marked := 0
+	notifications := notificationsOf[identity.FromContext(ctx)]
+	for i := range notifications {
+		if !notifications[i].Read {
+			notifications[i].Read = true
+			marked++
+		}
+	}
+	span.SetAttributes(attribute.Int("notification.count", marked))
+	return marked
+}
+
+// notifyChangeLocked notifies the users concerned by a committed change: the author of a liked or commented post,
+// and the users mentioned in a new post or comment
+// The caller must hold the post mutex
+func notifyChangeLocked(ctx context.Context, c change) {
+	switch c.kind {
+	case postLiked:
+		notifyLocked(c.post.AuthorID, models.NotificationLike, c.post.ID, identity.FromContext(ctx))
+	case commentAdded:
+		comment := c.post.Comments[len(c.post.Comments)-1]
+		notifyLocked(c.post.AuthorID, models.NotificationComment, c.post.ID, comment.AuthorID)
+		notifyMentionsLocked(c.post, comment.Mentions, comment.AuthorID)
+	case postCreated:
+		notifyMentionsLocked(c.post, c.post.Mentions, c.post.AuthorID)
+	}
+}
+
+// notifyMentionsLocked notifies the users mentioned in a post or comment who can see the post
+// The caller must hold the post mutex
+func notifyMentionsLocked(post models.Post, mentions []models.Mention, actorID int) {
+	notified := map[int]bool{}
+	for _, mention := range mentions {
+		if mention.UserID == 0 || notified[mention.UserID] || !filterFor(mention.UserID).canSee(post) {
+			continue
+		}
+		notified[mention.UserID] = true
+		notifyLocked(mention.UserID, models.NotificationMention, post.ID, actorID)
+	}
+}
+
+// notifyLocked records activity of an actor, 0 for anonymous callers, for a user
+// The activity is added to the unread notification of the same type about the same post if there is one
+// Users are not notified of their own activity, nor of the activity of users they blocked, muted or were blocked by
+// The caller must hold the post mutex
+func notifyLocked(userID int, kind models.NotificationType, postID, actorID int) {
+	if userID == 0 || userID == actorID || !acceptsActivity(userID, actorID) {
+		return
+	}
+
+	current := clock()
+	notifications := notificationsOf[userID]
+	for i, notification := range notifications {
+		if notification.Read || notification.Type != kind || notification.PostID != postID {
+			continue
+		}
+		if actorID == 0 {
+			notification.Anonymous++
+		} else {
+			notification.ActorIDs, notification.OtherActors = addRecentActor(notification.ActorIDs, actorID, notification.OtherActors)
+		}
+		notification.UpdatedAt = current
+
+		// Move the notification to the front, as the most recently updated
+		copy(notifications[1:i+1], notifications[:i])
+		notifications[0] = notification
+		return
+	}
+
+	notificationSeq++
+	notification := models.Notification{ID: notificationSeq, Type: kind, PostID: postID, ActorIDs: []int{}, CreatedAt: current, UpdatedAt: current}
+	if actorID == 0 {
+		notification.Anonymous = 1
+	} else {
+		notification.ActorIDs = []int{actorID}
+	}
+	notificationsOf[userID] = append([]models.Notification{notification}, notifications[:min(len(notifications), maxNotificationsPerUser-1)]...)
+}
+
+// addRecentActor puts an actor first among the recent actors of a notification, dropping the oldest into the count
+// of other actors beyond maxRecentActors; an actor coming back after being dropped is counted again
+func addRecentActor(recent []int, actorID, others int) ([]int, int) {
+	at := len(recent)
+	for i, id := range recent {
+		if id == actorID {
+			at = i
+			break
+		}
+	}
+	if at == len(recent) {
+		if len(recent) < maxRecentActors {
+			recent = append(recent, 0)
+		} else {
+			at, others = len(recent)-1, others+1
+		}
+	}
+	updated := make([]int, len(recent))
+	updated[0] = actorID
+	copy(updated[1:], recent[:at])
+	copy(updated[at+1:], recent[at+1:])
+	return updated, others
+}
+
+// acceptsActivity reports whether a user wants to hear about the activity of an actor, given their blocks and mutes
+func acceptsActivity(userID, actorID int) bool {
+	if actorID == 0 {
+		return true
+	}
+	safetyMutex.RLock()
+	defer safetyMutex.RUnlock()
+	return !blocksOf[userID][actorID] && !blocksOf[actorID][userID] && !mutesOf[userID][actorID]
+}
+
+// summarize describes a notification, e.g. "alice and 12 others liked your post"
+func summarize(ctx context.Context, notification models.Notification) string {
+	who := "Someone"
+	others := len(notification.ActorIDs) + notification.OtherActors + notification.Anonymous - 1
+	if len(notification.ActorIDs) > 0 {
+		if user, err := GetUserByID(ctx, notification.ActorIDs[0]); err == nil {
+			who = user.Username
+		}
+	}
+	switch {
+	case others == 1:
+		who += " and 1 other"
+	case others > 1:
+		who += fmt.Sprintf(" and %d others", others)
+	}
+
+	switch notification.Type {
+	case models.NotificationLike:
+		return who + " liked your post"
+	case models.NotificationComment:
+		return who + " commented on your post"
+	case models.NotificationMention:
+		return who + " mentioned you"
+	case models.NotificationFollow:
+		return who + " followed you"
+	default:
+		return who
+	}
+}
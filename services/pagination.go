@@ -0,0 +1,12 @@
+package services
+
+// pageOf returns the items of a 1-based page, or an empty slice past the last page
+// Pages are compared before multiplying so huge page numbers cannot overflow the start index
+func pageOf[T any](items []T, page, limit int) []T {
+	if page < 1 || limit < 1 || page-1 > (len(items)-1)/limit {
+		return []T{}
+	}
+	startIndex := (page - 1) * limit
+	endIndex := startIndex + min(limit, len(items)-startIndex)
+	return items[startIndex:endIndex]
+}
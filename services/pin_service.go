@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxPinnedPosts is how many posts a user can pin to their profile
+const MaxPinnedPosts = 3
+
+// Pinned posts, guarded by the post mutex like the posts they reference
+var pinnedPosts = map[int][]int{} // User ID to the IDs of their pinned posts, most recently pinned first
+
+// PinPost pins a post of the calling user to the top of their profile; pinning a pinned post is not an error.
+// Returns the pinned post, ErrPostNotFound if the caller cannot see it, ErrNotAuthor, or a validation error
+// if the post is not published or MaxPinnedPosts are already pinned.
+func PinPost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.PinPost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	userID := identity.FromContext(ctx)
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if posts[i].AuthorID == 0 || posts[i].AuthorID != userID {
+		return models.Post{}, recordError(span, ErrNotAuthor)
+	}
+	if !posts[i].Status.Published() {
+		return models.Post{}, recordError(span, errNotPublished("pin_post"))
+	}
+
+	pinned := pinnedPosts[userID]
+	if !containsInt(pinned, id) {
+		if len(pinned) >= MaxPinnedPosts {
+			return models.Post{}, recordError(span, rejectValidation("pin_post", fmt.Errorf("at most %d posts can be pinned", MaxPinnedPosts)))
+		}
+		pinnedPosts[userID] = append([]int{id}, pinned...)
+	}
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// UnpinPost unpins a post of the calling user; unpinning a post that is not pinned is not an error.
+// Returns the post, ErrPostNotFound if the caller cannot see it, or ErrNotAuthor.
+func UnpinPost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.UnpinPost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	userID := identity.FromContext(ctx)
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if posts[i].AuthorID == 0 || posts[i].AuthorID != userID {
+		return models.Post{}, recordError(span, ErrNotAuthor)
+	}
+
+	pinnedPosts[userID] = removeInt(pinnedPosts[userID], id)
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// GetPinnedPosts retrieves the posts pinned by a user that the calling user can see, most recently pinned first.
+// Returns the posts or ErrUserNotFound.
+func GetPinnedPosts(ctx context.Context, userID int) ([]models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.GetPinnedPosts", trace.WithAttributes(attribute.Int("user.id", userID)))
+	defer span.End()
+
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, recordError(span, err)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := newViewerFilter(ctx)
+	pinned := []models.Post{}
+	for _, id := range pinnedPosts[userID] {
+		if post, ok := filter.lookup.find(id); ok && filter.canSee(post) {
+			pinned = append(pinned, filter.present(post))
+		}
+	}
+	return pinned, nil
+}
+
+// forgetPinsLocked unpins a deleted post
+// The caller must hold the post mutex
+func forgetPinsLocked(post models.Post) {
+	if post.AuthorID != 0 {
+		pinnedPosts[post.AuthorID] = removeInt(pinnedPosts[post.AuthorID], post.ID)
+	}
+}
+
+// containsInt reports whether ids contains id
+func containsInt(ids []int, id int) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
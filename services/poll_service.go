@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Limits of polls
+const (
+	MinPollOptions      = 2
+	MaxPollOptions      = 4
+	MaxPollOptionLength = 50
+	MaxPollDuration     = 30 * 24 * time.Hour
+)
+
+// PollSpec describes the poll of a new post
+type PollSpec struct {
+	Options  []string
+	Multiple bool // Voters may choose several options
+	ClosesAt time.Time
+}
+
+// VotePoll records the vote of the calling user in the poll of a post; each user votes once.
+// Choices are the indexes of the chosen options, exactly one unless the poll allows several.
+// Returns the post with the results, or an error if the caller is unknown, the post cannot be seen or has no poll,
+// the poll is closed, the caller already voted or the choices are invalid.
+func VotePoll(ctx context.Context, postID int, choices []int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.VotePoll", trace.WithAttributes(attribute.Int("post.id", postID)))
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return models.Post{}, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i := findVisiblePostIndex(ctx, postID)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if !posts[i].Status.Published() {
+		return models.Post{}, recordError(span, errNotPublished("vote_poll"))
+	}
+	current := posts[i].Poll
+	if current == nil {
+		return models.Post{}, recordError(span, ErrPollNotFound)
+	}
+	if !clock().Before(current.ClosesAt) {
+		return models.Post{}, recordError(span, ErrPollClosed)
+	}
+	if _, voted := current.Votes[userID]; voted {
+		return models.Post{}, recordError(span, ErrAlreadyVoted)
+	}
+	if err := validateChoices(choices, *current); err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+
+	// Replace the poll, so posts previously returned to callers keep their results
+	poll := *current
+	poll.Options = append([]models.PollOption(nil), current.Options...)
+	poll.Votes = make(map[int][]int, len(current.Votes)+1)
+	for voterID, voted := range current.Votes {
+		poll.Votes[voterID] = voted
+	}
+	poll.Votes[userID] = append([]int(nil), choices...)
+	for _, choice := range choices {
+		poll.Options[choice].Votes++
+	}
+	posts[i].Poll = &poll
+
+	commitChanges(ctx, change{pollVoted, posts[i]})
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// validatePollSpec checks the poll of a new post published at the given time
+func validatePollSpec(operation string, spec PollSpec, publishedAt time.Time) error {
+	if len(spec.Options) < MinPollOptions || len(spec.Options) > MaxPollOptions {
+		return rejectValidation(operation, fmt.Errorf("a poll needs %d to %d options", MinPollOptions, MaxPollOptions))
+	}
+	seen := map[string]bool{}
+	for _, option := range spec.Options {
+		text := strings.ToLower(strings.TrimSpace(option))
+		if text == "" || len(option) > MaxPollOptionLength {
+			return rejectValidation(operation, fmt.Errorf("poll options must have 1 to %d characters", MaxPollOptionLength))
+		}
+		if seen[text] {
+			return rejectValidation(operation, fmt.Errorf("poll option %q is listed twice", option))
+		}
+		seen[text] = true
+	}
+	if !spec.ClosesAt.After(publishedAt) {
+		return rejectValidation(operation, errors.New("poll must close after the post is published"))
+	}
+	if spec.ClosesAt.Sub(publishedAt) > MaxPollDuration {
+		return rejectValidation(operation, fmt.Errorf("poll must close within %v of the post being published", MaxPollDuration))
+	}
+	return nil
+}
+
+// newPoll builds the poll of a new post from its validated spec
+func newPoll(spec PollSpec) *models.Poll {
+	poll := &models.Poll{Multiple: spec.Multiple, ClosesAt: spec.ClosesAt, Votes: map[int][]int{}}
+	for _, option := range spec.Options {
+		poll.Options = append(poll.Options, models.PollOption{Text: strings.TrimSpace(option)})
+	}
+	return poll
+}
+
+// validateChoices checks the options chosen in a vote
+func validateChoices(choices []int, poll models.Poll) error {
+	if len(choices) == 0 || (!poll.Multiple && len(choices) > 1) {
+		return rejectValidation("vote_poll", errors.New("choose one option, or several if the poll allows it"))
+	}
+	for i, choice := range choices {
+		if choice < 0 || choice >= len(poll.Options) {
+			return rejectValidation("vote_poll", fmt.Errorf("option %d does not exist", choice))
+		}
+		for _, previous := range choices[:i] {
+			if previous == choice {
+				return rejectValidation("vote_poll", fmt.Errorf("option %d is chosen twice", choice))
+			}
+		}
+	}
+	return nil
+}
+
+// showPoll returns the post with its poll as the viewer sees it: the votes are only shown once the viewer voted,
+// the poll closed or to its author, and who voted for what is never shown
+// The poll is copied, so the stored post is not modified
+func (f viewerFilter) showPoll(post models.Post) models.Post {
+	if post.Poll == nil {
+		return post
+	}
+	poll := *post.Poll
+	poll.Voters = len(poll.Votes)
+	poll.Voted = poll.Votes[f.viewerID]
+	poll.Votes = nil
+	poll.Closed = !f.now.Before(poll.ClosesAt)
+	poll.ShowResults = poll.Closed || poll.Voted != nil || (post.AuthorID != 0 && post.AuthorID == f.viewerID)
+	post.Poll = &poll
+	return post
+}
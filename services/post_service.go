@@ -1,148 +1,349 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"mini-social-media-api/hashtag"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
 	"mini-social-media-api/models"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var posts []models.Post       // In-memory storage for all posts
-var postMutex = &sync.Mutex{} // Mutex to ensure safe concurrent access to the posts slice
-var postIDCounter = 1         // Counter for generating unique post IDs
-var now = time.Now().Local()  // Current local time
+// recordError marks the span as failed with the given error and returns it
+func recordError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// validatePostContent checks that post content by authorID is not blank, within 250 characters and mentions users it may
+func validatePostContent(operation, content string, authorID int) error {
+	if content == "" || strings.TrimSpace(content) == "" {
+		return rejectValidation(operation, errors.New("post content cannot be empty"))
+	}
+	if len(content) > 250 {
+		return rejectValidation(operation, errors.New("post content exceeds maximum length of 250 characters"))
+	}
+	return validateMentions(operation, content, authorID)
+}
 
 // CreatePost creates a new post with the given content.
 // Returns the created post or an error if the content is invalid.
-func CreatePost(content string) (models.Post, error) {
+func CreatePost(ctx context.Context, content string) (models.Post, error) {
+	return CreatePostWithOptions(ctx, content, PostOptions{})
+}
+
+// CreatePostWithOptions creates a new post with the given content and optional parts such as attachments or a quoted post.
+// Returns the created post or an error if the content is invalid, an attachment cannot be used or the quoted post cannot be shared.
+func CreatePostWithOptions(ctx context.Context, content string, options PostOptions) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.CreatePost", trace.WithAttributes(attribute.Int("post.attachments", len(options.Attachments))))
+	defer span.End()
+
 	// Validate content
-	if content == "" || strings.TrimSpace(content) == "" {
-		return models.Post{}, errors.New("post content cannot be empty")
+	if err := validatePostContent("create_post", content, options.AuthorID); err != nil {
+		return models.Post{}, recordError(span, err)
 	}
-	if len(content) > 250 {
-		return models.Post{}, errors.New("post content exceeds maximum length of 250 characters")
+	if err := validateAttachmentRefs("create_post", options.Attachments); err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	if options.AuthorID != 0 {
+		if _, err := GetUserByID(ctx, options.AuthorID); err != nil {
+			return models.Post{}, recordError(span, ErrUnknownCaller)
+		}
+	}
+	if err := validateVisibility("create_post", options.Visibility, options.AuthorID); err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	status, err := validateSchedule("create_post", options.Status, options.PublishAt, options.AuthorID)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	if err := validateTTL("create_post", options.TTL, status); err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	if options.Poll != nil {
+		publishedAt := clock()
+		if options.PublishAt != nil {
+			publishedAt = *options.PublishAt
+		}
+		if err := validatePollSpec("create_post", *options.Poll, publishedAt); err != nil {
+			return models.Post{}, recordError(span, err)
+		}
 	}
 
-	postMutex.Lock()
+	lockPosts()
 	defer postMutex.Unlock()
 
-	// Initialize a new post with default values and given content
-	post := models.Post{
-		ID:        postIDCounter,
-		Content:   content,
-		Likes:     0,
-		Comments:  []models.Comment{},
-		CreatedAt: now,
-		UpdatedAt: now,
+	quoted := -1
+	if options.QuoteOf != 0 {
+		var err error
+		if quoted, err = findShareablePostLocked(ctx, options.QuoteOf); err != nil {
+			return models.Post{}, recordError(span, err)
+		}
 	}
 
-	postIDCounter++             // Increment the counter for the next postID
-	posts = append(posts, post) // Add the new post to the in-memory slice
+	// Claim the attachments with the ID the post is about to get, so a failure leaves the store untouched
+	mediaMutex.Lock()
+	attachments, err := claimAttachmentsLocked(postIDCounter, options.Attachments)
+	mediaMutex.Unlock()
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
 
-	return post, nil
+	draft := models.Post{AuthorID: options.AuthorID, Visibility: options.Visibility, Status: status, PublishAt: options.PublishAt, Attachments: attachments}
+	if options.TTL > 0 {
+		expiresAt := clock().Add(options.TTL)
+		draft.ExpiresAt = &expiresAt
+	}
+	if options.Poll != nil {
+		draft.Poll = newPoll(*options.Poll)
+	}
+	if quoted >= 0 {
+		draft.QuoteOf = posts[quoted].ID
+		if status.Published() {
+			posts[quoted].Quotes++ // Unpublished quotes are counted once published
+		}
+	}
+	post := createPostLocked(ctx, content, draft)
+	span.SetAttributes(attribute.Int("post.id", post.ID))
+
+	commitChanges(ctx, change{postCreated, post})
+	return newViewerFilter(ctx).present(post), nil
 }
 
-// UpdatePost updates the content of an existing post by its ID.
-// Returns the updated post or an error if the post is not found or the content is invalid.
-func UpdatePost(id int, newContent string) (models.Post, error) {
-	postMutex.Lock()
+// createPostLocked stores a new post with already validated content
+// The optional fields of the post, such as the author and attachments, are taken from post
+// The caller must hold the post mutex
+func createPostLocked(ctx context.Context, content string, post models.Post) models.Post {
+	// Initialize a new post with default values and given content
+	if post.Visibility == "" {
+		post.Visibility = models.VisibilityPublic
+	}
+	if post.Status == "" {
+		post.Status = models.StatusPublished
+	}
+	if post.Attachments == nil {
+		post.Attachments = []models.Attachment{}
+	}
+	post.Likes = 0
+	post.Comments = []models.Comment{}
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	setContent(&post, content)
+	return insertPost(ctx, post)
+}
+
+// setContent replaces the content of a post along with the hashtags and mentions parsed from it
+// Previews of links that are no longer in the content are dropped
+func setContent(post *models.Post, content string) {
+	post.Content = content
+	post.Hashtags = hashtag.Extract(content)
+	post.Mentions, _, _ = resolveMentions(content, post.AuthorID)
+	post.Previews = keepPreviews(post.Previews, postLinks(content))
+}
+
+// UpdatePost updates the content of an existing post of the calling user by its ID.
+// Returns the updated post, ErrPostNotFound if the caller cannot see the post, ErrNotAuthor,
+// or an error if the content is invalid.
+func UpdatePost(ctx context.Context, id int, newContent string) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.UpdatePost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
 	defer postMutex.Unlock()
 
 	// Validate the new content
-	if newContent == "" || strings.TrimSpace(newContent) == "" {
-		return models.Post{}, errors.New("post content cannot be empty")
+	if err := validatePostContent("update_post", newContent, identity.FromContext(ctx)); err != nil {
+		return models.Post{}, recordError(span, err)
 	}
-	if len(newContent) > 250 {
-		return models.Post{}, errors.New("post content exceeds maximum length of 250 characters")
+
+	post, err := updatePostLocked(ctx, id, newContent)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
 	}
 
+	commitChanges(ctx, change{postUpdated, post})
+	return post, nil
+}
+
+// updatePostLocked replaces the content of a post with already validated content
+// The caller must hold the post mutex
+func updatePostLocked(ctx context.Context, id int, newContent string) (models.Post, error) {
 	// Find the post by ID and update its content
-	for i, post := range posts {
-		if post.ID == id {
-			posts[i].Content = newContent
-			posts[i].UpdatedAt = time.Now()
-			return posts[i], nil
-		}
+	i, err := findOwnPostIndex(ctx, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if posts[i].RepostOf != 0 {
+		return models.Post{}, errRepostNotEditable("update_post")
+	}
+
+	setContent(&posts[i], newContent)
+	posts[i].UpdatedAt = time.Now()
+	return posts[i], nil
+}
+
+// DeletePost removes a post of the calling user and its comments by its ID.
+// Returns the deleted post, ErrPostNotFound if the caller cannot see the post, or ErrNotAuthor.
+func DeletePost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.DeletePost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	post, err := deletePostLocked(ctx, id)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+
+	commitChanges(ctx, change{postDeleted, post})
+	return post, nil
+}
+
+// deletePostLocked removes a post from the store
+// The caller must hold the post mutex
+func deletePostLocked(ctx context.Context, id int) (models.Post, error) {
+	i, err := findOwnPostIndex(ctx, id)
+	if err != nil {
+		return models.Post{}, err
 	}
 
-	return models.Post{}, errors.New("post not found")
+	removed := removePost(ctx, i)
+	releaseShareLocked(ctx, removed)
+	return removed, nil
+}
+
+// findOwnPostIndex returns the index of a post the calling user can see and wrote
+// Anonymous posts have no owner and stay editable by anonymous callers, as they were before posts had authors
+// The caller must hold the post mutex
+func findOwnPostIndex(ctx context.Context, id int) (int, error) {
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return -1, ErrPostNotFound
+	}
+	if posts[i].AuthorID != identity.FromContext(ctx) {
+		return -1, ErrNotAuthor
+	}
+	return i, nil
 }
 
 // GetAllPosts retrieves all posts from the in-memory storage.
+// Posts and comments hidden from the calling user by blocks and mutes are left out.
 // Returns a slice of all posts.
-func GetAllPosts() []models.Post {
-	postMutex.Lock()
+func GetAllPosts(ctx context.Context) []models.Post {
+	ctx, span := tracer.Start(ctx, "services.GetAllPosts")
+	defer span.End()
+
+	lockPosts()
 	defer postMutex.Unlock()
-	return posts
+	return newViewerFilter(ctx).apply(listPosts(ctx))
 }
 
 // LikePost increments the like count for a specific post by its ID.
-// Returns the updated post or an error if the post is not found.
-func LikePost(id int) (models.Post, error) {
-	postMutex.Lock()
+// Returns the updated post or an error if the post is not found or the calling user is blocked by its author.
+func LikePost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.LikePost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
 	defer postMutex.Unlock()
 
+	post, err := likePostLocked(ctx, id)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+
+	commitChanges(ctx, change{postLiked, post})
+	return newViewerFilter(ctx).present(post), nil
+}
+
+// likePostLocked increments the like count of a post
+// Posts the caller cannot see, e.g. because of a block, are reported as not found
+// The caller must hold the post mutex
+func likePostLocked(ctx context.Context, id int) (models.Post, error) {
 	// Find the post by its ID and increment its like count
-	for i, post := range posts {
-		if post.ID == id {
-			posts[i].Likes++
-			return posts[i], nil
-		}
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return models.Post{}, ErrPostNotFound
+	}
+	if !posts[i].Status.Published() {
+		return models.Post{}, errNotPublished("like_post")
 	}
 
-	return models.Post{}, errors.New("post not found")
+	posts[i].Likes++
+	return posts[i], nil
 }
 
 // GetPostDetailsByID retrieves the details of a specific post by its ID, excluding comments.
-// Returns the found post or an error if the post is not found.
-func GetPostDetailsByID(id int) (models.Post, error) {
-	postMutex.Lock()
+// Returns the found post or an error if the post is not found or hidden from the calling user by a block.
+func GetPostDetailsByID(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.GetPostDetailsByID", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
 	defer postMutex.Unlock()
 
-	// Iterate over posts to find the one matching the given ID
-	for _, post := range posts {
-		if post.ID == id {
-			return post, nil
-		}
+	// Find the post matching the given ID; blocks hide the post, mutes only hide comments
+	filter := newViewerFilter(ctx)
+	i := findPostIndex(ctx, id)
+	if i < 0 || !filter.canSee(posts[i]) {
+		return models.Post{}, recordError(span, ErrPostNotFound)
 	}
 
-	return models.Post{}, errors.New("post not found")
+	return filter.present(posts[i]), nil
 }
 
-// AddComment adds a new comment to a specific post by its ID.
-// Returns the updated post or an error if the post is not found or validation fails.
-func AddComment(postID int, comment models.Comment) (models.Post, error) {
+// AddComment adds a new comment by the calling user to a specific post by its ID.
+// Returns the updated post or an error if the post is not found, hidden from the calling user by a block, or validation fails.
+func AddComment(ctx context.Context, postID int, comment models.Comment) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.AddComment", trace.WithAttributes(attribute.Int("post.id", postID)))
+	defer span.End()
+
 	// Validate comment text
 	if comment.Text == "" || strings.TrimSpace(comment.Text) == "" {
-		logrus.Error()
-		return models.Post{}, errors.New("comment cannot be empty")
+		logging.FromContext(ctx).Errorln("Comment validation failed: comment cannot be empty")
+		return models.Post{}, recordError(span, rejectValidation("add_comment", errors.New("comment cannot be empty")))
 	}
 	if len(comment.Text) > 150 {
-		return models.Post{}, errors.New("comment exceeds maximum length of 150 characters")
+		return models.Post{}, recordError(span, rejectValidation("add_comment", errors.New("comment exceeds maximum length of 150 characters")))
+	}
+	if err := validateMentions("add_comment", comment.Text, identity.FromContext(ctx)); err != nil {
+		return models.Post{}, recordError(span, err)
 	}
 
-	postMutex.Lock()
+	lockPosts()
 	defer postMutex.Unlock()
 
-	// find the post by ID
-	for i, post := range posts {
-		if post.ID == postID {
-			// Create a new comment
-			newComment := models.Comment{
-				ID:        len(post.Comments) + 1, // Generate comment ID based on the length of the Comments slice
-				Text:      comment.Text,
-				CreatedAt: now,
-			}
-
-			// Append the new comment to the post's comments slice
-			posts[i].Comments = append(posts[i].Comments, newComment)
+	// find the post by ID; users blocked by the author cannot see it, so they cannot comment on it
+	filter := newViewerFilter(ctx)
+	i := findPostIndex(ctx, postID)
+	if i < 0 || !filter.canSee(posts[i]) {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if !posts[i].Status.Published() {
+		return models.Post{}, recordError(span, errNotPublished("add_comment"))
+	}
 
-			return posts[i], nil
-		}
+	// Create a new comment by the calling user
+	mentions, _, _ := resolveMentions(comment.Text, identity.FromContext(ctx))
+	newComment := models.Comment{
+		ID:        len(posts[i].Comments) + 1, // Generate comment ID based on the length of the Comments slice
+		AuthorID:  identity.FromContext(ctx),
+		Text:      comment.Text,
+		Mentions:  mentions,
+		CreatedAt: now,
 	}
 
-	return models.Post{}, errors.New("post not found")
+	// Append the new comment to the post's comments slice
+	posts[i].Comments = append(posts[i].Comments, newComment)
+
+	commitChanges(ctx, change{commentAdded, posts[i]})
+	return filter.present(posts[i]), nil
 }
@@ -1,8 +1,23 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"mini-social-media-api/dto"
+	"mini-social-media-api/events"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/media"
 	"mini-social-media-api/models"
+	"mini-social-media-api/unfurl"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -29,7 +44,7 @@ func TestCreatePost(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			post, err := CreatePost(testCase.content)
+			post, err := CreatePost(context.Background(), testCase.content)
 
 			// Check error matches expected result
 			if (err != nil) != testCase.wantErr {
@@ -94,7 +109,7 @@ func TestUpdatePost(t *testing.T) {
 	}
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			updatedPost, err := UpdatePost(testCase.id, testCase.newContent)
+			updatedPost, err := UpdatePost(context.Background(), testCase.id, testCase.newContent)
 
 			// Check if error expectation matches
 			if (err != nil) != testCase.wantErr {
@@ -144,8 +159,8 @@ func TestGetAllPosts(t *testing.T) {
 			// Reset global posts for each test case
 			posts = testCase.posts
 
-			// Call GetAllPosts() to get the posts
-			result := GetAllPosts()
+			// Call GetAllPosts(context.Background()) to get the posts
+			result := GetAllPosts(context.Background())
 
 			// Check if the length of the result matches the expected length
 			if len(result) != testCase.wantLen {
@@ -172,7 +187,7 @@ func TestLikePost(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			likedPost, err := LikePost(testCase.postID)
+			likedPost, err := LikePost(context.Background(), testCase.postID)
 
 			if (err != nil) != testCase.wantErr {
 				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
@@ -206,7 +221,7 @@ func TestGetPostDetailsByID(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			post, err := GetPostDetailsByID(testCase.postID)
+			post, err := GetPostDetailsByID(context.Background(), testCase.postID)
 
 			if (err != nil) != testCase.wantErr {
 				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
@@ -246,7 +261,7 @@ func TestAddComment(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := AddComment(testCase.postID, testCase.comment)
+			_, err := AddComment(context.Background(), testCase.postID, testCase.comment)
 
 			// Check for error expectation
 			if (err != nil) != testCase.wantErr {
@@ -255,7 +270,7 @@ func TestAddComment(t *testing.T) {
 
 			if !testCase.wantErr {
 				// Check if the comment was added
-				post, _ := GetPostDetailsByID(testCase.postID)
+				post, _ := GetPostDetailsByID(context.Background(), testCase.postID)
 
 				// Check if the comment was added correctly
 				if len(post.Comments) != testCase.wantComms {
@@ -270,3 +285,1959 @@ func TestAddComment(t *testing.T) {
 		})
 	}
 }
+
+func TestPatchPost(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      PatchFormat
+		document    string
+		wantErr     error
+		wantContent string
+	}{
+		// Valid cases
+		{"Merge patch content", MergePatch, `{"content": "Patched"}`, nil, "Patched"},
+		{"JSON patch replace content", JSONPatch, `[{"op": "replace", "path": "/content", "value": "Patched"}]`, nil, "Patched"},
+		{"JSON patch test then replace", JSONPatch, `[{"op": "test", "path": "/likes", "value": 10}, {"op": "replace", "path": "/content", "value": "Patched"}]`, nil, "Patched"},
+		{"Merge patch with unchanged read-only field", MergePatch, `{"id": 1, "content": "Patched"}`, nil, "Patched"},
+		{"JSON patch tests a field of the response", JSONPatch, `[{"op": "test", "path": "/entities", "value": []}, {"op": "replace", "path": "/content", "value": "Patched"}]`, nil, "Patched"},
+
+		// Invalid cases
+		{"Post not found", MergePatch, `{"content": "Patched"}`, ErrPostNotFound, "Post 1"},
+		{"Merge patch read-only likes", MergePatch, `{"likes": 100}`, ErrFieldNotPatchable, "Post 1"},
+		{"JSON patch read-only id", JSONPatch, `[{"op": "replace", "path": "/id", "value": 5}]`, ErrFieldNotPatchable, "Post 1"},
+		{"Read-only change rolls back content change", JSONPatch, `[{"op": "replace", "path": "/content", "value": "Patched"}, {"op": "add", "path": "/comments/-", "value": {"text": "x"}}]`, ErrFieldNotPatchable, "Post 1"},
+		{"Unknown field", MergePatch, `{"pinned": true}`, ErrFieldNotPatchable, "Post 1"},
+		{"Rendered field of the response", MergePatch, `{"entities": [{"type": "url"}]}`, ErrFieldNotPatchable, "Post 1"},
+		{"Field left out of the response of an anonymous post", JSONPatch, `[{"op": "test", "path": "/author_id", "value": 0}]`, ErrInvalidPatch, "Post 1"},
+		{"JSON patch failed test", JSONPatch, `[{"op": "test", "path": "/likes", "value": 0}, {"op": "replace", "path": "/content", "value": "Patched"}]`, ErrPatchConflict, "Post 1"},
+		{"Malformed patch", JSONPatch, `{"op": "replace"}`, ErrInvalidPatch, "Post 1"},
+		{"Merge patch removing content", MergePatch, `{"content": null}`, &ValidationError{}, "Post 1"},
+		{"Content too long", MergePatch, `{"content": "` + strings.Repeat("a", 251) + `"}`, &ValidationError{}, "Post 1"},
+		{"Content of wrong type", MergePatch, `{"content": 5}`, &ValidationError{}, "Post 1"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			posts = []models.Post{
+				{ID: 1, Content: "Post 1", Likes: 10, Comments: []models.Comment{}},
+			}
+			id := 1
+			if testCase.wantErr == ErrPostNotFound {
+				id = 99
+			}
+
+			_, err := PatchPost(context.Background(), id, testCase.format, []byte(testCase.document), dto.RenderOptions{})
+
+			var validationErr *ValidationError
+			switch want := testCase.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+			case *ValidationError:
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Expected validation error, got: %v", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("Expected error: %v, got: %v", want, err)
+				}
+			}
+
+			// The stored post is only modified by a successful patch
+			if posts[0].Content != testCase.wantContent || posts[0].Likes != 10 || len(posts[0].Comments) != 0 {
+				t.Errorf("Unexpected stored post after patch: %+v", posts[0])
+			}
+		})
+	}
+}
+
+func TestPatchPostVisibility(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name           string
+		anonymous      bool
+		document       string
+		wantErr        bool
+		wantVisibility models.Visibility
+		wantContent    string
+	}{
+		{"Visibility", false, `{"visibility": "followers"}`, false, models.VisibilityFollowers, "Hello"},
+		{"Visibility and content together", false, `{"visibility": "private", "content": "Edited"}`, false, models.VisibilityPrivate, "Edited"},
+		{"Unknown visibility", false, `{"visibility": "friends"}`, true, models.VisibilityPublic, "Hello"},
+		{"Removed visibility", false, `{"visibility": null, "content": "Edited"}`, true, models.VisibilityPublic, "Hello"},
+		{"Anonymous post", true, `{"visibility": "private"}`, true, models.VisibilityPublic, "Hello"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			resetSocialGraph()
+			alice, _ := CreateUser(ctx, "alice", "")
+			bob, _ := CreateUser(ctx, "bob", "")
+			author := identity.NewContext(ctx, alice.ID)
+			options := PostOptions{AuthorID: alice.ID}
+			if testCase.anonymous {
+				author, options = ctx, PostOptions{}
+			}
+			post, _ := CreatePostWithOptions(author, "Hello", options)
+
+			_, err := PatchPost(author, post.ID, MergePatch, []byte(testCase.document), dto.RenderOptions{})
+			var validationErr *ValidationError
+			if testCase.wantErr != errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error: %v, got: %v", testCase.wantErr, err)
+			}
+
+			stored, _ := GetPostDetailsByID(author, post.ID)
+			if stored.Visibility != testCase.wantVisibility || stored.Content != testCase.wantContent {
+				t.Errorf("Expected %s post %q, got %s post %q", testCase.wantVisibility, testCase.wantContent, stored.Visibility, stored.Content)
+			}
+			if _, err := GetPostDetailsByID(identity.NewContext(ctx, bob.ID), post.ID); (err != nil) != (testCase.wantVisibility != models.VisibilityPublic) {
+				t.Errorf("Expected only public posts to be seen by users not following the author, got %s post: %v", testCase.wantVisibility, err)
+			}
+		})
+	}
+}
+
+func TestPatchPostOnlySeesWhatTheCallerSees(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+
+	post, _ := CreatePostWithOptions(asAlice, "Tabs or spaces?", PostOptions{AuthorID: alice.ID, Poll: &PollSpec{Options: []string{"Tabs", "Spaces"}, ClosesAt: time.Now().Add(time.Hour)}})
+	VotePoll(identity.NewContext(ctx, bob.ID), post.ID, []int{0})
+	AddComment(identity.NewContext(ctx, carol.ID), post.ID, models.Comment{Text: "secretword"})
+	MuteUser(ctx, alice.ID, carol.ID)
+
+	// A right and a wrong guess must fail alike, so the outcome tells nothing
+	tests := []struct {
+		name  string
+		right string
+		wrong string
+	}{
+		{"Who voted for what", fmt.Sprintf(`{"op": "test", "path": "/poll/votes/%d", "value": [0]}`, bob.ID), fmt.Sprintf(`{"op": "test", "path": "/poll/votes/%d", "value": [1]}`, bob.ID)},
+		{"Comment of a muted user", `{"op": "test", "path": "/comments/0/text", "value": "secretword"}`, `{"op": "test", "path": "/comments/0/text", "value": "other"}`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, rightErr := PatchPost(asAlice, post.ID, JSONPatch, []byte("["+testCase.right+"]"), dto.RenderOptions{})
+			_, wrongErr := PatchPost(asAlice, post.ID, JSONPatch, []byte("["+testCase.wrong+"]"), dto.RenderOptions{})
+			if rightErr == nil || wrongErr == nil || rightErr.Error() != wrongErr.Error() {
+				t.Errorf("Expected both guesses to fail alike, got %v and %v", rightErr, wrongErr)
+			}
+		})
+	}
+}
+
+func TestOnlyAuthorsChangePosts(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		change func(ctx context.Context, id int) error
+	}{
+		{"Update", func(ctx context.Context, id int) error { _, err := UpdatePost(ctx, id, "Edited"); return err }},
+		{"Patch", func(ctx context.Context, id int) error {
+			_, err := PatchPost(ctx, id, MergePatch, []byte(`{"content": "Edited"}`), dto.RenderOptions{})
+			return err
+		}},
+		{"Delete", func(ctx context.Context, id int) error { _, err := DeletePost(ctx, id); return err }},
+		{"Batch update", func(ctx context.Context, id int) error {
+			results, _ := ExecuteBatch(ctx, []BatchOperation{{Op: BatchUpdate, PostID: id, Content: "Edited"}}, false)
+			return results[0].Err
+		}},
+		{"Batch delete", func(ctx context.Context, id int) error {
+			results, _ := ExecuteBatch(ctx, []BatchOperation{{Op: BatchDelete, PostID: id}}, false)
+			return results[0].Err
+		}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			resetSocialGraph()
+			alice, _ := CreateUser(ctx, "alice", "")
+			bob, _ := CreateUser(ctx, "bob", "")
+			asAlice := identity.NewContext(ctx, alice.ID)
+			post, _ := CreatePostWithOptions(asAlice, "Hello", PostOptions{AuthorID: alice.ID})
+
+			for name, caller := range map[string]context.Context{"another user": identity.NewContext(ctx, bob.ID), "an anonymous caller": ctx} {
+				if err := testCase.change(caller, post.ID); !errors.Is(err, ErrNotAuthor) {
+					t.Errorf("Expected ErrNotAuthor for %s, got: %v", name, err)
+				}
+			}
+			if stored, _ := GetPostDetailsByID(asAlice, post.ID); stored.Content != "Hello" {
+				t.Errorf("Expected the post to be left untouched, got: %+v", stored)
+			}
+			if err := testCase.change(asAlice, post.ID); err != nil {
+				t.Errorf("Expected the author to change the post, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	posts = []models.Post{
+		{ID: 1, Content: "Post 1", Likes: 10, Comments: []models.Comment{}},
+		{ID: 2, Content: "Post 2", Likes: 5, Comments: []models.Comment{}},
+	}
+
+	tests := []struct {
+		name    string
+		postID  int
+		wantErr bool
+		wantLen int
+	}{
+		{"Delete existing post", 1, false, 1},
+		{"Delete already deleted post", 1, true, 1},
+		{"Delete non-existent post", 99, true, 1},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			deleted, err := DeletePost(context.Background(), testCase.postID)
+
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if !testCase.wantErr && deleted.ID != testCase.postID {
+				t.Errorf("Expected deleted post ID %d, got %d", testCase.postID, deleted.ID)
+			}
+			if len(posts) != testCase.wantLen {
+				t.Errorf("Expected %d posts, got %d", testCase.wantLen, len(posts))
+			}
+		})
+	}
+}
+
+func TestExecuteBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		operations  []BatchOperation
+		atomic      bool
+		wantErr     error
+		wantResults []bool // Whether each operation succeeded
+		wantLikes   int    // Likes of post 1 afterwards
+		wantPosts   int
+	}{
+		{"Non atomic applies successful operations", []BatchOperation{
+			{Op: BatchCreate, Content: "New post"},
+			{Op: BatchLike, PostID: 1},
+			{Op: BatchLike, PostID: 99},
+			{Op: BatchUpdate, PostID: 1, Content: "Updated"},
+		}, false, nil, []bool{true, true, false, true}, 11, 3},
+		{"Atomic commits when every operation succeeds", []BatchOperation{
+			{Op: BatchLike, PostID: 1},
+			{Op: BatchDelete, PostID: 2},
+		}, true, nil, []bool{true, true}, 11, 1},
+		{"Atomic rolls back on failure", []BatchOperation{
+			{Op: BatchCreate, Content: "New post"},
+			{Op: BatchLike, PostID: 1},
+			{Op: BatchDelete, PostID: 2},
+			{Op: BatchUpdate, PostID: 1, Content: ""},
+			{Op: BatchLike, PostID: 1},
+		}, true, ErrBatchRolledBack, []bool{false, false, false, false, false}, 10, 2},
+		{"Unknown operation fails", []BatchOperation{{Op: "archive", PostID: 1}}, false, nil, []bool{false}, 10, 2},
+		{"Empty batch", []BatchOperation{}, false, ErrEmptyBatch, nil, 10, 2},
+		{"Batch too large", make([]BatchOperation, MaxBatchSize+1), false, ErrBatchTooLarge, nil, 10, 2},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			posts = []models.Post{
+				{ID: 1, Content: "Post 1", Likes: 10, Comments: []models.Comment{}},
+				{ID: 2, Content: "Post 2", Likes: 5, Comments: []models.Comment{}},
+			}
+			postIDCounter = 3
+
+			results, err := ExecuteBatch(context.Background(), testCase.operations, testCase.atomic)
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if len(results) != len(testCase.wantResults) {
+				t.Fatalf("Expected %d results, got %d", len(testCase.wantResults), len(results))
+			}
+			for i, result := range results {
+				succeeded := result.Err == nil && !result.RolledBack
+				if succeeded != testCase.wantResults[i] {
+					t.Errorf("Operation %d: expected success = %v, got result %+v", i, testCase.wantResults[i], result)
+				}
+			}
+
+			if len(posts) != testCase.wantPosts {
+				t.Errorf("Expected %d posts, got %d", testCase.wantPosts, len(posts))
+			}
+			if posts[0].Likes != testCase.wantLikes {
+				t.Errorf("Expected post 1 to have %d likes, got %d", testCase.wantLikes, posts[0].Likes)
+			}
+			if testCase.wantErr == ErrBatchRolledBack && (posts[0].Content != "Post 1" || postIDCounter != 3) {
+				t.Errorf("Expected rolled back store, got post %+v and next ID %d", posts[0], postIDCounter)
+			}
+		})
+	}
+}
+
+func TestExecuteBatchCreatesPostsOfTheCaller(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	FollowUser(ctx, bob.ID, alice.ID)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantAuthor int
+		wantErr    error
+	}{
+		{"Caller", identity.NewContext(ctx, alice.ID), alice.ID, nil},
+		{"Anonymous caller", ctx, 0, nil},
+		{"Unknown caller", identity.NewContext(ctx, 99), 0, ErrUnknownCaller},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			results, _ := ExecuteBatch(testCase.ctx, []BatchOperation{{Op: BatchCreate, Content: "Batched"}}, false)
+			if !errors.Is(results[0].Err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, results[0].Err)
+			}
+			if results[0].Post.AuthorID != testCase.wantAuthor {
+				t.Errorf("Expected author %d, got %d", testCase.wantAuthor, results[0].Post.AuthorID)
+			}
+		})
+	}
+
+	// Batch created posts reach the followers of their author
+	if timeline, _, _ := GetHomeTimeline(ctx, bob.ID, 1, 10); len(timeline) != 1 || timeline[0].AuthorID != alice.ID {
+		t.Errorf("Expected the batch created post in the timeline of the follower, got: %+v", timeline)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	posts = []models.Post{
+		{ID: 1, Content: "Cooking pasta tonight", Comments: []models.Comment{}},
+		{ID: 2, Content: "Running a marathon", Comments: []models.Comment{{ID: 1, Text: "Great pace on the run"}}},
+	}
+	postIDCounter = 3
+	RebuildIndexes(context.Background())
+
+	// Mutations through the services keep the index in sync
+	created, err := CreatePost(context.Background(), "Fresh pasta recipes")
+	if err != nil {
+		t.Fatalf("Expected no error creating the post, got: %v", err)
+	}
+	AddComment(context.Background(), 1, models.Comment{Text: "Save me some pasta"})
+	UpdatePost(context.Background(), 2, "Cycling all day")
+	DeletePost(context.Background(), 1)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		wantIDs []int
+	}{
+		{"Matches stemmed words in content", "cooked recipe", false, []int{created.ID}},
+		{"Deleted posts are removed", "tonight", false, []int{}},
+		{"Updated content replaces the old content", "marathon", false, []int{}},
+		{"Comments are searched", "running pace", false, []int{2}},
+		{"Phrase must match in order", `"pasta fresh"`, false, []int{}},
+		{"Empty query", "   ", true, nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			results, total, err := SearchPosts(context.Background(), testCase.query, 1, 10)
+
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if testCase.wantErr {
+				return
+			}
+			if total != len(testCase.wantIDs) || len(results) != len(testCase.wantIDs) {
+				t.Fatalf("Expected %d results, got %d of %d", len(testCase.wantIDs), len(results), total)
+			}
+			for i, result := range results {
+				if result.Post.ID != testCase.wantIDs[i] {
+					t.Errorf("Expected post %d at position %d, got %d", testCase.wantIDs[i], i, result.Post.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchPostsSkipsHiddenComments(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	post, _ := CreatePostWithOptions(ctx, "Weekend plans", PostOptions{AuthorID: bob.ID})
+	AddComment(identity.NewContext(ctx, carol.ID), post.ID, models.Comment{Text: "secretword from carol"})
+	AddComment(identity.NewContext(ctx, bob.ID), post.ID, models.Comment{Text: "Hiking on weekend"})
+	MuteUser(ctx, alice.ID, carol.ID)
+
+	tests := []struct {
+		name        string
+		viewerID    int
+		query       string
+		wantSnippet string // Empty when the post must not match
+	}{
+		{"Comment of a muted user", alice.ID, "secretword", ""},
+		{"Visible comment", alice.ID, "hiking", "<mark>Hiking</mark> on weekend"},
+		{"Comment visible to others", bob.ID, "secretword", "<mark>secretword</mark> from carol"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			results, total, _ := SearchPosts(identity.NewContext(ctx, testCase.viewerID), testCase.query, 1, 10)
+			if testCase.wantSnippet == "" {
+				if total != 0 {
+					t.Errorf("Expected no match, got: %+v", results)
+				}
+				return
+			}
+			if len(results) != 1 || results[0].Snippet != testCase.wantSnippet {
+				t.Errorf("Expected snippet %q, got: %+v", testCase.wantSnippet, results)
+			}
+		})
+	}
+}
+
+func TestGetPostsByTag(t *testing.T) {
+	posts = []models.Post{}
+	postIDCounter = 1
+	RebuildIndexes(context.Background())
+
+	first, _ := CreatePost(context.Background(), "Dinner #Pasta #italy")
+	second, _ := CreatePost(context.Background(), "More #pasta")
+	third, _ := CreatePost(context.Background(), "Lunch #italy")
+	UpdatePost(context.Background(), third.ID, "Lunch #pasta")
+	DeletePost(context.Background(), second.ID)
+
+	if !reflect.DeepEqual(first.Hashtags, []string{"pasta", "italy"}) {
+		t.Errorf("Expected hashtags [pasta italy], got %v", first.Hashtags)
+	}
+
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr error
+		wantIDs []int
+	}{
+		{"Case and # are ignored", "#PASTA", nil, []int{third.ID, first.ID}},
+		{"Removed by update", "italy", nil, []int{first.ID}},
+		{"Unknown tag", "unused", nil, []int{}},
+		{"Invalid tag", "not a tag", ErrInvalidTag, nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			tagged, total, err := GetPostsByTag(context.Background(), testCase.tag, 1, 10)
+
+			if err != testCase.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			ids := make([]int, 0, len(tagged))
+			for _, post := range tagged {
+				ids = append(ids, post.ID)
+			}
+			if total != len(testCase.wantIDs) || !reflect.DeepEqual(ids, testCase.wantIDs) {
+				t.Errorf("Expected posts %v, got %v of %d", testCase.wantIDs, ids, total)
+			}
+		})
+	}
+}
+
+func TestGetTrendingTags(t *testing.T) {
+	posts = []models.Post{}
+	postIDCounter = 1
+	RebuildIndexes(context.Background())
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	current := start
+	clock = func() time.Time { return current }
+	defer func() { clock = time.Now }()
+
+	// #coffee is posted twice an hour then once in the last hour, #launch only in the last hour
+	for hour := 0; hour < 6; hour++ {
+		current = start.Add(time.Duration(hour) * time.Hour)
+		CreatePost(context.Background(), "Morning #coffee")
+		if hour < 5 {
+			CreatePost(context.Background(), "Another #coffee")
+		}
+	}
+	current = start.Add(5*time.Hour + 30*time.Minute)
+	CreatePost(context.Background(), "#launch day")
+	CreatePost(context.Background(), "Watching the #launch")
+
+	trends := GetTrendingTags(context.Background(), time.Hour, 10)
+	if len(trends) != 1 || trends[0].Tag != "launch" || trends[0].Count != 2 {
+		t.Errorf("Expected only #launch trending with 2 posts, got %+v", trends)
+	}
+
+	// A day later nothing was posted in the last hour
+	current = current.Add(24 * time.Hour)
+	if trends := GetTrendingTags(context.Background(), time.Hour, 10); len(trends) != 0 {
+		t.Errorf("Expected no trending tags, got %+v", trends)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	users, usernames, userIDCounter = nil, map[string]int{}, 1
+
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{"Valid username", "alice", nil},
+		{"Taken ignoring case", "ALICE", ErrUsernameTaken},
+		{"Invalid characters", "bob smith", &ValidationError{}},
+		{"Empty username", "", &ValidationError{}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			user, err := CreateUser(context.Background(), testCase.username, "")
+
+			var validationErr *ValidationError
+			switch {
+			case testCase.wantErr == nil && err != nil:
+				t.Fatalf("Expected no error, got: %v", err)
+			case testCase.wantErr == nil && user.DisplayName != testCase.username:
+				t.Errorf("Expected display name to default to %q, got %q", testCase.username, user.DisplayName)
+			case testCase.wantErr == ErrUsernameTaken && !errors.Is(err, ErrUsernameTaken):
+				t.Errorf("Expected ErrUsernameTaken, got: %v", err)
+			case testCase.wantErr != nil && testCase.wantErr != ErrUsernameTaken && !errors.As(err, &validationErr):
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	posts, postIDCounter = []models.Post{}, 1
+	postSeqs, postSeqCounter = map[int]int{}, 0
+	users, usernames, userIDCounter = nil, map[string]int{}, 1
+	alice, _ := CreateUser(context.Background(), "alice", "Alice")
+	bob, _ := CreateUser(context.Background(), "bob", "Bob")
+	defer func() { UnknownMentions = FlagUnknownMentions }()
+
+	// Unknown usernames are flagged by default
+	post, err := CreatePost(context.Background(), "Thanks @Alice and @nobody")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := []models.Mention{
+		{Username: "Alice", UserID: alice.ID, Start: 7, End: 13},
+		{Username: "nobody", UserID: 0, Start: 18, End: 25},
+	}
+	if !reflect.DeepEqual(post.Mentions, want) {
+		t.Errorf("Expected mentions %+v, got %+v", want, post.Mentions)
+	}
+
+	other, _ := CreatePost(context.Background(), "No mentions here")
+	commented, err := AddComment(context.Background(), other.ID, models.Comment{Text: "cc @bob"})
+	if err != nil || len(commented.Comments[0].Mentions) != 1 || commented.Comments[0].Mentions[0].UserID != bob.ID {
+		t.Errorf("Expected the comment to mention bob, got %+v (error: %v)", commented.Comments, err)
+	}
+
+	// Unknown usernames fail validation when the policy rejects them
+	UnknownMentions = RejectUnknownMentions
+	if _, err := CreatePost(context.Background(), "Hello @nobody"); !errors.As(err, new(*ValidationError)) {
+		t.Errorf("Expected a validation error for an unknown mention, got: %v", err)
+	}
+	if _, err := AddComment(context.Background(), other.ID, models.Comment{Text: "@nobody"}); !errors.As(err, new(*ValidationError)) {
+		t.Errorf("Expected a validation error for an unknown mention in a comment, got: %v", err)
+	}
+	if _, err := UpdatePost(context.Background(), post.ID, "Thanks @alice"); err != nil {
+		t.Errorf("Expected known mentions to be accepted, got: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		userID  int
+		wantErr error
+		wantIDs []int
+	}{
+		{"Mentioned in content", alice.ID, nil, []int{post.ID}},
+		{"Mentioned in a comment", bob.ID, nil, []int{other.ID}},
+		{"Unknown user", 99, ErrUserNotFound, nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			mentioning, total, err := GetPostsMentioningUser(context.Background(), testCase.userID, 1, 10)
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			ids := []int{}
+			for _, p := range mentioning {
+				ids = append(ids, p.ID)
+			}
+			if testCase.wantErr == nil && (total != len(testCase.wantIDs) || !reflect.DeepEqual(ids, testCase.wantIDs)) {
+				t.Errorf("Expected posts %v, got %v of %d", testCase.wantIDs, ids, total)
+			}
+		})
+	}
+}
+
+func TestMentionsAcrossBlocks(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	BlockUser(ctx, alice.ID, bob.ID)
+	defer func() { UnknownMentions = FlagUnknownMentions }()
+
+	tests := []struct {
+		name     string
+		policy   MentionPolicy
+		authorID int
+		text     string
+		wantErr  bool
+		wantIDs  []int // Resolved user of each mention
+	}{
+		{"Blocked user mentions the blocker", FlagUnknownMentions, bob.ID, "Hey @alice and @carol", false, []int{0, carol.ID}},
+		{"Blocker mentions the blocked user", FlagUnknownMentions, alice.ID, "Hey @bob", false, []int{0}},
+		{"Rejected when the policy rejects", RejectUnknownMentions, bob.ID, "Hey @alice", true, nil},
+		{"Others are not affected", RejectUnknownMentions, carol.ID, "Hey @alice and @bob", false, []int{alice.ID, bob.ID}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			UnknownMentions = testCase.policy
+			post, err := CreatePostWithOptions(identity.NewContext(ctx, testCase.authorID), testCase.text, PostOptions{AuthorID: testCase.authorID})
+			if testCase.wantErr {
+				if !errors.As(err, new(*ValidationError)) {
+					t.Errorf("Expected a validation error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			ids := []int{}
+			for _, m := range post.Mentions {
+				ids = append(ids, m.UserID)
+			}
+			if !reflect.DeepEqual(ids, testCase.wantIDs) {
+				t.Errorf("Expected mentions of %v, got %v", testCase.wantIDs, ids)
+			}
+		})
+	}
+
+	// Comments follow the same rule, and the blocker gets no mention entry
+	UnknownMentions = FlagUnknownMentions
+	post, _ := CreatePostWithOptions(identity.NewContext(ctx, carol.ID), "Open thread", PostOptions{AuthorID: carol.ID})
+	commented, _ := AddComment(identity.NewContext(ctx, bob.ID), post.ID, models.Comment{Text: "@alice look"})
+	if mention := commented.Comments[0].Mentions[0]; mention.UserID != 0 {
+		t.Errorf("Expected the mention across the block to stay unresolved, got: %+v", mention)
+	}
+	mentioning, _, _ := GetPostsMentioningUser(ctx, alice.ID, 1, 10)
+	for _, p := range mentioning {
+		if p.ID == post.ID {
+			t.Errorf("Expected the thread not to be listed as mentioning alice, got: %+v", p)
+		}
+	}
+}
+
+// queuedUnfurler records the links to preview so the test decides when previews arrive
+type queuedUnfurler struct {
+	links []string
+	done  []func(unfurl.Preview)
+}
+
+func (q *queuedUnfurler) Enqueue(rawURL string, done func(unfurl.Preview)) bool {
+	q.links = append(q.links, rawURL)
+	q.done = append(q.done, done)
+	return true
+}
+
+func TestLinkPreviews(t *testing.T) {
+	posts, postIDCounter = []models.Post{}, 1
+	queue := &queuedUnfurler{}
+	LinkPreviews = queue
+	defer func() { LinkPreviews = nil }()
+
+	post, _ := CreatePost(context.Background(), "Read https://a.example/1 and https://b.example/2 and https://a.example/1")
+	if !reflect.DeepEqual(queue.links, []string{"https://a.example/1", "https://b.example/2"}) {
+		t.Fatalf("Expected each link to be queued once, got %v", queue.links)
+	}
+
+	// Previews arrive out of order but are kept in the order of the links
+	queue.done[1](unfurl.Preview{URL: "https://b.example/2", Title: "B"})
+	queue.done[0](unfurl.Preview{URL: "https://a.example/1", Title: "A"})
+	got, _ := GetPostDetailsByID(context.Background(), post.ID)
+	if len(got.Previews) != 2 || got.Previews[0].Title != "A" || got.Previews[1].Title != "B" {
+		t.Fatalf("Expected previews A and B, got %+v", got.Previews)
+	}
+
+	// Editing the content drops previews of removed links and only queues new links
+	UpdatePost(context.Background(), post.ID, "Only https://b.example/2 and https://c.example/3")
+	got, _ = GetPostDetailsByID(context.Background(), post.ID)
+	if len(got.Previews) != 1 || got.Previews[0].Title != "B" {
+		t.Errorf("Expected only preview B to be kept, got %+v", got.Previews)
+	}
+	if queue.links[len(queue.links)-1] != "https://c.example/3" || len(queue.links) != 3 {
+		t.Errorf("Expected only the new link to be queued, got %v", queue.links)
+	}
+
+	// A preview arriving after its link was removed is ignored
+	queue.done[0](unfurl.Preview{URL: "https://a.example/1", Title: "A"})
+	got, _ = GetPostDetailsByID(context.Background(), post.ID)
+	if len(got.Previews) != 1 {
+		t.Errorf("Expected the stale preview to be ignored, got %+v", got.Previews)
+	}
+}
+
+func TestMediaAttachments(t *testing.T) {
+	posts, postIDCounter = []models.Post{}, 1
+	mediaItems = map[string]*mediaRecord{}
+	store, err := media.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Blobs = store
+	defer func() { Blobs = nil }()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 640, 480)))
+	ctx := context.Background()
+
+	uploads := []struct {
+		name    string
+		data    []byte
+		altText string
+		wantErr error
+	}{
+		{"Not an image", []byte("#!/bin/sh\nrm -rf /"), "", ErrUnsupportedMedia},
+		{"Too large", make([]byte, MaxUploadBytes+1), "", ErrMediaTooLarge},
+		{"Alt text too long", buf.Bytes(), strings.Repeat("a", MaxAltTextLength+1), &ValidationError{}},
+		{"Valid image", buf.Bytes(), "A grey rectangle", nil},
+	}
+	var uploaded models.Attachment
+	for _, testCase := range uploads {
+		t.Run(testCase.name, func(t *testing.T) {
+			attachment, err := UploadMedia(ctx, testCase.data, testCase.altText)
+			var validationErr *ValidationError
+			if _, wantValidation := testCase.wantErr.(*ValidationError); wantValidation {
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Expected a validation error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if err == nil {
+				uploaded = attachment
+			}
+		})
+	}
+	if uploaded.ContentType != "image/png" || uploaded.Width != 640 || uploaded.Height != 480 {
+		t.Fatalf("Expected a 640x480 PNG, got %+v", uploaded)
+	}
+
+	// The thumbnail is a JPEG fitting in the thumbnail size
+	content, err := OpenMedia(ctx, uploaded.ID, true)
+	if err != nil {
+		t.Fatalf("Expected the thumbnail, got: %v", err)
+	}
+	config, format, _ := image.DecodeConfig(content.Body)
+	content.Body.Close()
+	if format != "jpeg" || config.Width != media.ThumbnailSize || config.Height != 240 {
+		t.Errorf("Expected a 320x240 JPEG thumbnail, got %s %dx%d", format, config.Width, config.Height)
+	}
+
+	creates := []struct {
+		name    string
+		refs    []AttachmentRef
+		wantErr error
+	}{
+		{"Unknown attachment", []AttachmentRef{{ID: "missing"}}, ErrMediaNotFound},
+		{"Too many attachments", make([]AttachmentRef, MaxAttachmentsPerPost+1), &ValidationError{}},
+		{"Attachment listed twice", []AttachmentRef{{ID: uploaded.ID}, {ID: uploaded.ID}}, &ValidationError{}},
+		{"Valid attachment", []AttachmentRef{{ID: uploaded.ID, AltText: "A darker rectangle"}}, nil},
+		{"Attachment already used", []AttachmentRef{{ID: uploaded.ID}}, ErrAttachmentInUse},
+	}
+	var post models.Post
+	for _, testCase := range creates {
+		t.Run(testCase.name, func(t *testing.T) {
+			created, err := CreatePostWithOptions(ctx, "Look at this", PostOptions{Attachments: testCase.refs})
+			var validationErr *ValidationError
+			if _, wantValidation := testCase.wantErr.(*ValidationError); wantValidation {
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Expected a validation error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if err == nil {
+				post = created
+			}
+		})
+	}
+
+	// Failed creations do not use up post IDs, and the alt text given with the post wins
+	if post.ID != 1 || len(post.Attachments) != 1 || post.Attachments[0].AltText != "A darker rectangle" {
+		t.Fatalf("Expected post 1 with the attachment, got %+v", post)
+	}
+
+	// Deleting the post deletes its attachments and their blobs
+	DeletePost(ctx, post.ID)
+	if _, err := OpenMedia(ctx, uploaded.ID, false); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("Expected ErrMediaNotFound after deleting the post, got: %v", err)
+	}
+	if _, err := store.Get(ctx, uploaded.ID); !errors.Is(err, media.ErrBlobNotFound) {
+		t.Errorf("Expected the blob to be deleted, got: %v", err)
+	}
+}
+
+func TestMediaFollowsItsPost(t *testing.T) {
+	resetSocialGraph()
+	mediaItems = map[string]*mediaRecord{}
+	store, err := media.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Blobs = store
+	defer func() { Blobs = nil }()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)))
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	asAlice, asBob := identity.NewContext(ctx, alice.ID), identity.NewContext(ctx, bob.ID)
+
+	// attached uploads an image as alice and attaches it to a new post of hers, or leaves it unattached for nil
+	attached := func(options *PostOptions) string {
+		attachment, err := UploadMedia(asAlice, buf.Bytes(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options != nil {
+			options.AuthorID, options.Attachments = alice.ID, []AttachmentRef{{ID: attachment.ID}}
+			if _, err := CreatePostWithOptions(asAlice, "Look", *options); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return attachment.ID
+	}
+	public := attached(&PostOptions{})
+	followers := attached(&PostOptions{Visibility: models.VisibilityFollowers})
+	private := attached(&PostOptions{Visibility: models.VisibilityPrivate})
+	draft := attached(&PostOptions{Status: models.StatusDraft})
+	expiring := attached(&PostOptions{TTL: time.Hour})
+	blocked := attached(&PostOptions{})
+	unattached := attached(nil)
+	BlockUser(ctx, alice.ID, bob.ID)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		id         string
+		wantFound  bool
+		wantPublic bool
+	}{
+		{"Public post", ctx, public, true, true},
+		{"Followers only post of someone not followed", ctx, followers, false, false},
+		{"Followers only post seen by its author", asAlice, followers, true, false},
+		{"Private post", ctx, private, false, false},
+		{"Draft", ctx, draft, false, false},
+		{"Draft seen by its author", asAlice, draft, true, false},
+		{"Post that expires later", ctx, expiring, true, false},
+		{"Post of a user who blocked the caller", asBob, blocked, false, false},
+		{"Unattached upload", asBob, unattached, false, false},
+		{"Unattached upload seen by its uploader", asAlice, unattached, true, false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, err := OpenMedia(testCase.ctx, testCase.id, false)
+			if !testCase.wantFound {
+				if !errors.Is(err, ErrMediaNotFound) {
+					t.Errorf("Expected ErrMediaNotFound, got: %v", err)
+				}
+				if _, err := GetMedia(testCase.ctx, testCase.id); !errors.Is(err, ErrMediaNotFound) {
+					t.Errorf("Expected GetMedia to fail with ErrMediaNotFound, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the attachment, got: %v", err)
+			}
+			content.Body.Close()
+			if content.Public != testCase.wantPublic {
+				t.Errorf("Expected public %v, got %v", testCase.wantPublic, content.Public)
+			}
+		})
+	}
+}
+
+// resetSocialGraph clears the posts, users, follow graph, home timelines, blocks, mutes, pins, bookmarks and notifications
+func resetSocialGraph() {
+	posts, postIDCounter = []models.Post{}, 1
+	users, usernames, userIDCounter = nil, map[string]int{}, 1
+	followingOf, followersOf, followSeq = map[int]map[int]int{}, map[int]map[int]int{}, 0
+	homeTimelines, authorPosts, pulledAuthors = map[int][]int{}, map[int][]int{}, map[int]bool{}
+	blocksOf, blockedBy, mutesOf = map[int]map[int]bool{}, map[int]map[int]bool{}, map[int]map[int]bool{}
+	pinnedPosts, bookmarksOf, bookmarkedBy = map[int][]int{}, map[int][]bookmark{}, map[int]map[int]bool{}
+	notificationsOf = map[int][]models.Notification{}
+	eventHub = events.NewHub(EventReplaySize, eventBufferSize)
+}
+
+func TestFollowUser(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+
+	tests := []struct {
+		name       string
+		followerID int
+		followeeID int
+		wantErr    error
+	}{
+		{"Follow", alice.ID, bob.ID, nil},
+		{"Follow twice", alice.ID, bob.ID, nil},
+		{"Another follower", carol.ID, bob.ID, nil},
+		{"Follow back", bob.ID, alice.ID, nil},
+		{"Unknown followee", alice.ID, 99, ErrUserNotFound},
+		{"Unknown follower", 99, bob.ID, ErrUnknownCaller},
+		{"Follow oneself", alice.ID, alice.ID, &ValidationError{}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := FollowUser(ctx, testCase.followerID, testCase.followeeID)
+			var validationErr *ValidationError
+			if _, wantValidation := testCase.wantErr.(*ValidationError); wantValidation {
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Expected a validation error, got: %v", err)
+				}
+			} else if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+		})
+	}
+
+	// Most recent follow first, with pagination
+	followers, total, _ := GetFollowers(ctx, bob.ID, 1, 1)
+	if total != 2 || len(followers) != 1 || followers[0].ID != carol.ID {
+		t.Errorf("Expected carol first of 2 followers, got %v of %d", followers, total)
+	}
+	following, total, _ := GetFollowing(ctx, alice.ID, 1, 10)
+	if total != 1 || following[0].ID != bob.ID {
+		t.Errorf("Expected alice to follow bob only, got %v", following)
+	}
+
+	UnfollowUser(ctx, alice.ID, bob.ID)
+	if _, total, _ := GetFollowers(ctx, bob.ID, 1, 10); total != 1 {
+		t.Errorf("Expected 1 follower after unfollowing, got %d", total)
+	}
+	if _, _, err := GetFollowers(ctx, 99, 1, 10); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+}
+
+func TestGetHomeTimeline(t *testing.T) {
+	defer func() { TimelineFanOutLimit = 10_000 }()
+
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"Fan-out on write", 10_000},
+		{"Fan-out on read", 0},
+		{"Author becomes large", 1},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			resetSocialGraph()
+			TimelineFanOutLimit = testCase.limit
+			ctx := context.Background()
+			alice, _ := CreateUser(ctx, "alice", "")
+			bob, _ := CreateUser(ctx, "bob", "")
+			carol, _ := CreateUser(ctx, "carol", "")
+			dave, _ := CreateUser(ctx, "dave", "")
+
+			older, _ := CreatePostWithOptions(ctx, "Bob before the follow", PostOptions{AuthorID: bob.ID})
+			FollowUser(ctx, alice.ID, bob.ID)
+			FollowUser(ctx, alice.ID, carol.ID)
+			FollowUser(ctx, dave.ID, bob.ID) // With a limit of 1, bob is fanned out on read from now on
+
+			CreatePostWithOptions(ctx, "Carol", PostOptions{AuthorID: carol.ID})
+			CreatePost(ctx, "Anonymous")
+			CreatePostWithOptions(ctx, "Dave is not followed", PostOptions{AuthorID: dave.ID})
+			newer, _ := CreatePostWithOptions(ctx, "Bob after the follow", PostOptions{AuthorID: bob.ID})
+			own, _ := CreatePostWithOptions(ctx, "Alice herself", PostOptions{AuthorID: alice.ID})
+			deleted, _ := CreatePostWithOptions(ctx, "Carol deleted", PostOptions{AuthorID: carol.ID})
+			DeletePost(identity.NewContext(ctx, carol.ID), deleted.ID)
+
+			timeline, total, err := GetHomeTimeline(ctx, alice.ID, 1, 10)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			var got []string
+			for _, post := range timeline {
+				got = append(got, post.Content)
+			}
+			want := []string{own.Content, newer.Content, "Carol", older.Content}
+			if total != len(want) || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Expected %v, got %v (total %d)", want, got, total)
+			}
+
+			// Unfollowing removes every post of the account, pushed or pulled
+			UnfollowUser(ctx, alice.ID, bob.ID)
+			timeline, _, _ = GetHomeTimeline(ctx, alice.ID, 1, 10)
+			if len(timeline) != 2 || timeline[0].ID != own.ID {
+				t.Errorf("Expected only alice's and carol's posts after unfollowing bob, got %+v", timeline)
+			}
+
+			// Pages of the timeline
+			timeline, total, _ = GetHomeTimeline(ctx, dave.ID, 2, 2)
+			if total != 3 || len(timeline) != 1 || timeline[0].ID != older.ID {
+				t.Errorf("Expected the oldest of dave's 3 posts on page 2, got %+v (total %d)", timeline, total)
+			}
+		})
+	}
+
+	if _, _, err := GetHomeTimeline(context.Background(), 99, 1, 10); !errors.Is(err, ErrUnknownCaller) {
+		t.Errorf("Expected ErrUnknownCaller, got: %v", err)
+	}
+}
+
+func TestBlockAndMute(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+	asCarol := identity.NewContext(ctx, carol.ID)
+
+	alicePost, _ := CreatePostWithOptions(ctx, "Alice", PostOptions{AuthorID: alice.ID})
+	carolPost, _ := CreatePostWithOptions(ctx, "Carol", PostOptions{AuthorID: carol.ID})
+	AddComment(asBob, carolPost.ID, models.Comment{Text: "Bob was here"})
+	AddComment(asCarol, carolPost.ID, models.Comment{Text: "Carol replies"})
+	FollowUser(ctx, bob.ID, alice.ID)
+
+	if _, err := BlockUser(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := MuteUser(ctx, carol.ID, bob.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// A blocked user cannot see, comment on or like the blocker's posts
+	if _, err := GetPostDetailsByID(asBob, alicePost.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected ErrPostNotFound for the blocked user, got: %v", err)
+	}
+	if _, err := AddComment(asBob, alicePost.ID, models.Comment{Text: "Hi"}); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the blocked user not to comment, got: %v", err)
+	}
+	if _, err := LikePost(asBob, alicePost.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the blocked user not to like, got: %v", err)
+	}
+	if results, _ := ExecuteBatch(asBob, []BatchOperation{{Op: BatchLike, PostID: alicePost.ID}}, false); !errors.Is(results[0].Err, ErrPostNotFound) {
+		t.Errorf("Expected the blocked user not to like in a batch, got: %v", results[0].Err)
+	}
+	if _, total, _ := GetFollowing(ctx, bob.ID, 1, 10); total != 0 {
+		t.Errorf("Expected blocking to remove the follow, got %d followed users", total)
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		wantPosts    []string
+		wantComments int // Comments visible on carol's post
+	}{
+		{"Anonymous sees everything", ctx, []string{"Alice", "Carol"}, 2},
+		{"Blocked user", asBob, []string{"Carol"}, 2},
+		{"Blocker", asAlice, []string{"Alice", "Carol"}, 1},
+		{"Muter does not see muted comments", asCarol, []string{"Alice", "Carol"}, 1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			var got []string
+			comments := -1
+			for _, post := range GetAllPosts(testCase.ctx) {
+				got = append(got, post.Content)
+				if post.ID == carolPost.ID {
+					comments = len(post.Comments)
+				}
+			}
+			if !reflect.DeepEqual(got, testCase.wantPosts) || comments != testCase.wantComments {
+				t.Errorf("Expected posts %v with %d comments, got %v with %d", testCase.wantPosts, testCase.wantComments, got, comments)
+			}
+		})
+	}
+
+	// Muting hides the muted user's posts from the muter's listings but the muted user can still comment
+	bobPost, _ := CreatePostWithOptions(ctx, "Bob", PostOptions{AuthorID: bob.ID})
+	for _, post := range GetAllPosts(asCarol) {
+		if post.ID == bobPost.ID {
+			t.Errorf("Expected the muted user's post to be hidden from the muter")
+		}
+	}
+	if _, err := AddComment(asBob, carolPost.ID, models.Comment{Text: "Still here"}); err != nil {
+		t.Errorf("Expected the muted user to comment unaware, got: %v", err)
+	}
+	if post, _ := GetPostDetailsByID(ctx, carolPost.ID); len(post.Comments) != 3 {
+		t.Errorf("Expected the stored post to keep every comment, got %d", len(post.Comments))
+	}
+
+	// Unblocking restores visibility
+	UnblockUser(ctx, alice.ID, bob.ID)
+	if _, err := LikePost(asBob, alicePost.ID); err != nil {
+		t.Errorf("Expected the unblocked user to like, got: %v", err)
+	}
+}
+
+func TestPostVisibility(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	follower, _ := CreateUser(ctx, "follower", "")
+	stranger, _ := CreateUser(ctx, "stranger", "")
+	FollowUser(ctx, follower.ID, alice.ID)
+	asAlice := identity.NewContext(ctx, alice.ID)
+
+	posted := map[models.Visibility]models.Post{}
+	for _, visibility := range []models.Visibility{models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityFollowers, models.VisibilityPrivate} {
+		post, err := CreatePostWithOptions(ctx, "#news "+string(visibility), PostOptions{AuthorID: alice.ID, Visibility: visibility})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		posted[visibility] = post
+	}
+
+	tests := []struct {
+		name       string
+		viewerID   int
+		visibility models.Visibility
+		wantSee    bool // By ID, and may comment on it
+		wantListed bool // In the list of all posts, search and tag pages
+	}{
+		{"Public to anonymous", 0, models.VisibilityPublic, true, true},
+		{"Unlisted to anonymous", 0, models.VisibilityUnlisted, true, false},
+		{"Unlisted to its author", alice.ID, models.VisibilityUnlisted, true, true},
+		{"Followers-only to a follower", follower.ID, models.VisibilityFollowers, true, true},
+		{"Followers-only to a stranger", stranger.ID, models.VisibilityFollowers, false, false},
+		{"Followers-only to anonymous", 0, models.VisibilityFollowers, false, false},
+		{"Private to a follower", follower.ID, models.VisibilityPrivate, false, false},
+		{"Private to its author", alice.ID, models.VisibilityPrivate, true, true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			viewerCtx := identity.NewContext(ctx, testCase.viewerID)
+			post := posted[testCase.visibility]
+
+			_, err := GetPostDetailsByID(viewerCtx, post.ID)
+			if (err == nil) != testCase.wantSee {
+				t.Errorf("Expected visible by ID: %v, got error: %v", testCase.wantSee, err)
+			}
+			_, err = AddComment(viewerCtx, post.ID, models.Comment{Text: "Nice"})
+			if (err == nil) != testCase.wantSee {
+				t.Errorf("Expected allowed to comment: %v, got error: %v", testCase.wantSee, err)
+			}
+
+			listed := false
+			for _, other := range GetAllPosts(viewerCtx) {
+				listed = listed || other.ID == post.ID
+			}
+			searched := false
+			results, _, _ := SearchPosts(viewerCtx, string(testCase.visibility), 1, 10)
+			for _, result := range results {
+				searched = searched || result.Post.ID == post.ID
+			}
+			if listed != testCase.wantListed || searched != testCase.wantListed {
+				t.Errorf("Expected listed: %v, got listed %v and found by search %v", testCase.wantListed, listed, searched)
+			}
+		})
+	}
+
+	// Only public posts count towards tags
+	if _, total, _ := GetPostsByTag(asAlice, "news", 1, 10); total != 1 {
+		t.Errorf("Expected only the public post on the tag page, got %d", total)
+	}
+
+	// Changing the visibility is reflected everywhere at once
+	if _, err := SetPostVisibility(identity.NewContext(ctx, follower.ID), posted[models.VisibilityFollowers].ID, models.VisibilityPublic); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("Expected ErrNotAuthor, got: %v", err)
+	}
+	if _, err := SetPostVisibility(asAlice, posted[models.VisibilityPrivate].ID, models.VisibilityPublic); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	SetPostVisibility(asAlice, posted[models.VisibilityPublic].ID, models.VisibilityPrivate)
+	if tagged, total, _ := GetPostsByTag(ctx, "news", 1, 10); total != 1 || tagged[0].ID != posted[models.VisibilityPrivate].ID {
+		t.Errorf("Expected only the newly public post on the tag page, got %+v", tagged)
+	}
+	if _, err := GetPostDetailsByID(ctx, posted[models.VisibilityPublic].ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the newly private post to be hidden, got: %v", err)
+	}
+	timeline, _, _ := GetHomeTimeline(ctx, follower.ID, 1, 10)
+	if len(timeline) != 3 {
+		t.Errorf("Expected the follower's timeline to hold the public, unlisted and followers-only posts, got %d posts", len(timeline))
+	}
+
+	// Anonymous posts cannot be restricted
+	if _, err := CreatePostWithOptions(ctx, "Secret", PostOptions{Visibility: models.VisibilityPrivate}); !errors.As(err, new(*ValidationError)) {
+		t.Errorf("Expected a validation error, got: %v", err)
+	}
+}
+
+func TestReposts(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	FollowUser(ctx, carol.ID, bob.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+	asCarol := identity.NewContext(ctx, carol.ID)
+
+	original, _ := CreatePostWithOptions(ctx, "Original", PostOptions{AuthorID: alice.ID})
+	private, _ := CreatePostWithOptions(ctx, "Secret", PostOptions{AuthorID: alice.ID, Visibility: models.VisibilityPrivate})
+
+	repost, err := Repost(asBob, original.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if repost.RepostOf != original.ID || repost.Original == nil || repost.Original.Content != "Original" {
+		t.Errorf("Expected the repost to embed post %d, got: %+v", original.ID, repost)
+	}
+
+	var validationErr *ValidationError
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      int
+		wantErr func(error) bool
+	}{
+		{"Twice", asBob, original.ID, func(err error) bool { return errors.Is(err, ErrAlreadyReposted) }},
+		{"Through the repost", asBob, repost.ID, func(err error) bool { return errors.Is(err, ErrAlreadyReposted) }},
+		{"Anonymous", ctx, original.ID, func(err error) bool { return errors.Is(err, ErrUnknownCaller) }},
+		{"Post the caller cannot see", asBob, private.ID, func(err error) bool { return errors.Is(err, ErrPostNotFound) }},
+		{"Private post of the caller", identity.NewContext(ctx, alice.ID), private.ID, func(err error) bool { return errors.As(err, &validationErr) }},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := Repost(testCase.ctx, testCase.id); !testCase.wantErr(err) {
+				t.Errorf("Expected a matching error, got: %v", err)
+			}
+		})
+	}
+
+	quote, err := CreatePostWithOptions(asCarol, "So true", PostOptions{AuthorID: carol.ID, QuoteOf: original.ID})
+	if err != nil || quote.Original == nil || quote.Original.ID != original.ID {
+		t.Fatalf("Expected the quote to embed post %d, got: %+v, %v", original.ID, quote, err)
+	}
+	if _, err := UpdatePost(asBob, repost.ID, "Edited"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected reposts not to be editable, got: %v", err)
+	}
+
+	details, _ := GetPostDetailsByID(ctx, original.ID)
+	if details.Reposts != 1 || details.Quotes != 1 {
+		t.Errorf("Expected 1 repost and 1 quote, got %d and %d", details.Reposts, details.Quotes)
+	}
+	timeline, _, _ := GetHomeTimeline(asCarol, carol.ID, 1, 10)
+	if len(timeline) == 0 || timeline[len(timeline)-1].ID != repost.ID {
+		t.Errorf("Expected the repost in the timeline of bob's follower, got: %+v", timeline)
+	}
+
+	// Reposts of a deleted post stay, without their original
+	DeletePost(identity.NewContext(ctx, alice.ID), original.ID)
+	details, _ = GetPostDetailsByID(ctx, quote.ID)
+	if details.Original != nil {
+		t.Errorf("Expected no original once deleted, got: %+v", details.Original)
+	}
+	if _, err := UndoRepost(asBob, original.ID); err != nil {
+		t.Errorf("Expected the repost of a deleted post to be removable, got: %v", err)
+	}
+	if _, err := UndoRepost(asBob, original.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected ErrPostNotFound once undone, got: %v", err)
+	}
+}
+
+func TestScheduledPosts(t *testing.T) {
+	resetSocialGraph()
+	current := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock = func() time.Time { return current }
+	defer func() { clock = time.Now }()
+
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	FollowUser(ctx, bob.ID, alice.ID)
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+
+	later, soon := current.Add(2*time.Hour), current.Add(time.Hour)
+	scheduledLater, _ := CreatePostWithOptions(asAlice, "Later #launch", PostOptions{AuthorID: alice.ID, PublishAt: &later})
+	scheduledSoon, _ := CreatePostWithOptions(asAlice, "Soon #launch", PostOptions{AuthorID: alice.ID, PublishAt: &soon})
+	draft, _ := CreatePostWithOptions(asAlice, "Draft #launch", PostOptions{AuthorID: alice.ID, Status: models.StatusDraft})
+	published, _ := CreatePostWithOptions(asAlice, "Now", PostOptions{AuthorID: alice.ID})
+
+	var validationErr *ValidationError
+	invalid := []struct {
+		name    string
+		options PostOptions
+	}{
+		{"Publication time in the past", PostOptions{AuthorID: alice.ID, PublishAt: &current}},
+		{"Scheduled without a time", PostOptions{AuthorID: alice.ID, Status: models.StatusScheduled}},
+		{"Draft with a time", PostOptions{AuthorID: alice.ID, Status: models.StatusDraft, PublishAt: &later}},
+		{"Anonymous draft", PostOptions{Status: models.StatusDraft}},
+	}
+	for _, testCase := range invalid {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := CreatePostWithOptions(ctx, "Invalid", testCase.options); !errors.As(err, &validationErr) {
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+
+	// Unpublished posts are only seen by their author, and listed nowhere but in their scheduled posts
+	if _, err := GetPostDetailsByID(asBob, draft.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the draft to be hidden from bob, got: %v", err)
+	}
+	if _, err := GetPostDetailsByID(asAlice, draft.ID); err != nil {
+		t.Errorf("Expected alice to see her draft, got: %v", err)
+	}
+	if listed := GetAllPosts(asAlice); len(listed) != 1 || listed[0].ID != published.ID {
+		t.Errorf("Expected only the published post in the listing, got: %+v", listed)
+	}
+	if _, total, _ := GetPostsByTag(ctx, "launch", 1, 10); total != 0 {
+		t.Errorf("Expected no unpublished post on the tag page, got %d", total)
+	}
+	scheduled, total, _ := GetScheduledPosts(asAlice, 1, 10)
+	if total != 3 || scheduled[0].ID != scheduledSoon.ID || scheduled[1].ID != scheduledLater.ID || scheduled[2].ID != draft.ID {
+		t.Errorf("Expected the soonest scheduled post first and the draft last, got: %+v", scheduled)
+	}
+	if _, err := SchedulePost(asBob, draft.ID, &later); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected bob not to find alice's draft, got: %v", err)
+	}
+	if _, err := SchedulePost(asAlice, published.ID, &later); !errors.As(err, &validationErr) {
+		t.Errorf("Expected published posts not to be scheduled, got: %v", err)
+	}
+
+	// Cancel the later post, then let the soon one come due
+	SchedulePost(asAlice, scheduledLater.ID, nil)
+	current = current.Add(3 * time.Hour)
+	RebuildIndexes(ctx)
+	due := PublishDuePosts(ctx)
+	if len(due) != 1 || due[0].ID != scheduledSoon.ID || due[0].Status != models.StatusPublished {
+		t.Fatalf("Expected the soon post to be published under its ID, got: %+v", due)
+	}
+	if again := PublishDuePosts(ctx); len(again) != 0 {
+		t.Errorf("Expected posts to be published once, got: %+v", again)
+	}
+
+	timeline, _, _ := GetHomeTimeline(asBob, bob.ID, 1, 10)
+	if len(timeline) != 2 || timeline[0].ID != due[0].ID {
+		t.Errorf("Expected the published post at the top of bob's timeline, got: %+v", timeline)
+	}
+	if _, total, _ := GetPostsByTag(ctx, "launch", 1, 10); total != 1 {
+		t.Errorf("Expected the published post on the tag page, got %d", total)
+	}
+	if _, total, _ = GetScheduledPosts(asAlice, 1, 10); total != 2 {
+		t.Errorf("Expected the cancelled post and the draft to stay unpublished, got %d", total)
+	}
+}
+
+func TestExpiringPosts(t *testing.T) {
+	resetSocialGraph()
+	current := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock = func() time.Time { return current }
+	defer func() { clock = time.Now }()
+
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	FollowUser(ctx, bob.ID, alice.ID)
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+
+	store, err := media.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Blobs = store
+	defer func() { Blobs = nil }()
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)))
+	photo, _ := UploadMedia(asAlice, buf.Bytes(), "")
+
+	story, err := CreatePostWithOptions(asAlice, "Story #today", PostOptions{AuthorID: alice.ID, TTL: time.Hour, Attachments: []AttachmentRef{{ID: photo.ID}}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if story.ExpiresAt == nil || !story.ExpiresAt.Equal(current.Add(time.Hour)) {
+		t.Errorf("Expected the story to expire in an hour, got: %v", story.ExpiresAt)
+	}
+	lasting, _ := CreatePostWithOptions(asAlice, "Lasting", PostOptions{AuthorID: alice.ID})
+	quote, _ := CreatePostWithOptions(asBob, "Look", PostOptions{AuthorID: bob.ID, QuoteOf: story.ID})
+
+	var validationErr *ValidationError
+	invalid := []struct {
+		name    string
+		options PostOptions
+	}{
+		{"Too short", PostOptions{TTL: time.Second}},
+		{"Too long", PostOptions{TTL: 8 * 24 * time.Hour}},
+		{"Draft", PostOptions{AuthorID: alice.ID, Status: models.StatusDraft, TTL: time.Hour}},
+	}
+	for _, testCase := range invalid {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := CreatePostWithOptions(ctx, "Invalid", testCase.options); !errors.As(err, &validationErr) {
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+
+	// Just before the expiry the story is there, from the expiry on it is hidden from every read
+	current = current.Add(time.Hour - time.Second)
+	if _, err := GetPostDetailsByID(asBob, story.ID); err != nil {
+		t.Errorf("Expected the story before its expiry, got: %v", err)
+	}
+	if trends := GetTrendingTags(ctx, time.Hour, 10); len(trends) != 1 || trends[0].Tag != "today" {
+		t.Errorf("Expected #today trending before the expiry, got %+v", trends)
+	}
+	current = current.Add(time.Second)
+
+	if _, err := GetPostDetailsByID(asAlice, story.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the expired story to be hidden from its author, got: %v", err)
+	}
+	if _, err := LikePost(asBob, story.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected the expired story not to be liked, got: %v", err)
+	}
+	if _, err := OpenMedia(asAlice, photo.ID, false); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("Expected the attachment of the expired story to be hidden, got: %v", err)
+	}
+	if trends := GetTrendingTags(ctx, time.Hour, 10); len(trends) != 0 {
+		t.Errorf("Expected the tags of the expired story out of trending, got %+v", trends)
+	}
+	if listed := GetAllPosts(ctx); len(listed) != 2 {
+		t.Errorf("Expected the expired story out of the listing, got: %+v", listed)
+	}
+	if results, _, _ := SearchPosts(ctx, "story", 1, 10); len(results) != 0 {
+		t.Errorf("Expected no search results for the expired story, got: %+v", results)
+	}
+	timeline, _, _ := GetHomeTimeline(asBob, bob.ID, 1, 10)
+	if len(timeline) != 2 || timeline[1].ID != lasting.ID {
+		t.Errorf("Expected the quote and the lasting post in bob's timeline, got: %+v", timeline)
+	}
+	if details, _ := GetPostDetailsByID(asBob, quote.ID); details.Original != nil {
+		t.Errorf("Expected the quote to lose the expired original, got: %+v", details.Original)
+	}
+
+	// The sweeper removes the story from the store once, along with its indexes
+	if swept := SweepExpiredPosts(ctx); len(swept) != 1 || swept[0].ID != story.ID {
+		t.Errorf("Expected the story to be swept, got: %+v", swept)
+	}
+	if swept := SweepExpiredPosts(ctx); len(swept) != 0 {
+		t.Errorf("Expected nothing left to sweep, got: %+v", swept)
+	}
+	if len(posts) != 2 {
+		t.Errorf("Expected 2 posts left in the store, got %d", len(posts))
+	}
+	if _, total, _ := GetPostsByTag(ctx, "today", 1, 10); total != 0 {
+		t.Errorf("Expected the swept story off the tag page, got %d", total)
+	}
+}
+
+func TestPolls(t *testing.T) {
+	resetSocialGraph()
+	current := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock = func() time.Time { return current }
+	defer func() { clock = time.Now }()
+
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	asBob := identity.NewContext(ctx, bob.ID)
+	asCarol := identity.NewContext(ctx, carol.ID)
+
+	closesAt := current.Add(24 * time.Hour)
+	post, err := CreatePostWithOptions(ctx, "Tabs or spaces?", PostOptions{AuthorID: alice.ID, Poll: &PollSpec{Options: []string{"Tabs", "Spaces"}, ClosesAt: closesAt}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var validationErr *ValidationError
+	invalid := []struct {
+		name string
+		spec PollSpec
+	}{
+		{"One option", PollSpec{Options: []string{"Yes"}, ClosesAt: closesAt}},
+		{"Five options", PollSpec{Options: []string{"A", "B", "C", "D", "E"}, ClosesAt: closesAt}},
+		{"Duplicate options", PollSpec{Options: []string{"Yes", " yes"}, ClosesAt: closesAt}},
+		{"Closed already", PollSpec{Options: []string{"Yes", "No"}, ClosesAt: current}},
+		{"Open too long", PollSpec{Options: []string{"Yes", "No"}, ClosesAt: current.Add(31 * 24 * time.Hour)}},
+	}
+	for _, testCase := range invalid {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := CreatePostWithOptions(ctx, "Poll", PostOptions{Poll: &testCase.spec}); !errors.As(err, &validationErr) {
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+
+	// Results are hidden until the caller votes
+	details, _ := GetPostDetailsByID(asBob, post.ID)
+	if details.Poll.ShowResults {
+		t.Errorf("Expected hidden results before voting, got: %+v", details.Poll)
+	}
+	if _, err := VotePoll(asBob, post.ID, []int{0, 1}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a single choice poll to reject two choices, got: %v", err)
+	}
+
+	// Concurrent votes of the same user count once
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := VotePoll(asBob, post.ID, []int{1}); err == nil {
+				accepted.Add(1)
+			} else if !errors.Is(err, ErrAlreadyVoted) {
+				t.Errorf("Expected ErrAlreadyVoted, got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Errorf("Expected exactly one accepted vote, got %d", accepted.Load())
+	}
+
+	details, _ = GetPostDetailsByID(asBob, post.ID)
+	if !details.Poll.ShowResults || details.Poll.Voters != 1 || details.Poll.Options[1].Votes != 1 || len(details.Poll.Voted) != 1 {
+		t.Errorf("Expected bob to see his vote in the results, got: %+v", details.Poll)
+	}
+	if details.Poll.Votes != nil {
+		t.Errorf("Expected who voted for what to stay hidden, got: %+v", details.Poll.Votes)
+	}
+	if details, _ = GetPostDetailsByID(asCarol, post.ID); details.Poll.ShowResults {
+		t.Errorf("Expected hidden results for carol, got: %+v", details.Poll)
+	}
+
+	// Once closed, everyone sees the results and nobody can vote
+	current = closesAt
+	if _, err := VotePoll(asCarol, post.ID, []int{0}); !errors.Is(err, ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed, got: %v", err)
+	}
+	if details, _ = GetPostDetailsByID(asCarol, post.ID); !details.Poll.Closed || !details.Poll.ShowResults {
+		t.Errorf("Expected the closed poll to show its results, got: %+v", details.Poll)
+	}
+	plain, _ := CreatePost(ctx, "No poll here")
+	if _, err := VotePoll(asCarol, plain.ID, []int{0}); !errors.Is(err, ErrPollNotFound) {
+		t.Errorf("Expected ErrPollNotFound, got: %v", err)
+	}
+}
+
+func TestPinsAndBookmarks(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+
+	var alicePosts []models.Post
+	for i := 0; i <= MaxPinnedPosts; i++ {
+		post, _ := CreatePostWithOptions(asAlice, fmt.Sprintf("Post %d", i), PostOptions{AuthorID: alice.ID})
+		alicePosts = append(alicePosts, post)
+	}
+
+	// Pins
+	var validationErr *ValidationError
+	for _, post := range alicePosts[:MaxPinnedPosts] {
+		if _, err := PinPost(asAlice, post.ID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if _, err := PinPost(asAlice, alicePosts[MaxPinnedPosts].ID); !errors.As(err, &validationErr) {
+		t.Errorf("Expected at most %d pinned posts, got: %v", MaxPinnedPosts, err)
+	}
+	if _, err := PinPost(asBob, alicePosts[0].ID); !errors.Is(err, ErrNotAuthor) {
+		t.Errorf("Expected ErrNotAuthor, got: %v", err)
+	}
+	pinned, _ := GetPinnedPosts(asBob, alice.ID)
+	if len(pinned) != MaxPinnedPosts || pinned[0].ID != alicePosts[MaxPinnedPosts-1].ID {
+		t.Errorf("Expected the most recently pinned post first, got: %+v", pinned)
+	}
+	SetPostVisibility(asAlice, alicePosts[0].ID, models.VisibilityPrivate)
+	if pinned, _ = GetPinnedPosts(asBob, alice.ID); len(pinned) != MaxPinnedPosts-1 {
+		t.Errorf("Expected bob not to see the private pinned post, got: %+v", pinned)
+	}
+
+	// Bookmarks, paginated with a cursor
+	for _, post := range alicePosts[1:] {
+		BookmarkPost(asBob, post.ID)
+	}
+	BookmarkPost(asBob, alicePosts[1].ID) // Already bookmarked
+
+	tests := []struct {
+		name     string
+		limit    int
+		wantIDs  [][]int
+		deleteID int
+	}{
+		{"Pages of two", 2, [][]int{{alicePosts[3].ID, alicePosts[2].ID}, {alicePosts[1].ID}}, 0},
+		{"Single page", 3, [][]int{{alicePosts[3].ID, alicePosts[2].ID, alicePosts[1].ID}}, 0},
+		{"After deleting a post", 1, [][]int{{alicePosts[3].ID}, {alicePosts[1].ID}}, alicePosts[2].ID},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.deleteID != 0 {
+				DeletePost(asAlice, testCase.deleteID)
+			}
+			cursor := ""
+			for i, wantIDs := range testCase.wantIDs {
+				page, next, err := GetBookmarks(asBob, cursor, testCase.limit)
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				var ids []int
+				for _, post := range page {
+					ids = append(ids, post.ID)
+				}
+				if !reflect.DeepEqual(ids, wantIDs) {
+					t.Errorf("Expected page %d to be %v, got %v", i+1, wantIDs, ids)
+				}
+				if last := i == len(testCase.wantIDs)-1; last != (next == "") {
+					t.Errorf("Expected a next cursor on every page but the last, got %q on page %d", next, i+1)
+				}
+				cursor = next
+			}
+		})
+	}
+
+	if _, _, err := GetBookmarks(asBob, "not-a-cursor", 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got: %v", err)
+	}
+	if len(bookmarkedBy[alicePosts[2].ID]) != 0 {
+		t.Errorf("Expected the bookmarks of the deleted post to be cleaned up, got: %v", bookmarkedBy[alicePosts[2].ID])
+	}
+	if pinned, _ = GetPinnedPosts(asAlice, alice.ID); len(pinned) != MaxPinnedPosts-1 {
+		t.Errorf("Expected the deleted post to be unpinned, got: %+v", pinned)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	dave, _ := CreateUser(ctx, "dave", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+	asCarol := identity.NewContext(ctx, carol.ID)
+	asDave := identity.NewContext(ctx, dave.ID)
+
+	post, _ := CreatePostWithOptions(asAlice, "Hello @bob", PostOptions{AuthorID: alice.ID})
+	LikePost(asAlice, post.ID) // Own activity is not notified
+	LikePost(asBob, post.ID)
+	LikePost(asCarol, post.ID)
+	LikePost(asBob, post.ID) // Moves bob first again
+	LikePost(ctx, post.ID)
+	MuteUser(ctx, alice.ID, dave.ID)
+	LikePost(asDave, post.ID) // Muted
+	AddComment(asCarol, post.ID, models.Comment{Text: "Welcome @dave"})
+	FollowUser(ctx, bob.ID, alice.ID)
+
+	notifications, total, unread, err := GetNotifications(asAlice, false, 1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if total != 3 || unread != 3 {
+		t.Fatalf("Expected 3 unread notifications, got %d of %d: %+v", unread, total, notifications)
+	}
+
+	tests := []struct {
+		name        string
+		kind        models.NotificationType
+		wantActors  []int
+		wantSummary string
+	}{
+		{"Follow", models.NotificationFollow, []int{bob.ID}, "bob followed you"},
+		{"Comment", models.NotificationComment, []int{carol.ID}, "carol commented on your post"},
+		{"Likes", models.NotificationLike, []int{bob.ID, carol.ID}, "bob and 2 others liked your post"},
+	}
+
+	for i, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			notification := notifications[i]
+			if notification.Type != testCase.kind || !reflect.DeepEqual(notification.ActorIDs, testCase.wantActors) {
+				t.Errorf("Expected a %s notification by %v, got: %+v", testCase.kind, testCase.wantActors, notification)
+			}
+			if notification.Summary != testCase.wantSummary {
+				t.Errorf("Expected summary %q, got %q", testCase.wantSummary, notification.Summary)
+			}
+		})
+	}
+
+	// Mentions reach the mentioned users, in posts and comments
+	for _, user := range []models.User{bob, dave} {
+		mentions, _, _, _ := GetNotifications(identity.NewContext(ctx, user.ID), false, 1, 10)
+		if len(mentions) != 1 || mentions[0].Type != models.NotificationMention || mentions[0].PostID != post.ID {
+			t.Errorf("Expected %s to be notified of the mention, got: %+v", user.Username, mentions)
+		}
+	}
+
+	// Reading ends the aggregation, so new activity starts a new notification
+	likes := notifications[2]
+	if _, err := MarkNotificationRead(asAlice, likes.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := MarkNotificationRead(asBob, likes.ID); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("Expected bob not to read alice's notification, got: %v", err)
+	}
+	LikePost(asCarol, post.ID)
+	if unreadOnly, _, unread, _ := GetNotifications(asAlice, true, 1, 10); len(unreadOnly) != 3 || unread != 3 || unreadOnly[0].ID == likes.ID {
+		t.Errorf("Expected a new like notification among 3 unread, got %d: %+v", unread, unreadOnly)
+	}
+	if marked := MarkAllNotificationsRead(asAlice); marked != 3 {
+		t.Errorf("Expected 3 notifications marked as read, got %d", marked)
+	}
+
+	// Notifications about deleted posts are left out
+	DeletePost(asAlice, post.ID)
+	if _, total, _, _ := GetNotifications(asAlice, false, 1, 10); total != 1 {
+		t.Errorf("Expected only the follow notification once the post is deleted, got %d", total)
+	}
+}
+
+func TestEventStreamsFollowSocialGraph(t *testing.T) {
+	resetSocialGraph()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+	FollowUser(ctx, bob.ID, alice.ID)
+
+	timeline, err := StreamTimeline(asBob, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	original, _ := CreatePostWithOptions(identity.NewContext(ctx, carol.ID), "Original", PostOptions{AuthorID: carol.ID})
+	repost, _ := Repost(asAlice, original.ID)
+	if event, _ := nextEvent(t, timeline); event.Post.ID != repost.ID || event.Post.Original == nil || event.Post.Original.ID != original.ID {
+		t.Errorf("Expected the repost with the original embedded, got: %+v", event)
+	}
+
+	// Blocks apply to open streams
+	BlockUser(ctx, alice.ID, bob.ID)
+	CreatePostWithOptions(asAlice, "Blocked", PostOptions{AuthorID: alice.ID})
+	own, _ := CreatePostWithOptions(asBob, "Mine", PostOptions{AuthorID: bob.ID})
+	if event, _ := nextEvent(t, timeline); event.Post.ID != own.ID {
+		t.Errorf("Expected the post of the blocker to be skipped, got: %+v", event)
+	}
+}
+
+func TestAddRecentActor(t *testing.T) {
+	tests := []struct {
+		name       string
+		recent     []int
+		actorID    int
+		others     int
+		wantRecent []int
+		wantOthers int
+	}{
+		{"First actor", []int{}, 1, 0, []int{1}, 0},
+		{"New actor below the cap", []int{2, 1}, 3, 0, []int{3, 2, 1}, 0},
+		{"Listed actor moves first", []int{3, 2, 1}, 2, 0, []int{2, 3, 1}, 0},
+		{"Most recent actor again", []int{3, 2, 1}, 3, 4, []int{3, 2, 1}, 4},
+		{"New actor drops the oldest", []int{3, 2, 1}, 4, 0, []int{4, 3, 2}, 1},
+		{"Dropped actors add up", []int{4, 3, 2}, 5, 1, []int{5, 4, 3}, 2},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			recent, others := addRecentActor(testCase.recent, testCase.actorID, testCase.others)
+			if !reflect.DeepEqual(recent, testCase.wantRecent) || others != testCase.wantOthers {
+				t.Errorf("Expected %v and %d others, got %v and %d others", testCase.wantRecent, testCase.wantOthers, recent, others)
+			}
+		})
+	}
+}
+
+// nextEvent waits for the next event of a stream, failing the test after a second
+func nextEvent(t *testing.T, stream <-chan PostEvent) (PostEvent, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-stream:
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event, got none")
+		return PostEvent{}, false
+	}
+}
+
+func TestEventStreams(t *testing.T) {
+	resetSocialGraph()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alice, _ := CreateUser(ctx, "alice", "")
+	bob, _ := CreateUser(ctx, "bob", "")
+	carol, _ := CreateUser(ctx, "carol", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+	asBob := identity.NewContext(ctx, bob.ID)
+	asCarol := identity.NewContext(ctx, carol.ID)
+	FollowUser(ctx, bob.ID, alice.ID)
+
+	post, _ := CreatePostWithOptions(asAlice, "Hello", PostOptions{AuthorID: alice.ID})
+	private, _ := CreatePostWithOptions(asAlice, "Secret", PostOptions{AuthorID: alice.ID, Visibility: models.VisibilityPrivate})
+	if _, err := StreamPost(asBob, private.ID, 0); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Expected ErrPostNotFound for a hidden post, got: %v", err)
+	}
+	if _, err := StreamTimeline(ctx, 0); !errors.Is(err, ErrUnknownCaller) {
+		t.Errorf("Expected ErrUnknownCaller for an anonymous timeline, got: %v", err)
+	}
+
+	postStream, err := StreamPost(asBob, post.ID, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	timeline, err := StreamTimeline(asBob, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	LikePost(asCarol, post.ID)
+	AddComment(asCarol, post.ID, models.Comment{Text: "Hi"})
+	CreatePostWithOptions(asCarol, "Not followed", PostOptions{AuthorID: carol.ID})
+	CreatePostWithOptions(asAlice, "Only me", PostOptions{AuthorID: alice.ID, Visibility: models.VisibilityPrivate})
+	followers, _ := CreatePostWithOptions(asAlice, "Followers", PostOptions{AuthorID: alice.ID, Visibility: models.VisibilityFollowers})
+	own, _ := CreatePostWithOptions(asBob, "Mine", PostOptions{AuthorID: bob.ID})
+	DeletePost(asAlice, post.ID)
+
+	tests := []struct {
+		name   string
+		stream <-chan PostEvent
+		want   []string // Event type and post ID
+	}{
+		{"Post", postStream, []string{
+			fmt.Sprint(EventPostLiked, post.ID), fmt.Sprint(EventPostCommented, post.ID), fmt.Sprint(EventPostDeleted, post.ID),
+		}},
+		{"Home timeline", timeline, []string{
+			fmt.Sprint(EventPostLiked, post.ID), fmt.Sprint(EventPostCommented, post.ID), fmt.Sprint(EventPostCreated, followers.ID),
+			fmt.Sprint(EventPostCreated, own.ID), fmt.Sprint(EventPostDeleted, post.ID),
+		}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, want := range testCase.want {
+				event, _ := nextEvent(t, testCase.stream)
+				if got := fmt.Sprint(event.Type, event.Post.ID); got != want {
+					t.Errorf("Expected event %s, got %s", want, got)
+				}
+			}
+		})
+	}
+
+	// The stream of a post ends with the post
+	if event, ok := nextEvent(t, postStream); ok {
+		t.Errorf("Expected the post stream to end, got: %+v", event)
+	}
+
+	// Resuming replays the events after the last one received, or asks to reload when they are no longer kept
+	resumed, _ := StreamTimeline(asBob, uint64(1))
+	if event, _ := nextEvent(t, resumed); event.Type != EventPostLiked || event.Post.Likes != 1 {
+		t.Errorf("Expected the like to be replayed with the post as it was, got: %+v", event)
+	}
+	stale, _ := StreamTimeline(asBob, 1_000_000)
+	if event, _ := nextEvent(t, stale); event.Type != EventResync || event.ID == 0 {
+		t.Errorf("Expected a resync event resuming after the latest event, got: %+v", event)
+	}
+
+	// Streams end with their context
+	cancel()
+	if _, ok := nextEvent(t, timeline); ok {
+		t.Error("Expected the timeline stream to end with its context")
+	}
+}
+
+func TestFindSequencedPostIndex(t *testing.T) {
+	resetSocialGraph()
+	ctx := context.Background()
+	alice, _ := CreateUser(ctx, "alice", "")
+	asAlice := identity.NewContext(ctx, alice.ID)
+
+	draft, _ := CreatePostWithOptions(asAlice, "Draft", PostOptions{AuthorID: alice.ID, Status: models.StatusDraft})
+	first, _ := CreatePostWithOptions(asAlice, "First", PostOptions{AuthorID: alice.ID})
+	deleted, _ := CreatePostWithOptions(asAlice, "Deleted", PostOptions{AuthorID: alice.ID})
+	last, _ := CreatePostWithOptions(asAlice, "Last", PostOptions{AuthorID: alice.ID})
+	PublishPost(asAlice, draft.ID)
+	DeletePost(asAlice, deleted.ID)
+
+	tests := []struct {
+		name      string
+		id        int
+		wantIndex int
+	}{
+		{"Oldest post", first.ID, 0},
+		{"Post created after a deleted one", last.ID, 1},
+		{"Draft published last", draft.ID, 2},
+		{"Deleted post", deleted.ID, -1},
+		{"Unknown post", 99, -1},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := findSequencedPostIndex(ctx, testCase.id); got != testCase.wantIndex {
+				t.Errorf("Expected index %d, got %d", testCase.wantIndex, got)
+			}
+		})
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name  string
+		page  int
+		limit int
+		want  []int
+	}{
+		{"First page", 1, 2, []int{1, 2}},
+		{"Last partial page", 3, 2, []int{5}},
+		{"Past the last page", 4, 2, []int{}},
+		{"Limit larger than the items", 1, math.MaxInt, items},
+		{"Page whose start overflows", math.MaxInt, 2, []int{}},
+		{"Page and limit whose product overflows", math.MaxInt / 2, 3, []int{}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := pageOf(items, testCase.page, testCase.limit); fmt.Sprint(got) != fmt.Sprint(testCase.want) {
+				t.Errorf("Expected %v, got %v", testCase.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"mini-social-media-api/richtext"
+	"mini-social-media-api/unfurl"
+)
+
+// maxPreviewsPerPost limits how many links of a post are previewed
+const maxPreviewsPerPost = 3
+
+// LinkUnfurler builds link previews in the background; implemented by *unfurl.Unfurler
+type LinkUnfurler interface {
+	Enqueue(rawURL string, done func(unfurl.Preview)) bool
+}
+
+// LinkPreviews fetches the previews of links in posts; nil disables link previews
+var LinkPreviews LinkUnfurler
+
+// postLinks returns the distinct links of a text that get a preview, in order of appearance
+func postLinks(content string) []string {
+	var links []string
+	seen := map[string]bool{}
+	for _, e := range richtext.Extract(content) {
+		if e.Type != richtext.URL || seen[e.Value] {
+			continue
+		}
+		seen[e.Value] = true
+		links = append(links, e.Value)
+		if len(links) == maxPreviewsPerPost {
+			break
+		}
+	}
+	return links
+}
+
+// keepPreviews returns the previews of the given links
+func keepPreviews(previews []models.LinkPreview, links []string) []models.LinkPreview {
+	kept := []models.LinkPreview{}
+	for _, preview := range previews {
+		if containsString(links, preview.URL) {
+			kept = append(kept, preview)
+		}
+	}
+	return kept
+}
+
+// enqueuePreviews schedules the previews of the links of a post that do not have one yet
+// Never blocks, so it is safe to call while holding the post mutex
+func enqueuePreviews(ctx context.Context, post models.Post) {
+	if LinkPreviews == nil {
+		return
+	}
+	for _, link := range postLinks(post.Content) {
+		if hasPreview(post, link) {
+			continue
+		}
+		postID := post.ID
+		if !LinkPreviews.Enqueue(link, func(preview unfurl.Preview) { attachPreview(postID, preview) }) {
+			logging.FromContext(ctx).WithField(logging.FieldPostID, postID).Warnln("Link preview queue is full, skipping " + link)
+		}
+	}
+}
+
+// attachPreview adds a fetched preview to a post, unless the post was deleted or no longer contains the link
+func attachPreview(postID int, preview unfurl.Preview) {
+	ctx, span := tracer.Start(context.Background(), "services.attachPreview")
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i := findPostIndex(ctx, postID)
+	if i < 0 || !containsString(postLinks(posts[i].Content), preview.URL) || hasPreview(posts[i], preview.URL) {
+		return
+	}
+
+	// Build a new slice, in the order of the links, so that posts previously returned to callers are not modified
+	attached := models.LinkPreview{
+		URL:         preview.URL,
+		Title:       preview.Title,
+		Description: preview.Description,
+		Image:       preview.Image,
+		SiteName:    preview.SiteName,
+	}
+	previews := make([]models.LinkPreview, 0, len(posts[i].Previews)+1)
+	for _, link := range postLinks(posts[i].Content) {
+		if link == attached.URL {
+			previews = append(previews, attached)
+		}
+		for _, existing := range posts[i].Previews {
+			if existing.URL == link {
+				previews = append(previews, existing)
+			}
+		}
+	}
+	posts[i].Previews = previews
+}
+
+func hasPreview(post models.Post, link string) bool {
+	for _, preview := range post.Previews {
+		if preview.URL == link {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repost reshares a post as the calling user; reposts appear in the home timelines of the user's followers.
+// Reposting a repost reshares the original post. Each user can repost a post once.
+// Returns the repost, with the original embedded, or an error if the caller is unknown, the post cannot be seen
+// or shared, or the caller already reposted it.
+func Repost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.Repost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return models.Post{}, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i, err := findShareablePostLocked(ctx, id)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	if findRepostIndex(userID, posts[i].ID) >= 0 {
+		return models.Post{}, recordError(span, ErrAlreadyReposted)
+	}
+
+	// A repost is a post without content of its own, as visible as the original
+	posts[i].Reposts++
+	repost := createPostLocked(ctx, "", models.Post{AuthorID: userID, Visibility: posts[i].Visibility, RepostOf: posts[i].ID})
+	span.SetAttributes(attribute.Int("repost.id", repost.ID))
+
+	commitChanges(ctx, change{postCreated, repost})
+	return newViewerFilter(ctx).present(repost), nil
+}
+
+// UndoRepost deletes the repost of a post by the calling user.
+// Returns the deleted repost, or ErrPostNotFound if the caller did not repost the post.
+func UndoRepost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.UndoRepost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Accept the ID of a repost too, like Repost does
+	originalID := id
+	if i := findPostIndex(ctx, id); i >= 0 && posts[i].RepostOf != 0 {
+		originalID = posts[i].RepostOf
+	}
+	i := findRepostIndex(identity.FromContext(ctx), originalID)
+	if i < 0 {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+
+	repost, err := deletePostLocked(ctx, posts[i].ID)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	commitChanges(ctx, change{postDeleted, repost})
+	return repost, nil
+}
+
+// findShareablePostLocked returns the index of the post to repost or quote, following a repost to its original
+// Only public and unlisted posts can be shared, so sharing never widens the audience of a post
+// The caller must hold the post mutex
+func findShareablePostLocked(ctx context.Context, id int) (int, error) {
+	i := findVisiblePostIndex(ctx, id)
+	if i >= 0 && posts[i].RepostOf != 0 {
+		i = findVisiblePostIndex(ctx, posts[i].RepostOf)
+	}
+	if i < 0 {
+		return -1, ErrPostNotFound
+	}
+	if !posts[i].Status.Published() {
+		return -1, errNotPublished("share_post")
+	}
+
+	switch posts[i].Visibility {
+	case models.VisibilityPublic, models.VisibilityUnlisted:
+		return i, nil
+	default:
+		return -1, rejectValidation("share_post", errors.New("only public and unlisted posts can be reposted or quoted"))
+	}
+}
+
+// errRepostNotEditable is returned when editing a repost, which has no content of its own
+func errRepostNotEditable(operation string) error {
+	return rejectValidation(operation, errors.New("reposts have no content to edit"))
+}
+
+// findRepostIndex returns the index of the repost of a post by a user, or -1 if the user has not reposted it
+// The caller must hold the post mutex
+func findRepostIndex(userID, originalID int) int {
+	if userID == 0 {
+		return -1
+	}
+	for i, post := range posts {
+		if post.RepostOf == originalID && post.AuthorID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// releaseShareLocked decrements the repost or quote count of the post shared by a deleted post
+// Unpublished posts are not counted yet
+// The caller must hold the post mutex
+func releaseShareLocked(ctx context.Context, post models.Post) {
+	if (post.RepostOf == 0 && post.QuoteOf == 0) || !post.Status.Published() {
+		return
+	}
+	i := findPostIndex(ctx, post.RepostOf+post.QuoteOf) // At most one of them is set
+	if i < 0 {
+		return
+	}
+	if post.RepostOf != 0 {
+		posts[i].Reposts--
+	} else {
+		posts[i].Quotes--
+	}
+}
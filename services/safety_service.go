@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mini-social-media-api/models"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Blocks are stored in both directions so the users who blocked a viewer are found without a scan
+var blocksOf = map[int]map[int]bool{}  // Blocker ID to the users they blocked
+var blockedBy = map[int]map[int]bool{} // User ID to the users who blocked them
+var mutesOf = map[int]map[int]bool{}   // Muter ID to the users they muted
+var safetyMutex = &sync.RWMutex{}      // Guards blocks and mutes; may be acquired while holding the post mutex
+
+// BlockUser blocks a user: neither user sees the other's posts and comments, and the blocked user
+// can no longer comment on or like the blocker's posts. Existing follows between them are removed.
+// Returns the blocked user, ErrUnknownCaller if the blocker does not exist, or ErrUserNotFound.
+func BlockUser(ctx context.Context, blockerID, blockedID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.BlockUser", trace.WithAttributes(attribute.Int("user.id", blockerID), attribute.Int("blocked.id", blockedID)))
+	defer span.End()
+
+	if blockerID == blockedID {
+		return models.User{}, recordError(span, rejectValidation("block_user", errors.New("users cannot block themselves")))
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	blocked, err := lookupFollowPair(ctx, blockerID, blockedID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	// A blocked user must not keep receiving the blocker's posts, nor the other way round
+	followMutex.Lock()
+	for _, pair := range [][2]int{{blockerID, blockedID}, {blockedID, blockerID}} {
+		delete(followingOf[pair[0]], pair[1])
+		delete(followersOf[pair[1]], pair[0])
+	}
+	followMutex.Unlock()
+	purgeTimelineLocked(blockerID, blockedID)
+	purgeTimelineLocked(blockedID, blockerID)
+
+	safetyMutex.Lock()
+	addRelation(blocksOf, blockerID, blockedID)
+	addRelation(blockedBy, blockedID, blockerID)
+	safetyMutex.Unlock()
+	socialGraphChanged()
+	return blocked, nil
+}
+
+// UnblockUser removes a block; unblocking a user that is not blocked is not an error. Follows are not restored.
+// Returns the unblocked user, ErrUnknownCaller if the blocker does not exist, or ErrUserNotFound.
+func UnblockUser(ctx context.Context, blockerID, blockedID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.UnblockUser", trace.WithAttributes(attribute.Int("user.id", blockerID), attribute.Int("blocked.id", blockedID)))
+	defer span.End()
+
+	blocked, err := lookupFollowPair(ctx, blockerID, blockedID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	safetyMutex.Lock()
+	delete(blocksOf[blockerID], blockedID)
+	delete(blockedBy[blockedID], blockerID)
+	safetyMutex.Unlock()
+	socialGraphChanged()
+	return blocked, nil
+}
+
+// MuteUser hides the posts and comments of a user from the listings of the muter.
+// The muted user is not told and can still interact with the muter's posts.
+// Returns the muted user, ErrUnknownCaller if the muter does not exist, or ErrUserNotFound.
+func MuteUser(ctx context.Context, muterID, mutedID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.MuteUser", trace.WithAttributes(attribute.Int("user.id", muterID), attribute.Int("muted.id", mutedID)))
+	defer span.End()
+
+	if muterID == mutedID {
+		return models.User{}, recordError(span, rejectValidation("mute_user", errors.New("users cannot mute themselves")))
+	}
+	muted, err := lookupFollowPair(ctx, muterID, mutedID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	safetyMutex.Lock()
+	addRelation(mutesOf, muterID, mutedID)
+	safetyMutex.Unlock()
+	socialGraphChanged()
+	return muted, nil
+}
+
+// UnmuteUser removes a mute; unmuting a user that is not muted is not an error.
+// Returns the unmuted user, ErrUnknownCaller if the muter does not exist, or ErrUserNotFound.
+func UnmuteUser(ctx context.Context, muterID, mutedID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "services.UnmuteUser", trace.WithAttributes(attribute.Int("user.id", muterID), attribute.Int("muted.id", mutedID)))
+	defer span.End()
+
+	muted, err := lookupFollowPair(ctx, muterID, mutedID)
+	if err != nil {
+		return models.User{}, recordError(span, err)
+	}
+
+	safetyMutex.Lock()
+	delete(mutesOf[muterID], mutedID)
+	safetyMutex.Unlock()
+	socialGraphChanged()
+	return muted, nil
+}
+
+// addRelation adds a user to a relation set of another user
+// The caller must hold the safety mutex
+func addRelation(relations map[int]map[int]bool, from, to int) {
+	if relations[from] == nil {
+		relations[from] = map[int]bool{}
+	}
+	relations[from][to] = true
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/logging"
+	"mini-social-media-api/models"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SchedulerInterval is how often the scheduler looks for scheduled posts that are due
+const SchedulerInterval = time.Second
+
+// GetScheduledPosts retrieves the drafts and scheduled posts of the calling user.
+// Scheduled posts come first, the next to be published first, followed by drafts, the newest first.
+// Returns the requested page of posts, the total number of unpublished posts, or ErrUnknownCaller.
+func GetScheduledPosts(ctx context.Context, page, limit int) ([]models.Post, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetScheduledPosts")
+	defer span.End()
+
+	userID := identity.FromContext(ctx)
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, 0, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := filterFor(userID)
+	var unpublished []models.Post
+	for _, post := range listPosts(ctx) {
+		if !post.Status.Published() && post.AuthorID == userID {
+			unpublished = append(unpublished, filter.present(post))
+		}
+	}
+	sort.SliceStable(unpublished, func(i, j int) bool {
+		a, b := unpublished[i], unpublished[j]
+		if (a.PublishAt == nil) != (b.PublishAt == nil) {
+			return a.PublishAt != nil
+		}
+		if a.PublishAt != nil && !a.PublishAt.Equal(*b.PublishAt) {
+			return a.PublishAt.Before(*b.PublishAt)
+		}
+		return a.ID > b.ID
+	})
+
+	return pageOf(unpublished, page, limit), len(unpublished), nil
+}
+
+// SchedulePost sets when an unpublished post of the calling user is published; nil turns it back into a draft.
+// Returns the updated post, ErrPostNotFound if the caller cannot see the post, ErrNotAuthor, or a validation error
+// if the post is already published or the time is not in the future.
+func SchedulePost(ctx context.Context, id int, publishAt *time.Time) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.SchedulePost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i, err := findUnpublishedPostIndex(ctx, "schedule_post", id)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+	status := models.StatusDraft
+	if publishAt != nil {
+		status = models.StatusScheduled
+	}
+	if _, err := validateSchedule("schedule_post", status, publishAt, posts[i].AuthorID); err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+
+	posts[i].Status = status
+	posts[i].PublishAt = publishAt
+	posts[i].UpdatedAt = time.Now()
+	commitChanges(ctx, change{postUpdated, posts[i]})
+	return newViewerFilter(ctx).present(posts[i]), nil
+}
+
+// PublishPost publishes a draft or scheduled post of the calling user at once.
+// The post keeps its ID and appears at the top of home timelines, which are ordered by publication.
+// Returns the published post, ErrPostNotFound if the caller cannot see the post, ErrNotAuthor,
+// or a validation error if the post is already published.
+func PublishPost(ctx context.Context, id int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.PublishPost", trace.WithAttributes(attribute.Int("post.id", id)))
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	i, err := findUnpublishedPostIndex(ctx, "publish_post", id)
+	if err != nil {
+		return models.Post{}, recordError(span, err)
+	}
+
+	post := publishLocked(ctx, i)
+	span.SetAttributes(attribute.Int("post.published_id", post.ID))
+	return newViewerFilter(ctx).present(post), nil
+}
+
+// PublishDuePosts publishes the scheduled posts whose time has come, in the order they were scheduled for.
+// Returns the published posts.
+func PublishDuePosts(ctx context.Context) []models.Post {
+	ctx, span := tracer.Start(ctx, "services.PublishDuePosts")
+	defer span.End()
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Collect the due posts first, since publishing moves a post to the end of the store
+	var due []models.Post
+	current := clock()
+	for _, post := range listPosts(ctx) {
+		if post.Status == models.StatusScheduled && post.PublishAt != nil && !post.PublishAt.After(current) {
+			due = append(due, post)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].PublishAt.Before(*due[j].PublishAt) })
+
+	published := make([]models.Post, 0, len(due))
+	for _, post := range due {
+		published = append(published, publishLocked(ctx, findPostIndex(ctx, post.ID)))
+	}
+	span.SetAttributes(attribute.Int("post.count", len(published)))
+	return published
+}
+
+// RunScheduler publishes due scheduled posts at once and then every interval until ctx is done.
+// The scheduled posts are read from the store on every run, so no timers have to be kept per post
+// and posts that became due while the scheduler was not running are published on its first run.
+func RunScheduler(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, func() {
+		for _, post := range PublishDuePosts(ctx) {
+			logging.FromContext(ctx).WithField(logging.FieldPostID, post.ID).Infoln("Published scheduled post")
+		}
+	})
+}
+
+// runEvery runs a background job at once and then every interval until ctx is done
+func runEvery(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishLocked publishes the unpublished post at index i and runs the side effects of a new post
+// The post keeps its ID, so links, bookmarks and streams of the post keep working, and moves to the end of the store,
+// which orders timelines by publication
+// The caller must hold the post mutex
+func publishLocked(ctx context.Context, i int) models.Post {
+	posts[i].Status = models.StatusPublished
+	posts[i].PublishAt = nil
+	post := movePostToEnd(ctx, i)
+
+	if post.QuoteOf != 0 {
+		if j := findPostIndex(ctx, post.QuoteOf); j >= 0 {
+			posts[j].Quotes++
+		}
+	}
+
+	commitChanges(ctx, change{postCreated, post})
+	return post
+}
+
+// findUnpublishedPostIndex returns the index of an unpublished post of the calling user
+// The caller must hold the post mutex
+func findUnpublishedPostIndex(ctx context.Context, operation string, id int) (int, error) {
+	i := findVisiblePostIndex(ctx, id)
+	if i < 0 {
+		return -1, ErrPostNotFound
+	}
+	if posts[i].AuthorID == 0 || posts[i].AuthorID != identity.FromContext(ctx) {
+		return -1, ErrNotAuthor
+	}
+	if posts[i].Status.Published() {
+		return -1, rejectValidation(operation, errors.New("post is already published"))
+	}
+	return i, nil
+}
+
+// validateSchedule checks the status and publication time of a post and returns its resolved status
+// A publication time schedules the post; only posts with an author can be kept unpublished
+func validateSchedule(operation string, status models.PostStatus, publishAt *time.Time, authorID int) (models.PostStatus, error) {
+	if status == "" {
+		status = models.StatusPublished
+		if publishAt != nil {
+			status = models.StatusScheduled
+		}
+	}
+
+	switch status {
+	case models.StatusPublished:
+		if publishAt != nil {
+			return "", rejectValidation(operation, errors.New("published posts cannot have a publication time"))
+		}
+		return status, nil
+	case models.StatusDraft:
+		if publishAt != nil {
+			return "", rejectValidation(operation, errors.New("drafts cannot have a publication time"))
+		}
+	case models.StatusScheduled:
+		if publishAt == nil {
+			return "", rejectValidation(operation, errors.New("scheduled posts need a publication time"))
+		}
+		if !publishAt.After(clock()) {
+			return "", rejectValidation(operation, errors.New("publication time must be in the future"))
+		}
+	default:
+		return "", rejectValidation(operation, errors.New("status must be published, draft or scheduled"))
+	}
+
+	if authorID == 0 {
+		return "", rejectValidation(operation, errors.New("anonymous posts cannot be drafts or scheduled"))
+	}
+	return status, nil
+}
+
+// errNotPublished is returned when interacting with a post that its author has not published yet
+func errNotPublished(operation string) error {
+	return rejectValidation(operation, errors.New("post is not published yet"))
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/models"
+	"mini-social-media-api/search"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// searchIndex holds the full-text index of posts and their comments
+var searchIndex = search.NewIndex()
+
+// SearchResult is a post matching a search query
+type SearchResult struct {
+	Post    models.Post
+	Score   float64
+	Snippet string // HTML escaped text with matches wrapped in <mark>
+}
+
+// searchDocument builds the index document of a post: its content followed by its comments
+func searchDocument(post models.Post) search.Document {
+	fields := make([]string, 0, len(post.Comments)+1)
+	fields = append(fields, post.Content)
+	for _, comment := range post.Comments {
+		fields = append(fields, comment.Text)
+	}
+	return search.Document{ID: post.ID, Fields: fields}
+}
+
+// rebuildSearchIndexLocked indexes every post in the store from scratch
+// The caller must hold the post mutex
+func rebuildSearchIndexLocked(ctx context.Context) {
+	stored := listPosts(ctx)
+	docs := make([]search.Document, 0, len(stored))
+	for _, post := range stored {
+		if post.Status.Published() {
+			docs = append(docs, searchDocument(post))
+		}
+	}
+	searchIndex.Rebuild(docs)
+}
+
+// SearchPosts finds the posts whose content or comments match the query, best match first.
+// Supports plain words, matched after stemming, and "quoted phrases".
+// Returns the requested page of results, the total number of matches, or an error if the query is empty.
+func SearchPosts(ctx context.Context, query string, page, limit int) ([]SearchResult, int, error) {
+	ctx, span := tracer.Start(ctx, "services.SearchPosts", trace.WithAttributes(attribute.String("search.query", query)))
+	defer span.End()
+
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, recordError(span, ErrEmptySearchQuery)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Comments of users the caller blocked or muted neither match nor show in snippets
+	filter := newViewerFilter(ctx)
+	hits := searchIndex.SearchVisible(query, func(id, field int) bool {
+		if field == 0 {
+			return true // The content of the post
+		}
+		post, ok := filter.lookup.find(id)
+		if !ok || field > len(post.Comments) {
+			return false
+		}
+		author := post.Comments[field-1].AuthorID
+		return !filter.blocked[author] && !filter.muted[author]
+	})
+	span.SetAttributes(attribute.Int("search.hits", len(hits)))
+
+	// Leave out the posts hidden from the calling user by their visibility, blocks and mutes
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	listed := map[int]bool{}
+	for _, id := range filter.listedIDs(ctx, ids) {
+		listed[id] = true
+	}
+	kept := hits[:0:0]
+	for _, hit := range hits {
+		if listed[hit.ID] {
+			kept = append(kept, hit)
+		}
+	}
+	hits = kept
+
+	paged := pageOf(hits, page, limit)
+	results := make([]SearchResult, 0, len(paged))
+	for _, hit := range paged {
+		i := findPostIndex(ctx, hit.ID)
+		if i < 0 {
+			continue // Index and store are updated together, so this only happens if the store was replaced directly
+		}
+		results = append(results, SearchResult{Post: filter.present(posts[i]), Score: hit.Score, Snippet: hit.Snippet})
+	}
+	return results, len(hits), nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/metrics"
+	"mini-social-media-api/models"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var posts []models.Post       // In-memory storage for all posts
+var postMutex = &sync.Mutex{} // Mutex to ensure safe concurrent access to the posts slice
+var postIDCounter = 1         // Counter for generating unique post IDs
+var postSeqs = map[int]int{}  // Post ID to its position in publication order, which is the order of the store
+var postSeqCounter = 0        // Last position given in publication order
+var now = time.Now().Local()  // Current local time
+var clock = time.Now          // Source of the current time for time based features, replaced in tests
+
+var tracer = otel.Tracer("mini-social-media-api/services")
+
+// lockPosts acquires the post mutex and records how long the caller waited for it
+func lockPosts() {
+	start := time.Now()
+	postMutex.Lock()
+	metrics.StoreLockWaitSeconds.Observe(time.Since(start).Seconds())
+}
+
+// findPostIndex returns the index of the post with the given ID in the store, or -1 if it does not exist
+// The caller must hold the post mutex
+func findPostIndex(ctx context.Context, id int) int {
+	_, span := tracer.Start(ctx, "store.findPost")
+	defer span.End()
+	span.SetAttributes(attribute.Int("post.id", id))
+
+	for i, post := range posts {
+		if post.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertPost assigns the next ID to the post and appends it to the store
+// The caller must hold the post mutex
+func insertPost(ctx context.Context, post models.Post) models.Post {
+	_, span := tracer.Start(ctx, "store.insertPost")
+	defer span.End()
+
+	post.ID = postIDCounter
+	postIDCounter++             // Increment the counter for the next postID
+	posts = append(posts, post) // Add the new post to the in-memory slice
+	sequencePost(post.ID)
+	span.SetAttributes(attribute.Int("post.id", post.ID))
+
+	metrics.PostsTotal.Set(float64(len(posts)))
+	return post
+}
+
+// movePostToEnd moves the post at index i to the end of the store and returns it, keeping its ID
+// The store lists posts in the order they were published, so a post is moved once it is published
+// A new slice is built so that slices previously returned by listPosts are not modified
+// The caller must hold the post mutex
+func movePostToEnd(ctx context.Context, i int) models.Post {
+	_, span := tracer.Start(ctx, "store.movePostToEnd")
+	defer span.End()
+
+	moved := posts[i]
+	span.SetAttributes(attribute.Int("post.id", moved.ID))
+	posts = append(append(posts[:i:i], posts[i+1:]...), moved)
+	sequencePost(moved.ID)
+	return moved
+}
+
+// sequencePost gives a post the next position in publication order, after every post already in the store
+// Positions are given when a post is created and again when it is published, so they follow the order of the store
+// The caller must hold the post mutex
+func sequencePost(id int) {
+	postSeqCounter++
+	postSeqs[id] = postSeqCounter
+}
+
+// seqOf returns the position of a post in publication order, or 0 for deleted posts so they sort as the oldest
+// The caller must hold the post mutex
+func seqOf(id int) int {
+	return postSeqs[id]
+}
+
+// findSequencedPostIndex returns the index of a post by a binary search on its position in publication order,
+// or -1 for posts without a position such as deleted posts
+// Falls back to a scan if the store no longer follows the positions, e.g. after a rolled back batch
+// The caller must hold the post mutex
+func findSequencedPostIndex(ctx context.Context, id int) int {
+	seq, ok := postSeqs[id]
+	if !ok {
+		return -1
+	}
+	i := sort.Search(len(posts), func(i int) bool { return postSeqs[posts[i].ID] >= seq })
+	if i < len(posts) && posts[i].ID == id {
+		return i
+	}
+	return findPostIndex(ctx, id)
+}
+
+// listPosts returns all posts in the store
+// The caller must hold the post mutex
+func listPosts(ctx context.Context) []models.Post {
+	_, span := tracer.Start(ctx, "store.listPosts")
+	defer span.End()
+	span.SetAttributes(attribute.Int("post.count", len(posts)))
+
+	return posts
+}
+
+// removePost deletes the post at index i from the store and returns it
+// A new slice is built so that slices previously returned by listPosts are not modified
+// The caller must hold the post mutex
+func removePost(ctx context.Context, i int) models.Post {
+	_, span := tracer.Start(ctx, "store.removePost")
+	defer span.End()
+
+	removed := posts[i]
+	span.SetAttributes(attribute.Int("post.id", removed.ID))
+	posts = append(posts[:i:i], posts[i+1:]...)
+
+	metrics.PostsTotal.Set(float64(len(posts)))
+	return removed
+}
+
+// storeSnapshot is a copy of the store used to roll back a failed transaction
+type storeSnapshot struct {
+	posts         []models.Post
+	postIDCounter int
+}
+
+// takeSnapshot copies the posts and their comments so that later in-place updates do not affect the snapshot
+// The caller must hold the post mutex
+func takeSnapshot() storeSnapshot {
+	copied := make([]models.Post, len(posts))
+	for i, post := range posts {
+		post.Comments = append([]models.Comment(nil), post.Comments...)
+		copied[i] = post
+	}
+	return storeSnapshot{posts: copied, postIDCounter: postIDCounter}
+}
+
+// restore replaces the store with the snapshot
+// The caller must hold the post mutex
+func (s storeSnapshot) restore() {
+	posts = s.posts
+	postIDCounter = s.postIDCounter
+	metrics.PostsTotal.Set(float64(len(posts)))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/hashtag"
+	"mini-social-media-api/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tagIndex maps hashtags to the posts using them, for tag pages and trending tags
+var tagIndex = hashtag.NewIndex()
+
+// TrendingWindows are the sliding windows trending tags can be computed over
+var TrendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+}
+
+// rebuildTagIndexLocked indexes the hashtags of every public post, dated by the creation time of the post
+// The caller must hold the post mutex
+func rebuildTagIndexLocked(ctx context.Context) {
+	tagIndex.Reset()
+	for _, post := range listPosts(ctx) {
+		if post.Visibility == models.VisibilityPublic && post.Status.Published() {
+			tagIndex.Set(post.ID, post.Hashtags, post.CreatedAt)
+			expireTagsAt(post)
+		}
+	}
+}
+
+// expireTagsAt stops the tags of an expiring post from trending once it expires, as reads hide it before it is swept
+func expireTagsAt(post models.Post) {
+	if post.ExpiresAt != nil {
+		tagIndex.Expire(post.ID, *post.ExpiresAt)
+	}
+}
+
+// GetPostsByTag retrieves the posts using a hashtag, most recently tagged first.
+// The tag may be given with or without its leading #, in any case.
+// Returns the requested page of posts, the total number of tagged posts, or ErrInvalidTag.
+func GetPostsByTag(ctx context.Context, tag string, page, limit int) ([]models.Post, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetPostsByTag", trace.WithAttributes(attribute.String("hashtag", tag)))
+	defer span.End()
+
+	normalized, ok := hashtag.Normalize(tag)
+	if !ok {
+		return nil, 0, recordError(span, ErrInvalidTag)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := newViewerFilter(ctx)
+	ids := filter.listedIDs(ctx, tagIndex.Posts(normalized))
+	paged := pageOf(ids, page, limit)
+	tagged := make([]models.Post, 0, len(paged))
+	for _, id := range paged {
+		if i := findPostIndex(ctx, id); i >= 0 {
+			tagged = append(tagged, filter.present(posts[i]))
+		}
+	}
+	return tagged, len(ids), nil
+}
+
+// GetTrendingTags returns up to limit hashtags used more than usual in the window ending now, fastest rising first.
+func GetTrendingTags(ctx context.Context, window time.Duration, limit int) []hashtag.Trend {
+	_, span := tracer.Start(ctx, "services.GetTrendingTags", trace.WithAttributes(attribute.String("trending.window", window.String())))
+	defer span.End()
+
+	trends := tagIndex.Trending(clock(), window, limit)
+	span.SetAttributes(attribute.Int("trending.tags", len(trends)))
+	return trends
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"mini-social-media-api/models"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timelineCacheSize is how many of the newest posts a home timeline holds
+const timelineCacheSize = 800
+
+// TimelineFanOutLimit chooses how new posts reach the home timelines of followers.
+// Posts of authors with at most this many followers are pushed into the cached timeline of every follower
+// when written (fan-out on write). Posts of larger accounts are merged into timelines when they are read
+// (fan-out on read), so a single post never costs millions of writes. 0 reads every timeline on request.
+var TimelineFanOutLimit = 10_000
+
+// Home timeline state, guarded by the post mutex like the posts it references
+var homeTimelines = map[int][]int{} // User ID to the IDs of the posts pushed to their timeline, oldest first
+var authorPosts = map[int][]int{}   // Author ID to the IDs of their posts, oldest first
+var pulledAuthors = map[int]bool{}  // Authors with posts that were not pushed, merged into timelines on read
+
+// GetHomeTimeline retrieves the posts of the user and of the accounts they follow, newest first.
+// Returns the requested page of posts, the total number of posts in the timeline, or ErrUnknownCaller.
+func GetHomeTimeline(ctx context.Context, userID, page, limit int) ([]models.Post, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetHomeTimeline", trace.WithAttributes(attribute.Int("user.id", userID)))
+	defer span.End()
+
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, 0, recordError(span, ErrUnknownCaller)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	// Merge the pushed posts with the posts of the user and of the followed accounts that are read on request
+	ids := append([]int{}, homeTimelines[userID]...)
+	ids = append(ids, authorPosts[userID]...)
+	pulled := 0
+	for _, followeeID := range followeeIDs(userID) {
+		if pulledAuthors[followeeID] {
+			ids = append(ids, authorPosts[followeeID]...)
+			pulled++
+		}
+	}
+	span.SetAttributes(attribute.Int("timeline.pulled_authors", pulled))
+
+	// Post IDs are assigned at creation, so drafts published later than newer posts have lower IDs; order by
+	// publication instead, newest first. A post may be both pushed and pulled
+	sort.Slice(ids, func(i, j int) bool { return seqOf(ids[i]) > seqOf(ids[j]) })
+	filter := filterFor(userID)
+	timeline := make([]models.Post, 0, min(len(ids), timelineCacheSize))
+	for i, id := range ids {
+		if i > 0 && ids[i-1] == id {
+			continue
+		}
+		if j := findSequencedPostIndex(ctx, id); j >= 0 && filter.inTimeline(posts[j]) {
+			timeline = append(timeline, filter.present(posts[j]))
+		}
+		if len(timeline) == timelineCacheSize {
+			break
+		}
+	}
+
+	return pageOf(timeline, page, limit), len(timeline), nil
+}
+
+// fanOutLocked records a new post of its author and pushes it to the timelines of their followers,
+// unless the author has more than TimelineFanOutLimit followers
+// The caller must hold the post mutex
+func fanOutLocked(post models.Post) {
+	if post.AuthorID == 0 {
+		return
+	}
+	authorPosts[post.AuthorID] = append(authorPosts[post.AuthorID], post.ID)
+
+	followers := followerIDs(post.AuthorID)
+	if len(followers) > TimelineFanOutLimit {
+		pulledAuthors[post.AuthorID] = true
+		return
+	}
+	for _, followerID := range followers {
+		homeTimelines[followerID] = appendCapped(homeTimelines[followerID], post.ID)
+	}
+}
+
+// forgetPostLocked removes a deleted post from the publication order and from the posts of its author
+// Pushed copies are skipped when timelines are read, since the post no longer exists
+// The caller must hold the post mutex
+func forgetPostLocked(post models.Post) {
+	delete(postSeqs, post.ID)
+	if post.AuthorID == 0 {
+		return
+	}
+	authorPosts[post.AuthorID] = removeInt(authorPosts[post.AuthorID], post.ID)
+}
+
+// backfillTimelineLocked pushes the recent posts of a newly followed account into the follower's timeline
+// Posts of pulled authors are already merged on read
+// The caller must hold the post mutex
+func backfillTimelineLocked(followerID, followeeID int) {
+	if pulledAuthors[followeeID] || len(authorPosts[followeeID]) == 0 {
+		return
+	}
+	merged := append(append([]int{}, homeTimelines[followerID]...), authorPosts[followeeID]...)
+	sort.Slice(merged, func(i, j int) bool { return seqOf(merged[i]) < seqOf(merged[j]) })
+	if len(merged) > timelineCacheSize {
+		merged = merged[len(merged)-timelineCacheSize:]
+	}
+	homeTimelines[followerID] = merged
+}
+
+// purgeTimelineLocked removes the posts of an unfollowed account from the follower's timeline
+// The caller must hold the post mutex
+func purgeTimelineLocked(followerID, followeeID int) {
+	unfollowed := map[int]bool{}
+	for _, id := range authorPosts[followeeID] {
+		unfollowed[id] = true
+	}
+	kept := homeTimelines[followerID][:0:0]
+	for _, id := range homeTimelines[followerID] {
+		if !unfollowed[id] {
+			kept = append(kept, id)
+		}
+	}
+	homeTimelines[followerID] = kept
+}
+
+// rebuildTimelinesLocked rebuilds the publication order, the posts of every author and the home timelines from the store
+// The caller must hold the post mutex
+func rebuildTimelinesLocked(ctx context.Context) {
+	homeTimelines = map[int][]int{}
+	authorPosts = map[int][]int{}
+	pulledAuthors = map[int]bool{}
+	postSeqs = map[int]int{}
+	for _, post := range listPosts(ctx) {
+		sequencePost(post.ID)
+		if post.Status.Published() {
+			fanOutLocked(post)
+		}
+	}
+}
+
+// appendCapped appends an ID to a timeline, dropping the oldest IDs beyond timelineCacheSize
+func appendCapped(timeline []int, id int) []int {
+	timeline = append(timeline, id)
+	if len(timeline) > timelineCacheSize {
+		timeline = append(timeline[:0:0], timeline[len(timeline)-timelineCacheSize:]...)
+	}
+	return timeline
+}
+
+// removeInt returns the IDs without the given ID, in a new slice
+func removeInt(ids []int, id int) []int {
+	kept := make([]int, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-social-media-api/mention"
+	"mini-social-media-api/models"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var users []models.User          // In-memory storage for all users
+var usernames = map[string]int{} // Lower case username to user ID
+var userMutex = &sync.RWMutex{}  // Guards users and usernames; may be acquired while holding the post mutex
+var userIDCounter = 1            // Counter for generating unique user IDs
+
+// MentionPolicy decides what happens to mentions of usernames that do not belong to any user
+type MentionPolicy string
+
+const (
+	// FlagUnknownMentions keeps the text and returns the mention with no user ID
+	FlagUnknownMentions MentionPolicy = "flag"
+	// RejectUnknownMentions rejects the post or comment with a validation error
+	RejectUnknownMentions MentionPolicy = "reject"
+)
+
+// UnknownMentions is the policy applied to mentions of unknown usernames
+var UnknownMentions = FlagUnknownMentions
+
+// ParseMentionPolicy converts a configuration value to a MentionPolicy; an empty value selects the default
+func ParseMentionPolicy(value string) (MentionPolicy, error) {
+	switch policy := MentionPolicy(strings.ToLower(value)); policy {
+	case "":
+		return FlagUnknownMentions, nil
+	case FlagUnknownMentions, RejectUnknownMentions:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown mention policy %q, expected %q or %q", value, FlagUnknownMentions, RejectUnknownMentions)
+	}
+}
+
+// CreateUser registers a user with a unique username.
+// Returns the created user, a validation error if the username or display name is invalid, or ErrUsernameTaken.
+func CreateUser(ctx context.Context, username, displayName string) (models.User, error) {
+	_, span := tracer.Start(ctx, "services.CreateUser")
+	defer span.End()
+
+	if !mention.ValidUsername(username) {
+		return models.User{}, recordError(span, rejectValidation("create_user", fmt.Errorf("username must be 1-%d letters, digits or underscores", mention.MaxUsernameLength)))
+	}
+	if len(displayName) > 50 {
+		return models.User{}, recordError(span, rejectValidation("create_user", errors.New("display name exceeds maximum length of 50 characters")))
+	}
+	if strings.TrimSpace(displayName) == "" {
+		displayName = username
+	}
+
+	userMutex.Lock()
+	defer userMutex.Unlock()
+
+	key := strings.ToLower(username)
+	if _, taken := usernames[key]; taken {
+		return models.User{}, recordError(span, ErrUsernameTaken)
+	}
+
+	user := models.User{ID: userIDCounter, Username: username, DisplayName: displayName, CreatedAt: time.Now()}
+	userIDCounter++
+	users = append(users, user)
+	usernames[key] = user.ID
+	span.SetAttributes(attribute.Int("user.id", user.ID))
+	return user, nil
+}
+
+// GetUserByID retrieves a user by its ID.
+// Returns the user or ErrUserNotFound.
+func GetUserByID(ctx context.Context, id int) (models.User, error) {
+	_, span := tracer.Start(ctx, "services.GetUserByID", trace.WithAttributes(attribute.Int("user.id", id)))
+	defer span.End()
+
+	userMutex.RLock()
+	defer userMutex.RUnlock()
+
+	if i := findUserIndex(id); i >= 0 {
+		return users[i], nil
+	}
+	return models.User{}, recordError(span, ErrUserNotFound)
+}
+
+// GetPostsMentioningUser retrieves the posts whose content or comments mention a user, newest first.
+// Returns the requested page of posts, the total number of such posts, or ErrUserNotFound.
+func GetPostsMentioningUser(ctx context.Context, userID, page, limit int) ([]models.Post, int, error) {
+	ctx, span := tracer.Start(ctx, "services.GetPostsMentioningUser", trace.WithAttributes(attribute.Int("user.id", userID)))
+	defer span.End()
+
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, 0, recordError(span, err)
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	stored := newViewerFilter(ctx).apply(listPosts(ctx))
+	var mentioning []models.Post
+	for i := len(stored) - 1; i >= 0; i-- {
+		if mentionsUser(stored[i], userID) {
+			mentioning = append(mentioning, stored[i])
+		}
+	}
+
+	return pageOf(mentioning, page, limit), len(mentioning), nil
+}
+
+// findUserIndex returns the index of the user with the given ID, or -1 if it does not exist
+// The caller must hold the user mutex
+func findUserIndex(id int) int {
+	for i, user := range users {
+		if user.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveMentions extracts the mentions of a text written by authorID and looks up the user of each username
+// Users who blocked the author or were blocked by them cannot be mentioned, so their mentions are left unresolved
+// like those of unknown usernames; the unknown and blocked usernames are also returned
+func resolveMentions(text string, authorID int) (mentions []models.Mention, unknown, blocked []string) {
+	extracted := mention.Extract(text)
+	mentions = make([]models.Mention, 0, len(extracted))
+	userMutex.RLock()
+	for _, m := range extracted {
+		mentions = append(mentions, models.Mention{Username: m.Username, UserID: usernames[strings.ToLower(m.Username)], Start: m.Start, End: m.End})
+	}
+	userMutex.RUnlock()
+
+	safetyMutex.RLock()
+	defer safetyMutex.RUnlock()
+	for i, m := range mentions {
+		switch {
+		case m.UserID == 0:
+			unknown = append(unknown, m.Username)
+		case blocksOf[authorID][m.UserID] || blockedBy[authorID][m.UserID]:
+			mentions[i].UserID = 0
+			blocked = append(blocked, m.Username)
+		}
+	}
+	return mentions, unknown, blocked
+}
+
+// validateMentions applies the UnknownMentions policy to the mentions of a text written by authorID,
+// both to unknown usernames and to users on the other side of a block
+func validateMentions(operation, text string, authorID int) error {
+	if UnknownMentions != RejectUnknownMentions {
+		return nil
+	}
+	_, unknown, blocked := resolveMentions(text, authorID)
+	if len(unknown) > 0 {
+		return rejectValidation(operation, fmt.Errorf("mentioned user @%s does not exist", unknown[0]))
+	}
+	if len(blocked) > 0 {
+		return rejectValidation(operation, fmt.Errorf("user @%s cannot be mentioned", blocked[0]))
+	}
+	return nil
+}
+
+// mentionsUser reports whether the content or a comment of a post mentions the user
+func mentionsUser(post models.Post, userID int) bool {
+	for _, m := range post.Mentions {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	for _, comment := range post.Comments {
+		for _, m := range comment.Mentions {
+			if m.UserID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
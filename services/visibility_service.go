@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mini-social-media-api/identity"
+	"mini-social-media-api/models"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetPostVisibility changes who can see a post; only its author may do so.
+// The change applies at once to every listing, search, tag page and home timeline.
+// Returns the updated post, ErrPostNotFound if the caller cannot see the post, or ErrNotAuthor.
+func SetPostVisibility(ctx context.Context, id int, visibility models.Visibility) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "services.SetPostVisibility", trace.WithAttributes(attribute.Int("post.id", id), attribute.String("post.visibility", string(visibility))))
+	defer span.End()
+
+	if !visibility.Valid() {
+		return models.Post{}, recordError(span, rejectValidation("set_visibility", errors.New("visibility must be public, unlisted, followers or private")))
+	}
+
+	lockPosts()
+	defer postMutex.Unlock()
+
+	filter := newViewerFilter(ctx)
+	i := findPostIndex(ctx, id)
+	if i < 0 || !filter.canSee(posts[i]) {
+		return models.Post{}, recordError(span, ErrPostNotFound)
+	}
+	if posts[i].AuthorID == 0 || posts[i].AuthorID != filter.viewerID {
+		return models.Post{}, recordError(span, ErrNotAuthor)
+	}
+
+	if posts[i].Visibility != visibility {
+		posts[i].Visibility = visibility
+		posts[i].UpdatedAt = time.Now()
+		commitChanges(ctx, change{postUpdated, posts[i]})
+	}
+	return filter.present(posts[i]), nil
+}
+
+// findVisiblePostIndex returns the index of the post with the given ID if the calling user can see it, or -1
+// Hidden posts are reported as missing, so their existence is not revealed
+// The caller must hold the post mutex
+func findVisiblePostIndex(ctx context.Context, id int) int {
+	i := findPostIndex(ctx, id)
+	if i < 0 || !newViewerFilter(ctx).canSee(posts[i]) {
+		return -1
+	}
+	return i
+}
+
+// validateVisibility checks the visibility of a new post; restricting a post requires an author to restrict it to
+func validateVisibility(operation string, visibility models.Visibility, authorID int) error {
+	if visibility == "" || visibility == models.VisibilityPublic {
+		return nil
+	}
+	if !visibility.Valid() {
+		return rejectValidation(operation, errors.New("visibility must be public, unlisted, followers or private"))
+	}
+	if authorID == 0 {
+		return rejectValidation(operation, errors.New("anonymous posts are always public"))
+	}
+	return nil
+}
+
+// viewerFilter applies the visibility of posts and the blocks and mutes of the calling user to what they see
+type viewerFilter struct {
+	lookup    *postLookup
+	viewerID  int
+	now       time.Time    // Posts that expired by then are hidden
+	following map[int]bool // Users followed by the viewer
+	blocked   map[int]bool // Users blocked by the viewer or who blocked the viewer
+	muted     map[int]bool // Users muted by the viewer
+}
+
+// socialGraphVersion changes whenever follows, blocks or mutes change, so cached filters know to be rebuilt
+var socialGraphVersion atomic.Uint64
+
+// socialGraphChanged marks the filters built before a change to follows, blocks or mutes as stale
+func socialGraphChanged() {
+	socialGraphVersion.Add(1)
+}
+
+// newViewerFilter builds the filter of the calling user in ctx; anonymous callers only see public and unlisted posts
+func newViewerFilter(ctx context.Context) viewerFilter {
+	return filterFor(identity.FromContext(ctx))
+}
+
+// filterFor builds the filter of a user, or of an anonymous caller for 0
+func filterFor(viewerID int) viewerFilter {
+	filter := viewerFilter{lookup: &postLookup{}, viewerID: viewerID, now: clock(), following: map[int]bool{}, blocked: map[int]bool{}, muted: map[int]bool{}}
+	if viewerID == 0 {
+		return filter
+	}
+
+	for _, id := range followeeIDs(viewerID) {
+		filter.following[id] = true
+	}
+
+	safetyMutex.RLock()
+	defer safetyMutex.RUnlock()
+	for id := range blocksOf[viewerID] {
+		filter.blocked[id] = true
+	}
+	for id := range blockedBy[viewerID] {
+		filter.blocked[id] = true
+	}
+	for id := range mutesOf[viewerID] {
+		filter.muted[id] = true
+	}
+	return filter
+}
+
+// canSee reports whether the viewer may see and interact with a post, e.g. by ID, commenting or liking
+// Blocks and the visibility of the post apply everywhere; unpublished posts are only seen by their author
+// Expired posts are hidden from everyone, even before the sweeper removes them
+func (f viewerFilter) canSee(post models.Post) bool {
+	if f.blocked[post.AuthorID] || expired(post, f.now) {
+		return false
+	}
+	if !post.Status.Published() {
+		return post.AuthorID != 0 && post.AuthorID == f.viewerID
+	}
+	switch post.Visibility {
+	case models.VisibilityFollowers:
+		return post.AuthorID == f.viewerID || f.following[post.AuthorID]
+	case models.VisibilityPrivate:
+		return post.AuthorID == f.viewerID
+	default:
+		return true
+	}
+}
+
+// inTimeline reports whether a post appears in the home timeline of the viewer; mutes hide posts from it
+// Reposts only appear while the viewer can see the reposted post and has not muted its author
+// Unpublished posts never appear, not even to their author
+func (f viewerFilter) inTimeline(post models.Post) bool {
+	if !post.Status.Published() || !f.canSee(post) || f.muted[post.AuthorID] {
+		return false
+	}
+	if post.RepostOf != 0 {
+		original, ok := f.original(post)
+		return ok && !f.muted[original.AuthorID]
+	}
+	return true
+}
+
+// lists reports whether a post appears in the other listings of the viewer, such as all posts, search and tag pages
+// Unlisted posts only appear in the listings of their author
+func (f viewerFilter) lists(post models.Post) bool {
+	if post.Visibility == models.VisibilityUnlisted && post.AuthorID != f.viewerID {
+		return false
+	}
+	return f.inTimeline(post)
+}
+
+// present returns the post as the viewer sees it: with the reposted or quoted post embedded if the viewer can see it,
+// without the comments of blocked and muted users, and with the poll results only once the viewer may see them
+func (f viewerFilter) present(post models.Post) models.Post {
+	post.Original = nil
+	if original, ok := f.original(post); ok {
+		original = f.showPoll(f.redact(original))
+		original.Original = nil // Only one level is embedded
+		post.Original = &original
+	}
+	return f.showPoll(f.redact(post))
+}
+
+// original returns the post reposted or quoted by a post, if it still exists and the viewer can see it
+// The caller must hold the post mutex
+func (f viewerFilter) original(post models.Post) (models.Post, bool) {
+	id := originalID(post)
+	if id == 0 {
+		return models.Post{}, false
+	}
+	original, ok := f.lookup.find(id)
+	if !ok || !f.canSee(original) {
+		return models.Post{}, false
+	}
+	return original, true
+}
+
+// originalID returns the ID of the post reposted or quoted by a post, or 0
+func originalID(post models.Post) int {
+	if post.RepostOf != 0 {
+		return post.RepostOf
+	}
+	return post.QuoteOf
+}
+
+// redact returns the post without the comments of blocked and muted users
+// The comments are copied, so the stored post is not modified
+func (f viewerFilter) redact(post models.Post) models.Post {
+	if len(f.blocked) == 0 && len(f.muted) == 0 {
+		return post
+	}
+	comments := make([]models.Comment, 0, len(post.Comments))
+	for _, comment := range post.Comments {
+		if !f.blocked[comment.AuthorID] && !f.muted[comment.AuthorID] {
+			comments = append(comments, comment)
+		}
+	}
+	post.Comments = comments
+	return post
+}
+
+// apply returns the posts listed for the viewer, as they see them
+func (f viewerFilter) apply(listed []models.Post) []models.Post {
+	kept := make([]models.Post, 0, len(listed))
+	for _, post := range listed {
+		if f.lists(post) {
+			kept = append(kept, f.present(post))
+		}
+	}
+	return kept
+}
+
+// listedIDs returns the IDs of the posts listed for the viewer, in the same order
+// The caller must hold the post mutex
+func (f viewerFilter) listedIDs(ctx context.Context, ids []int) []int {
+	index := make(map[int]int, len(posts))
+	for i, post := range listPosts(ctx) {
+		index[post.ID] = i
+	}
+	kept := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if i, ok := index[id]; ok && f.lists(posts[i]) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// postLookup finds posts by ID, indexing the store on first use so listings do not scan it for every embedded post
+// Posts with a position in publication order are found by binary search without building the index
+// The caller must hold the post mutex for as long as the lookup is used, unless it is detached
+type postLookup struct {
+	index    map[int]int
+	detached map[int]models.Post // When not nil, the only posts found, copied from the store, so no lock is needed
+}
+
+func (l *postLookup) find(id int) (models.Post, bool) {
+	if l.detached != nil {
+		post, ok := l.detached[id]
+		return post, ok
+	}
+	if _, ok := postSeqs[id]; ok {
+		if i := findSequencedPostIndex(context.Background(), id); i >= 0 {
+			return posts[i], true
+		}
+	}
+	if l.index == nil {
+		l.index = make(map[int]int, len(posts))
+		for i, post := range posts {
+			l.index[post.ID] = i
+		}
+	}
+	i, ok := l.index[id]
+	if !ok {
+		return models.Post{}, false
+	}
+	return posts[i], true
+}
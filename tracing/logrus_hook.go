@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogrusHook adds the trace and span IDs of the active span to log entries created with logrus.WithContext
+type LogrusHook struct{}
+
+// Levels returns the log levels the hook fires for
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire injects trace_id and span_id fields when the entry's context carries a valid span
+func (LogrusHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	entry.Data["trace_id"] = spanContext.TraceID().String()
+	entry.Data["span_id"] = spanContext.SpanID().String()
+	return nil
+}
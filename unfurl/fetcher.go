@@ -0,0 +1,148 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Errors returned by fetchers
+var (
+	ErrBlockedAddress = errors.New("address is not publicly routable")
+	ErrUnsupportedURL = errors.New("only absolute http and https URLs can be fetched")
+)
+
+// Page is a fetched web page
+type Page struct {
+	URL         *url.URL // Final URL after redirects, used to resolve relative image URLs
+	ContentType string
+	Body        []byte // At most the size limit of the fetcher
+}
+
+// Fetcher downloads web pages
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Page, error)
+}
+
+// HTTPFetcher fetches pages over HTTP with a timeout, a size limit and SSRF protection
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// Limits of the HTTP fetcher
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultMaxBytes = 512 << 10 // Metadata lives in the head of the page, so there is no need to read more
+	maxRedirects    = 5
+)
+
+// NewHTTPFetcher creates a fetcher that only connects to publicly routable addresses
+// allowAddress overrides the address check, e.g. to reach an httptest server on loopback; nil uses IsPublicAddress
+func NewHTTPFetcher(timeout time.Duration, maxBytes int64, allowAddress func(netip.Addr) bool) *HTTPFetcher {
+	if allowAddress == nil {
+		allowAddress = IsPublicAddress
+	}
+
+	// The check runs on the address actually dialed, after DNS resolution, so every redirect
+	// and every DNS answer is checked and a rebinding DNS server cannot slip a private address in
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !allowAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			return nil
+		},
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil, // A proxy would dial on our behalf and bypass the address check
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrUnsupportedURL
+				}
+				return nil
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+// Fetch downloads at most the size limit of the page at rawURL
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (Page, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return Page{}, ErrUnsupportedURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return Page{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "mini-social-media-api link preview")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Page{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Page{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{URL: resp.Request.URL, ContentType: resp.Header.Get("Content-Type"), Body: body}, nil
+}
+
+// nonPublicPrefixes are the ranges that are not routable on the public internet
+// Loopback, private, link-local (including cloud metadata at 169.254.169.254), multicast
+// and unspecified addresses are checked with the netip methods
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("2001:db8::/32"),  // Documentation
+}
+
+// IsPublicAddress reports whether an IP address is publicly routable
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap() // ::ffff:127.0.0.1 is loopback too
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
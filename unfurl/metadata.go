@@ -0,0 +1,134 @@
+package unfurl
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNotHTML is returned when a link does not point to an HTML page
+var ErrNotHTML = errors.New("link is not an HTML page")
+
+// Maximum lengths of the text fields of a preview, in characters
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+)
+
+// Preview is the metadata shown for a link
+type Preview struct {
+	URL         string // The link as written in the post
+	Title       string
+	Description string
+	Image       string // Absolute http or https URL, empty if the page has none
+	SiteName    string
+}
+
+// ParsePreview extracts OpenGraph and Twitter card metadata from a page
+// OpenGraph properties win over Twitter card properties, which win over the title element and the description meta tag
+func ParsePreview(page Page) (Preview, error) {
+	mediaType, _, _ := mime.ParseMediaType(page.ContentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, ErrNotHTML
+	}
+
+	meta := map[string]string{}
+	var title strings.Builder
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(page.Body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// End of the page, or of the part that was read
+			return buildPreview(page.URL, meta, title.String()), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "meta":
+				key, content := metaAttributes(token)
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = content
+				}
+			case "title":
+				inTitle = true
+			case "body":
+				return buildPreview(page.URL, meta, title.String()), nil // Metadata only lives in the head
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().Data == "title" {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// metaAttributes returns the lower case property or name of a meta tag and its content
+func metaAttributes(token html.Token) (string, string) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(attr.Val)
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+func buildPreview(pageURL *url.URL, meta map[string]string, title string) Preview {
+	return Preview{
+		Title:       truncate(firstNonEmpty(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: truncate(firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		Image:       resolveImage(pageURL, firstNonEmpty(meta["og:image:secure_url"], meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])),
+		SiteName:    truncate(meta["og:site_name"], maxTitleLength),
+	}
+}
+
+// resolveImage makes an image URL absolute, dropping it unless it uses http or https
+func resolveImage(pageURL *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(strings.TrimSpace(image))
+	if err != nil {
+		return ""
+	}
+	if pageURL != nil {
+		ref = pageURL.ResolveReference(ref)
+	}
+	if ref.Host == "" || (ref.Scheme != "http" && ref.Scheme != "https") {
+		return ""
+	}
+	return ref.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// truncate collapses white space and cuts text to at most max characters
+func truncate(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
@@ -0,0 +1,231 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func allowAll(netip.Addr) bool { return true }
+
+func TestParsePreview(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/articles/1")
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        Preview
+		wantErr     error
+	}{
+		{"OpenGraph", "text/html; charset=utf-8", `<html><head>
+			<meta property="og:title" content="OG title"><meta name="twitter:title" content="Card title">
+			<title>Page title</title><meta property="og:description" content="OG description">
+			<meta property="og:image" content="/img/cover.png"><meta property="og:site_name" content="Example">
+			</head><body><meta property="og:title" content="Ignored"></body></html>`,
+			Preview{Title: "OG title", Description: "OG description", Image: "https://example.com/img/cover.png", SiteName: "Example"}, nil},
+		{"Twitter card", "text/html", `<meta name="twitter:title" content="Card title"><meta name="twitter:description" content="Card description"><meta name="twitter:image" content="https://cdn.example.com/card.jpg">`,
+			Preview{Title: "Card title", Description: "Card description", Image: "https://cdn.example.com/card.jpg"}, nil},
+		{"Plain HTML", "text/html", "<title>  Just a\n page </title><meta name=\"description\" content=\"About\">",
+			Preview{Title: "Just a page", Description: "About"}, nil},
+		{"Unsafe image scheme", "text/html", `<meta property="og:image" content="javascript:alert(1)">`, Preview{}, nil},
+		{"Not HTML", "application/json", `{"title": "no"}`, Preview{}, ErrNotHTML},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := ParsePreview(Page{URL: pageURL, ContentType: testCase.contentType, Body: []byte(testCase.body)})
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if got != testCase.want {
+				t.Errorf("Expected %+v, got %+v", testCase.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>Hello</title>" + strings.Repeat("x", 4096)))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(100*time.Millisecond, 1024, allowAll)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantErr  bool
+		wantPath string
+	}{
+		{"Page", server.URL + "/page", false, "/page"},
+		{"Follows redirects", server.URL + "/redirect", false, "/page"},
+		{"Too many redirects", server.URL + "/loop", true, ""},
+		{"Timeout", server.URL + "/slow", true, ""},
+		{"Error status", server.URL + "/missing", true, ""},
+		{"Unsupported scheme", "file:///etc/passwd", true, ""},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			page, err := fetcher.Fetch(context.Background(), testCase.url)
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", testCase.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if page.URL.Path != testCase.wantPath {
+				t.Errorf("Expected final path %s, got %s", testCase.wantPath, page.URL.Path)
+			}
+			if len(page.Body) != 1024 {
+				t.Errorf("Expected the body to be cut at 1024 bytes, got %d", len(page.Body))
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the request to be blocked before reaching the server")
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second, 1024, nil)
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+
+	for _, target := range []string{server.URL, "http://localhost" + port, "http://[::1]" + port, "http://0.0.0.0" + port} {
+		if _, err := fetcher.Fetch(context.Background(), target); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("Expected %s to be blocked, got: %v", target, err)
+		}
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.addr, func(t *testing.T) {
+			if got := IsPublicAddress(netip.MustParseAddr(testCase.addr)); got != testCase.want {
+				t.Errorf("Expected %v, got %v", testCase.want, got)
+			}
+		})
+	}
+}
+
+// countingFetcher serves a fixed page and counts fetches per URL
+type countingFetcher struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, rawURL string) (Page, error) {
+	f.mu.Lock()
+	f.calls[rawURL]++
+	f.mu.Unlock()
+
+	if strings.Contains(rawURL, "broken") {
+		return Page{}, errors.New("connection refused")
+	}
+	return Page{ContentType: "text/html", Body: []byte("<title>" + rawURL + "</title>")}, nil
+}
+
+func (f *countingFetcher) count(rawURL string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[rawURL]
+}
+
+func TestUnfurlerCache(t *testing.T) {
+	fetcher := &countingFetcher{calls: map[string]int{}}
+	u := New(fetcher, Options{CacheTTL: time.Hour, FailureTTL: time.Minute})
+	defer u.Close()
+
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	u.now = func() time.Time { return current }
+
+	for i := 0; i < 3; i++ {
+		preview, err := u.Unfurl(context.Background(), "https://example.com")
+		if err != nil || preview.Title != "https://example.com" || preview.URL != "https://example.com" {
+			t.Fatalf("Expected a preview, got %+v (error: %v)", preview, err)
+		}
+		u.Unfurl(context.Background(), "https://broken.example.com")
+	}
+	if fetcher.count("https://example.com") != 1 || fetcher.count("https://broken.example.com") != 1 {
+		t.Errorf("Expected one fetch per link while cached, got %v", fetcher.calls)
+	}
+
+	// Failures expire sooner than previews
+	current = current.Add(2 * time.Minute)
+	u.Unfurl(context.Background(), "https://example.com")
+	u.Unfurl(context.Background(), "https://broken.example.com")
+	if fetcher.count("https://example.com") != 1 || fetcher.count("https://broken.example.com") != 2 {
+		t.Errorf("Expected only the failed link to be fetched again, got %v", fetcher.calls)
+	}
+}
+
+func TestUnfurlerEnqueue(t *testing.T) {
+	u := New(&countingFetcher{calls: map[string]int{}}, Options{})
+	defer u.Close()
+
+	done := make(chan Preview, 1)
+	if !u.Enqueue("https://example.com/post", func(p Preview) { done <- p }) {
+		t.Fatal("Expected the link to be queued")
+	}
+	u.Enqueue("https://broken.example.com", func(p Preview) { t.Errorf("Expected no callback for a failed link, got %+v", p) })
+
+	select {
+	case preview := <-done:
+		if preview.Title != "https://example.com/post" {
+			t.Errorf("Expected the preview of the link, got %+v", preview)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the preview to be delivered")
+	}
+
+	u.Close()
+	if u.Enqueue("https://example.com/late", func(Preview) {}) {
+		t.Error("Expected a closed unfurler to refuse links")
+	}
+}
@@ -0,0 +1,181 @@
+package unfurl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Options configures an Unfurler; zero values select the defaults
+type Options struct {
+	Workers    int           // Concurrent fetches (default 4)
+	QueueSize  int           // Links waiting to be fetched before Enqueue drops them (default 100)
+	Timeout    time.Duration // Limit of a single fetch (default DefaultTimeout)
+	CacheTTL   time.Duration // How long a preview is reused (default 1h)
+	FailureTTL time.Duration // How long a failed link is not retried (default 5m)
+	CacheSize  int           // Maximum number of cached links (default 1000)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 100
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.CacheTTL <= 0 {
+		o.CacheTTL = time.Hour
+	}
+	if o.FailureTTL <= 0 {
+		o.FailureTTL = 5 * time.Minute
+	}
+	if o.CacheSize <= 0 {
+		o.CacheSize = 1000
+	}
+	return o
+}
+
+type cacheEntry struct {
+	preview Preview
+	err     error
+	expires time.Time
+}
+
+type job struct {
+	url  string
+	done func(Preview)
+}
+
+// Unfurler builds link previews in the background and caches them
+// It is safe for concurrent use
+type Unfurler struct {
+	fetcher Fetcher
+	options Options
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+
+	jobs   chan job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates an Unfurler and starts its workers; call Close to stop them
+func New(fetcher Fetcher, options Options) *Unfurler {
+	options = options.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &Unfurler{
+		fetcher: fetcher,
+		options: options,
+		now:     time.Now,
+		cache:   map[string]cacheEntry{},
+		jobs:    make(chan job, options.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for i := 0; i < options.Workers; i++ {
+		u.wg.Add(1)
+		go u.work()
+	}
+	return u
+}
+
+// Close cancels fetches in progress and stops the workers; queued links are dropped
+func (u *Unfurler) Close() {
+	u.cancel()
+	u.wg.Wait()
+}
+
+// Enqueue schedules a preview of the link and calls done with it once it is ready
+// done is not called if the link cannot be previewed
+// Never blocks: returns false if the queue is full or the Unfurler is closed
+func (u *Unfurler) Enqueue(rawURL string, done func(Preview)) bool {
+	if u.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case u.jobs <- job{url: rawURL, done: done}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (u *Unfurler) work() {
+	defer u.wg.Done()
+	for {
+		select {
+		case <-u.ctx.Done():
+			return
+		case j := <-u.jobs:
+			if preview, err := u.Unfurl(u.ctx, j.url); err == nil {
+				j.done(preview)
+			}
+		}
+	}
+}
+
+// Unfurl returns the preview of a link, from the cache when possible
+func (u *Unfurler) Unfurl(ctx context.Context, rawURL string) (Preview, error) {
+	if entry, ok := u.cached(rawURL); ok {
+		return entry.preview, entry.err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, u.options.Timeout)
+	defer cancel()
+
+	var preview Preview
+	page, err := u.fetcher.Fetch(fetchCtx, rawURL)
+	if err == nil {
+		preview, err = ParsePreview(page)
+	}
+	if ctx.Err() != nil {
+		return Preview{}, ctx.Err() // The caller gave up, so the link was not really tried
+	}
+	preview.URL = rawURL
+
+	ttl := u.options.CacheTTL
+	if err != nil {
+		ttl = u.options.FailureTTL
+	}
+	u.store(rawURL, cacheEntry{preview: preview, err: err, expires: u.now().Add(ttl)})
+	return preview, err
+}
+
+func (u *Unfurler) cached(rawURL string) (cacheEntry, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.cache[rawURL]
+	if !ok || !u.now().Before(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (u *Unfurler) store(rawURL string, entry cacheEntry) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.cache) >= u.options.CacheSize {
+		now := u.now()
+		for key, cached := range u.cache {
+			if !now.Before(cached.expires) {
+				delete(u.cache, key)
+			}
+		}
+		for key := range u.cache {
+			if len(u.cache) < u.options.CacheSize {
+				break
+			}
+			delete(u.cache, key) // Still full: evict arbitrary entries
+		}
+	}
+	u.cache[rawURL] = entry
+}